@@ -0,0 +1,153 @@
+// Command capi builds libarbor, a C shared library exposing a Tokenizer to
+// other languages, such as the ctypes wrapper in python/:
+//
+//	go build -buildmode=c-shared -o build/libarbor.so ./capi
+//
+// The generated libarbor.h declares the exported functions. A tokenizer is an
+// opaque handle released with arbor_tokenizer_free. Functions that can fail
+// take a char **err which, on failure, points to a message to release with
+// arbor_string_free. Results are released with arbor_result_free.
+package main
+
+/*
+#include <stdint.h>
+#include <stdlib.h>
+
+// arbor_result holds a tokenized document: tokens is an array of n_tokens
+// IDs and paths a row-major n_tokens x depth array of padded paths.
+typedef struct {
+	int32_t *tokens;
+	int32_t *paths;
+	int64_t n_tokens;
+	int64_t depth;
+} arbor_result;
+*/
+import "C"
+
+import (
+	"bytes"
+	"errors"
+	"runtime/cgo"
+	"unsafe"
+
+	"github.com/clems4ever/arbor-encoder/tokenizer"
+)
+
+func main() {}
+
+// arbor_tokenizer_new loads the vocab at vocabPath and returns a tokenizer
+// handle, or 0 on error.
+//
+//export arbor_tokenizer_new
+func arbor_tokenizer_new(vocabPath *C.char, errOut **C.char) C.uintptr_t {
+	tok, err := tokenizer.NewTokenizer(C.GoString(vocabPath))
+	if err != nil {
+		setError(errOut, err)
+		return 0
+	}
+	return C.uintptr_t(cgo.NewHandle(tok))
+}
+
+// arbor_tokenizer_free releases a handle returned by arbor_tokenizer_new.
+//
+//export arbor_tokenizer_free
+func arbor_tokenizer_free(handle C.uintptr_t) {
+	if handle != 0 {
+		cgo.Handle(handle).Delete()
+	}
+}
+
+// arbor_tokenize tokenizes the XML document in buf[0:length] into out. It
+// returns 0 on success and -1 on error, leaving out untouched.
+//
+//export arbor_tokenize
+func arbor_tokenize(handle C.uintptr_t, buf *C.char, length C.int64_t, out *C.arbor_result, errOut **C.char) C.int {
+	tok := cgo.Handle(handle).Value().(*tokenizer.Tokenizer)
+	// The tokenizer does not keep the input, so buf is read in place.
+	doc := unsafe.Slice((*byte)(unsafe.Pointer(buf)), int(length))
+	res, err := tok.Tokenize(bytes.NewReader(doc))
+	if err != nil {
+		setError(errOut, err)
+		return -1
+	}
+
+	n, depth := len(res.Tokens), 0
+	if n > 0 {
+		depth = len(res.PaddedPaths[0])
+	}
+	tokens := allocInt32(n)
+	for i, id := range res.Tokens {
+		tokens[i] = C.int32_t(id)
+	}
+	paths := allocInt32(n * depth)
+	for i, row := range res.PaddedPaths {
+		for j, v := range row {
+			paths[i*depth+j] = C.int32_t(v)
+		}
+	}
+
+	out.tokens = unsafe.SliceData(tokens)
+	out.paths = unsafe.SliceData(paths)
+	out.n_tokens = C.int64_t(n)
+	out.depth = C.int64_t(depth)
+	return 0
+}
+
+// arbor_result_free releases the arrays of a result filled by arbor_tokenize
+// and zeroes it.
+//
+//export arbor_result_free
+func arbor_result_free(res *C.arbor_result) {
+	if res == nil {
+		return
+	}
+	C.free(unsafe.Pointer(res.tokens))
+	C.free(unsafe.Pointer(res.paths))
+	*res = C.arbor_result{}
+}
+
+// arbor_decode decodes tokens[0:n] to space-joined tokens or, when xml is
+// non-zero, to an XML document. It returns a string to release with
+// arbor_string_free, or NULL on error.
+//
+//export arbor_decode
+func arbor_decode(handle C.uintptr_t, tokens *C.int32_t, n C.int64_t, xml C.int, errOut **C.char) *C.char {
+	tok := cgo.Handle(handle).Value().(*tokenizer.Tokenizer)
+	ids := make([]int, int(n))
+	for i, id := range unsafe.Slice(tokens, int(n)) {
+		ids[i] = int(id)
+	}
+	if xml == 0 {
+		return C.CString(tok.Decode(ids))
+	}
+	root, err := tok.DecodeXML(ids)
+	if err != nil {
+		setError(errOut, err)
+		return nil
+	}
+	if root == nil {
+		setError(errOut, errors.New("no element to decode"))
+		return nil
+	}
+	return C.CString(root.String())
+}
+
+// arbor_string_free releases a string returned by the library.
+//
+//export arbor_string_free
+func arbor_string_free(s *C.char) {
+	C.free(unsafe.Pointer(s))
+}
+
+// allocInt32 allocates n int32_t with malloc, at least one so the pointer is
+// never NULL.
+func allocInt32(n int) []C.int32_t {
+	p := (*C.int32_t)(C.malloc(C.size_t(max(n, 1)) * C.size_t(unsafe.Sizeof(C.int32_t(0)))))
+	return unsafe.Slice(p, n)
+}
+
+func setError(errOut **C.char, err error) {
+	if errOut != nil {
+		*errOut = C.CString(err.Error())
+	}
+}
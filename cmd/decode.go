@@ -0,0 +1,331 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/clems4ever/arbor-encoder/tokenizer"
+	"github.com/spf13/cobra"
+)
+
+var (
+	decodeFormat string
+	decodeStrict bool
+	decodeRaw    bool
+)
+
+var decodeCmd = &cobra.Command{
+	Use:   "decode [file]",
+	Short: "Decode token IDs back to XML",
+	Long: `Read sequences of token IDs from a file, or stdin when no file or "-" is
+given, and print each as pretty-printed XML.
+
+Input formats (--format, detected by default):
+  json   one or more JSON arrays of IDs, one per line for JSONL, arrays of
+         arrays, or objects with a "tokens" array such as /tokenize responses
+  npy    a NumPy int array, one sequence per row when 2D
+
+Negative IDs are taken as padding and dropped. Truncated or unbalanced
+sequences are repaired before decoding and the repairs are reported on
+stderr; --strict rejects them instead. --raw prints the tokens of each
+sequence separated by spaces without rebuilding the XML.`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		var in io.Reader = os.Stdin
+		if len(args) == 1 && args[0] != "-" {
+			f, err := os.Open(args[0])
+			if err != nil {
+				fmt.Printf("Error opening file: %v\n", err)
+				os.Exit(1)
+			}
+			defer f.Close()
+			in = f
+		}
+
+		sequences, err := readTokenSequences(in, decodeFormat)
+		if err != nil {
+			fmt.Printf("Error reading tokens: %v\n", err)
+			os.Exit(1)
+		}
+		tok, err := tokenizer.NewTokenizer(vocabPath)
+		if err != nil {
+			fmt.Printf("Error creating tokenizer: %v\n", err)
+			os.Exit(1)
+		}
+
+		failed := false
+		for i, tokens := range sequences {
+			if i > 0 {
+				fmt.Println()
+			}
+			if err := decodeSequence(os.Stdout, tok, i+1, tokens); err != nil {
+				fmt.Fprintf(os.Stderr, "sequence %d: %v\n", i+1, err)
+				failed = true
+			}
+		}
+		if failed {
+			os.Exit(1)
+		}
+	},
+}
+
+// decodeSequence prints the n-th sequence according to --raw and --strict.
+func decodeSequence(w io.Writer, tok *tokenizer.Tokenizer, n int, tokens []int) error {
+	if decodeRaw {
+		_, err := fmt.Fprintln(w, tok.Decode(tokens))
+		return err
+	}
+
+	repaired, report := tok.RepairTokens(tokens)
+	if report.Changed() {
+		if decodeStrict {
+			return fmt.Errorf("malformed sequence: %s", report.Actions[0])
+		}
+		for _, action := range report.Actions {
+			fmt.Fprintf(os.Stderr, "sequence %d: repaired: %s\n", n, action)
+		}
+	}
+	root, err := tok.DecodeXML(repaired)
+	if err != nil {
+		return err
+	}
+	if root == nil {
+		return errors.New("no element to decode")
+	}
+	root.PrettyPrint(w, 0)
+	return nil
+}
+
+// readTokenSequences reads the token sequences of r in the given format:
+// json, npy, or auto to detect it.
+func readTokenSequences(r io.Reader, format string) ([][]int, error) {
+	br := bufio.NewReader(r)
+	if format == "auto" {
+		format = "json"
+		if magic, _ := br.Peek(6); string(magic) == "\x93NUMPY" {
+			format = "npy"
+		}
+	}
+
+	var sequences [][]int
+	switch format {
+	case "json", "jsonl":
+		dec := json.NewDecoder(br)
+		dec.UseNumber()
+		for {
+			var v interface{}
+			if err := dec.Decode(&v); err == io.EOF {
+				break
+			} else if err != nil {
+				return nil, err
+			}
+			seqs, err := jsonSequences(v)
+			if err != nil {
+				return nil, err
+			}
+			sequences = append(sequences, seqs...)
+		}
+	case "npy":
+		var err error
+		sequences, err = readNPY(br)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unknown --format %q (expected auto, json, jsonl or npy)", format)
+	}
+
+	for i, seq := range sequences {
+		sequences[i] = dropPadding(seq)
+	}
+	if len(sequences) == 0 {
+		return nil, errors.New("no token sequence found")
+	}
+	return sequences, nil
+}
+
+// jsonSequences returns the sequences of a JSON value: an array of IDs, an
+// array of such arrays, or an object with a "tokens" field.
+func jsonSequences(v interface{}) ([][]int, error) {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		tokens, ok := v["tokens"]
+		if !ok {
+			return nil, errors.New(`JSON object without a "tokens" field`)
+		}
+		return jsonSequences(tokens)
+	case []interface{}:
+		if len(v) > 0 {
+			if _, nested := v[0].([]interface{}); nested {
+				var sequences [][]int
+				for _, row := range v {
+					seqs, err := jsonSequences(row)
+					if err != nil {
+						return nil, err
+					}
+					sequences = append(sequences, seqs...)
+				}
+				return sequences, nil
+			}
+		}
+		seq := make([]int, len(v))
+		for i, id := range v {
+			n, ok := id.(json.Number)
+			if !ok {
+				return nil, fmt.Errorf("token %d: expected an integer, got %v", i, id)
+			}
+			parsed, err := strconv.Atoi(n.String())
+			if err != nil {
+				return nil, fmt.Errorf("token %d: %w", i, err)
+			}
+			seq[i] = parsed
+		}
+		return [][]int{seq}, nil
+	}
+	return nil, fmt.Errorf("expected an array of token IDs, got %v", v)
+}
+
+var (
+	npyDescr = regexp.MustCompile(`'descr':\s*'([<>|]?)([iu])(\d)'`)
+	npyShape = regexp.MustCompile(`'shape':\s*\(([^)]*)\)`)
+)
+
+// readNPY reads a 1D or 2D integer array in the NumPy .npy format, returning
+// the rows of a 2D array as separate sequences.
+func readNPY(r io.Reader) ([][]int, error) {
+	var preamble [10]byte
+	if _, err := io.ReadFull(r, preamble[:]); err != nil {
+		return nil, fmt.Errorf("npy: %w", err)
+	}
+	if string(preamble[:6]) != "\x93NUMPY" {
+		return nil, errors.New("npy: bad magic string")
+	}
+	headerLen := int(binary.LittleEndian.Uint16(preamble[8:10]))
+	if preamble[6] >= 2 {
+		// Versions 2.0 and up have a 4-byte header length.
+		var rest [2]byte
+		if _, err := io.ReadFull(r, rest[:]); err != nil {
+			return nil, fmt.Errorf("npy: %w", err)
+		}
+		headerLen = int(binary.LittleEndian.Uint32(append(preamble[8:10:10], rest[:]...)))
+	}
+	header := make([]byte, headerLen)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, fmt.Errorf("npy: %w", err)
+	}
+
+	if bytes.Contains(header, []byte("'fortran_order': True")) {
+		return nil, errors.New("npy: Fortran-ordered arrays are not supported")
+	}
+	descr := npyDescr.FindSubmatch(header)
+	if descr == nil {
+		return nil, fmt.Errorf("npy: unsupported dtype in header %q, expected integers", header)
+	}
+	var order binary.ByteOrder = binary.LittleEndian
+	if string(descr[1]) == ">" {
+		order = binary.BigEndian
+	}
+	signed, size := descr[2][0] == 'i', int(descr[3][0]-'0')
+	switch size {
+	case 1, 2, 4, 8:
+	default:
+		return nil, fmt.Errorf("npy: unsupported integer size %d", size)
+	}
+
+	shape := npyShape.FindSubmatch(header)
+	if shape == nil {
+		return nil, errors.New("npy: missing shape")
+	}
+	var dims []int
+	for _, d := range strings.Split(string(shape[1]), ",") {
+		if d = strings.TrimSpace(d); d == "" {
+			continue
+		}
+		n, err := strconv.Atoi(d)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("npy: bad shape %q", shape[1])
+		}
+		dims = append(dims, n)
+	}
+	rows, cols := 1, 0
+	switch len(dims) {
+	case 1:
+		cols = dims[0]
+	case 2:
+		rows, cols = dims[0], dims[1]
+	default:
+		return nil, fmt.Errorf("npy: expected 1 or 2 dimensions, got %d", len(dims))
+	}
+
+	if cols == 0 && rows > 1 {
+		return nil, errors.New("npy: rows have no tokens")
+	}
+	if cols > 0 && rows > math.MaxInt/size/cols {
+		return nil, fmt.Errorf("npy: array of shape %q is too large", shape[1])
+	}
+	// The shape is not trusted to size the buffer: the data is read as it
+	// comes and must be as long as the shape says.
+	n := rows * cols * size
+	data, err := io.ReadAll(io.LimitReader(r, int64(n)))
+	if err != nil {
+		return nil, fmt.Errorf("npy: %w", err)
+	}
+	if len(data) < n {
+		return nil, fmt.Errorf("npy: %w", io.ErrUnexpectedEOF)
+	}
+	sequences := make([][]int, rows)
+	for i := range sequences {
+		seq := make([]int, cols)
+		for j := range seq {
+			b := data[(i*cols+j)*size:]
+			switch {
+			case size == 1 && signed:
+				seq[j] = int(int8(b[0]))
+			case size == 1:
+				seq[j] = int(b[0])
+			case size == 2 && signed:
+				seq[j] = int(int16(order.Uint16(b)))
+			case size == 2:
+				seq[j] = int(order.Uint16(b))
+			case size == 4 && signed:
+				seq[j] = int(int32(order.Uint32(b)))
+			case size == 4:
+				seq[j] = int(order.Uint32(b))
+			default:
+				seq[j] = int(order.Uint64(b))
+			}
+		}
+		sequences[i] = seq
+	}
+	return sequences, nil
+}
+
+// dropPadding removes the negative IDs of a sequence.
+func dropPadding(tokens []int) []int {
+	out := tokens[:0]
+	for _, id := range tokens {
+		if id >= 0 {
+			out = append(out, id)
+		}
+	}
+	return out
+}
+
+func init() {
+	rootCmd.AddCommand(decodeCmd)
+
+	decodeCmd.Flags().StringVarP(&vocabPath, "vocab", "v", "examples/vocab.json", "Path to vocabulary file")
+	decodeCmd.Flags().StringVarP(&decodeFormat, "format", "f", "auto", "Input format: auto, json, jsonl or npy")
+	decodeCmd.Flags().BoolVar(&decodeStrict, "strict", false, "Fail on sequences that need repairs to decode")
+	decodeCmd.Flags().BoolVar(&decodeRaw, "raw", false, "Print the space-joined tokens instead of XML")
+}
@@ -0,0 +1,228 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/clems4ever/arbor-encoder/server"
+	"github.com/clems4ever/arbor-encoder/tokenizer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// npyFile builds a .npy file of the given version with a header holding descr
+// and shape, followed by data.
+func npyFile(version byte, descr, shape string, data []byte) []byte {
+	header := "{'descr': '" + descr + "', 'fortran_order': False, 'shape': (" + shape + "), }\n"
+	var b bytes.Buffer
+	b.WriteString("\x93NUMPY")
+	b.WriteByte(version)
+	b.WriteByte(0)
+	if version >= 2 {
+		binary.Write(&b, binary.LittleEndian, uint32(len(header)))
+	} else {
+		binary.Write(&b, binary.LittleEndian, uint16(len(header)))
+	}
+	b.WriteString(header)
+	b.Write(data)
+	return b.Bytes()
+}
+
+// npyData encodes values with the given byte order and integer type.
+func npyData(order binary.ByteOrder, values ...interface{}) []byte {
+	var b bytes.Buffer
+	for _, v := range values {
+		binary.Write(&b, order, v)
+	}
+	return b.Bytes()
+}
+
+func TestReadTokenSequences(t *testing.T) {
+	tests := []struct {
+		name     string
+		format   string
+		input    string
+		expected [][]int
+		err      string
+	}{
+		{name: "JSON array", format: "auto", input: `[1, 2, 3]`, expected: [][]int{{1, 2, 3}}},
+		{name: "JSONL", format: "jsonl", input: "[1, 2]\n[3]\n\n[4, 5]\n", expected: [][]int{{1, 2}, {3}, {4, 5}}},
+		{name: "nested arrays", format: "json", input: `[[1, 2], [3, 4]]`, expected: [][]int{{1, 2}, {3, 4}}},
+		{name: "tokens object", format: "auto", input: `{"tokens": [1, 2], "padded_paths": [[0], [0]]}`, expected: [][]int{{1, 2}}},
+		{name: "tokens objects as JSONL", format: "auto", input: "{\"tokens\": [1]}\n{\"tokens\": [[2], [3]]}", expected: [][]int{{1}, {2}, {3}}},
+		{name: "padding", format: "auto", input: `[[1, 2, -1, -1], [3, 4, 5, -100]]`, expected: [][]int{{1, 2}, {3, 4, 5}}},
+		{name: "empty sequence", format: "auto", input: `[]`, expected: [][]int{{}}},
+		{name: "no sequence", format: "auto", input: " \n", err: "no token sequence found"},
+		{name: "float", format: "json", input: `[1, 2.5]`, err: "token 1"},
+		{name: "string", format: "json", input: `[1, "2"]`, err: "token 1: expected an integer"},
+		{name: "object without tokens", format: "json", input: `{"ids": [1]}`, err: `without a "tokens" field`},
+		{name: "scalar", format: "json", input: `42`, err: "expected an array of token IDs"},
+		{name: "bad JSON", format: "json", input: `[1, 2`, err: "unexpected EOF"},
+		{name: "unknown format", format: "csv", input: `1,2`, err: `unknown --format "csv"`},
+		{
+			name:     "npy v1 int32",
+			format:   "auto",
+			input:    string(npyFile(1, "<i4", "3,", npyData(binary.LittleEndian, int32(100257), int32(200001), int32(-1)))),
+			expected: [][]int{{100257, 200001}},
+		},
+		{
+			name:     "npy v2 int64 2D",
+			format:   "npy",
+			input:    string(npyFile(2, "<i8", "2, 2", npyData(binary.LittleEndian, int64(1), int64(2), int64(3), int64(-1)))),
+			expected: [][]int{{1, 2}, {3}},
+		},
+		{
+			name:     "npy big-endian int16",
+			format:   "auto",
+			input:    string(npyFile(1, ">i2", "2,", npyData(binary.BigEndian, int16(300), int16(-2)))),
+			expected: [][]int{{300}},
+		},
+		{
+			name:     "npy uint8",
+			format:   "auto",
+			input:    string(npyFile(1, "|u1", "2,", []byte{7, 255})),
+			expected: [][]int{{7, 255}},
+		},
+		{
+			name:     "npy int8",
+			format:   "auto",
+			input:    string(npyFile(1, "|i1", "2,", []byte{7, 255})),
+			expected: [][]int{{7}},
+		},
+		{
+			name:     "npy uint32",
+			format:   "auto",
+			input:    string(npyFile(1, "<u4", "1,", npyData(binary.LittleEndian, uint32(4000000000)))),
+			expected: [][]int{{4000000000}},
+		},
+		{name: "npy float", format: "npy", input: string(npyFile(1, "<f4", "1,", make([]byte, 4))), err: "unsupported dtype"},
+		{name: "npy odd size", format: "npy", input: string(npyFile(1, "<i3", "1,", make([]byte, 3))), err: "unsupported integer size 3"},
+		{name: "npy 3D", format: "npy", input: string(npyFile(1, "<i4", "1, 1, 1", make([]byte, 4))), err: "expected 1 or 2 dimensions, got 3"},
+		{name: "npy truncated data", format: "npy", input: string(npyFile(1, "<i4", "2,", make([]byte, 4))), err: "npy: unexpected EOF"},
+		{name: "npy negative dim", format: "npy", input: string(npyFile(1, "<i4", "-1, 4", make([]byte, 4))), err: `npy: bad shape "-1, 4"`},
+		{name: "npy overflowing shape", format: "npy", input: string(npyFile(1, "<i8", "4611686018427387904, 4", make([]byte, 8))), err: "too large"},
+		{name: "npy huge shape", format: "npy", input: string(npyFile(1, "<i8", "1000000000000,", make([]byte, 8))), err: "npy: unexpected EOF"},
+		{name: "npy empty rows", format: "npy", input: string(npyFile(1, "<i8", "1000000000000, 0", nil)), err: "rows have no tokens"},
+		{name: "npy empty", format: "npy", input: string(npyFile(1, "<i4", "0,", nil)), expected: [][]int{{}}},
+		{name: "npy truncated header", format: "npy", input: "\x93NUMPY\x01\x00\x40\x00{'descr'", err: "npy: unexpected EOF"},
+		{name: "npy bad magic", format: "npy", input: "[1, 2, 3, 4, 5]", err: "npy: bad magic string"},
+		{
+			name:   "npy Fortran order",
+			format: "npy",
+			input:  strings.Replace(string(npyFile(1, "<i4", "1, 1", make([]byte, 4))), "False", "True ", 1),
+			err:    "Fortran-ordered arrays are not supported",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sequences, err := readTokenSequences(strings.NewReader(tt.input), tt.format)
+			if tt.err != "" {
+				assert.ErrorContains(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, sequences)
+		})
+	}
+}
+
+func newDecodeTokenizer(t *testing.T) *tokenizer.Tokenizer {
+	data, err := json.Marshal(map[string]int{
+		"<Doc>": 200001, "</Doc>": 200002,
+		"<Item>": 200003, "</Item>": 200004,
+		"##id":                  200100,
+		tokenizer.TokenValueEnd: 200200,
+	})
+	require.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "vocab.json")
+	require.NoError(t, os.WriteFile(path, data, 0o644))
+	tok, err := tokenizer.NewTokenizer(path)
+	require.NoError(t, err)
+	return tok
+}
+
+const decodeDoc = `<Doc id="d1"><Item>Hello</Item><Item>World</Item></Doc>`
+
+func TestReadTokenSequences_ServerNPY(t *testing.T) {
+	tok := newDecodeTokenizer(t)
+	ts := httptest.NewServer(server.New(tok))
+	defer ts.Close()
+	res, err := tok.Tokenize(strings.NewReader(decodeDoc))
+	require.NoError(t, err)
+
+	fetch := func(query string) []byte {
+		resp, err := http.Post(ts.URL+"/tokenize?"+query, "application/xml", strings.NewReader(decodeDoc))
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return body
+	}
+
+	sequences, err := readTokenSequences(bytes.NewReader(fetch("format=npy")), "auto")
+	require.NoError(t, err)
+	assert.Equal(t, [][]int{res.Tokens}, sequences)
+
+	// The rows of a 2D array are sequences, without their -1 padding.
+	sequences, err = readTokenSequences(bytes.NewReader(fetch("format=npy&array=padded_paths")), "auto")
+	require.NoError(t, err)
+	require.Len(t, sequences, len(res.Tokens))
+	for i, row := range res.PaddedPaths {
+		assert.Equal(t, dropPadding(append([]int(nil), row...)), sequences[i], "row %d", i)
+	}
+
+	// /tokenize JSON responses give their tokens.
+	sequences, err = readTokenSequences(bytes.NewReader(fetch("")), "auto")
+	require.NoError(t, err)
+	assert.Equal(t, [][]int{res.Tokens}, sequences)
+}
+
+func TestDecodeSequence(t *testing.T) {
+	tok := newDecodeTokenizer(t)
+	res, err := tok.Tokenize(strings.NewReader(decodeDoc))
+	require.NoError(t, err)
+	truncated := res.Tokens[:len(res.Tokens)-3]
+
+	tests := []struct {
+		name     string
+		raw      bool
+		strict   bool
+		tokens   []int
+		expected string
+		err      string
+	}{
+		{name: "well-formed", tokens: res.Tokens, expected: "<Item>Hello</Item>"},
+		{name: "strict well-formed", strict: true, tokens: res.Tokens, expected: "<Item>World</Item>"},
+		{name: "repaired", tokens: truncated, expected: "</Doc>"},
+		{name: "strict truncated", strict: true, tokens: truncated, err: "malformed sequence"},
+		{name: "raw", raw: true, tokens: res.Tokens, expected: tok.Decode(res.Tokens) + "\n"},
+		{name: "raw truncated", raw: true, strict: true, tokens: truncated, expected: tok.Decode(truncated) + "\n"},
+		{name: "empty", tokens: []int{}, err: "no element to decode"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decodeRaw, decodeStrict = tt.raw, tt.strict
+			t.Cleanup(func() { decodeRaw, decodeStrict = false, false })
+
+			var out bytes.Buffer
+			err := decodeSequence(&out, tok, 1, tt.tokens)
+			if tt.err != "" {
+				assert.ErrorContains(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Contains(t, out.String(), tt.expected)
+		})
+	}
+}
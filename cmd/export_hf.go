@@ -0,0 +1,72 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+
+	"github.com/clems4ever/arbor-encoder/tokenizer"
+	"github.com/spf13/cobra"
+)
+
+var (
+	hfOutput   string
+	hfBPERanks string
+)
+
+var exportHFCmd = &cobra.Command{
+	Use:   "export-hf",
+	Short: "Export the vocabulary as a Hugging Face tokenizer.json",
+	Long: `Write the content BPE, the cl100k_base special tokens and the tokens of the
+vocab file as a Hugging Face tokenizer.json, so that PreTrainedTokenizerFast
+decodes token IDs like the tokenizer does. Content tokens spelled like a
+vocab token cannot keep their ID in the export and are listed on stderr.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		tok, err := newExportTokenizer()
+		if err != nil {
+			fmt.Printf("Error creating tokenizer: %v\n", err)
+			os.Exit(1)
+		}
+
+		var buf bytes.Buffer
+		shadowed, err := tok.WriteHFTokenizer(&buf)
+		if err != nil {
+			fmt.Printf("Error exporting: %v\n", err)
+			os.Exit(1)
+		}
+		if err := os.WriteFile(hfOutput, buf.Bytes(), 0o644); err != nil {
+			fmt.Printf("Error writing %s: %v\n", hfOutput, err)
+			os.Exit(1)
+		}
+		if len(shadowed) > 0 {
+			fmt.Fprintf(os.Stderr, "Warning: content tokens %v share their string with a vocab token and are left out\n", shadowed)
+		}
+		fmt.Printf("Wrote %s\n", hfOutput)
+	},
+}
+
+// newExportTokenizer creates the tokenizer from --vocab, with the BPE ranks of
+// --bpe-ranks when set.
+func newExportTokenizer() (*tokenizer.Tokenizer, error) {
+	if hfBPERanks == "" {
+		return tokenizer.NewTokenizer(vocabPath)
+	}
+	vocab, err := os.ReadFile(vocabPath)
+	if err != nil {
+		return nil, err
+	}
+	ranks, err := os.ReadFile(hfBPERanks)
+	if err != nil {
+		return nil, err
+	}
+	return tokenizer.NewTokenizerFromBytes(vocab, ranks)
+}
+
+func init() {
+	rootCmd.AddCommand(exportHFCmd)
+
+	exportHFCmd.Flags().StringVarP(&vocabPath, "vocab", "v", "examples/vocab.json", "Path to vocabulary file")
+	exportHFCmd.Flags().StringVarP(&hfOutput, "output", "o", "tokenizer.json", "Path of the tokenizer.json to write")
+	exportHFCmd.Flags().StringVar(&hfBPERanks, "bpe-ranks", "", "Path to cl100k_base.tiktoken, instead of the tiktoken download")
+}
@@ -0,0 +1,275 @@
+package cmd
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/clems4ever/arbor-encoder/tokenizer"
+	"github.com/spf13/cobra"
+)
+
+var (
+	inspectView  string
+	inspectColor string
+	inspectHTML  bool
+)
+
+var inspectCmd = &cobra.Command{
+	Use:   "inspect [file]",
+	Short: "Show the tokens, kinds and paths of a file as a table and a tree",
+	Long: `Tokenize a file and print a table of the tokens with their index, ID,
+kind, decoded string and path, followed by the tree they encode, each node
+annotated with its path and token span. --view selects the table, the tree
+or both. --color colors tokens by kind, and --html writes a standalone HTML
+report to stdout instead.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		f, err := os.Open(args[0])
+		if err != nil {
+			fmt.Printf("Error opening file: %v\n", err)
+			os.Exit(1)
+		}
+		defer f.Close()
+
+		opts, err := tokenizerOptions()
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		tok, err := tokenizer.NewTokenizer(vocabPath, opts...)
+		if err != nil {
+			fmt.Printf("Error creating tokenizer: %v\n", err)
+			os.Exit(1)
+		}
+		res, err := tokenizeInput(tok, f, inputFormat)
+		if err != nil {
+			fmt.Printf("Error tokenizing: %v\n", err)
+			os.Exit(1)
+		}
+		ins := tok.Inspect(res)
+
+		var table, tree bool
+		switch inspectView {
+		case "all":
+			table, tree = true, true
+		case "table":
+			table = true
+		case "tree":
+			tree = true
+		default:
+			fmt.Printf("Error: unknown --view %q (expected all, table or tree)\n", inspectView)
+			os.Exit(1)
+		}
+
+		if inspectHTML {
+			if err := writeInspectionHTML(os.Stdout, args[0], ins, table, tree); err != nil {
+				fmt.Printf("Error writing HTML: %v\n", err)
+				os.Exit(1)
+			}
+			return
+		}
+
+		var color bool
+		switch inspectColor {
+		case "auto":
+			color = isTerminal(os.Stdout)
+		case "always":
+			color = true
+		case "never":
+		default:
+			fmt.Printf("Error: unknown --color %q (expected auto, always or never)\n", inspectColor)
+			os.Exit(1)
+		}
+		if table {
+			writeTokenTable(os.Stdout, ins.Tokens, color)
+		}
+		if table && tree {
+			fmt.Println()
+		}
+		if tree {
+			for _, root := range ins.Roots {
+				writeTree(os.Stdout, root, "", "", color)
+			}
+		}
+	},
+}
+
+// kindColors are the ANSI colors of the token kinds; the others are not
+// colored.
+var kindColors = map[tokenizer.TokenKind]string{
+	tokenizer.KindStartTag:            "34",
+	tokenizer.KindEndTag:              "34",
+	tokenizer.KindRegisteredAttr:      "33",
+	tokenizer.KindUnregisteredAttr:    "33",
+	tokenizer.KindUnregisteredAttrEnd: "33",
+	tokenizer.KindKey:                 "36",
+	tokenizer.KindKeyEnd:              "36",
+	tokenizer.KindValue:               "36",
+	tokenizer.KindValueEnd:            "36",
+	tokenizer.KindContent:             "32",
+	tokenizer.KindTypedValue:          "35",
+	tokenizer.KindTypedValueEnd:       "35",
+	tokenizer.KindBoolean:             "35",
+	tokenizer.KindOrdered:             "35",
+	tokenizer.KindUnordered:           "35",
+	tokenizer.KindUnknown:             "31",
+}
+
+func colorize(s string, kind tokenizer.TokenKind, color bool) string {
+	code, ok := kindColors[kind]
+	if !color || !ok {
+		return s
+	}
+	return "\x1b[" + code + "m" + s + "\x1b[0m"
+}
+
+// tokenText renders a token for display, quoting content so that spaces and
+// newlines show.
+func tokenText(info tokenizer.TokenInfo) string {
+	if info.Kind == tokenizer.KindContent {
+		return strconv.Quote(info.Text)
+	}
+	return info.Text
+}
+
+func formatPath(path []int) string {
+	return fmt.Sprint(path)
+}
+
+// writeTokenTable writes one aligned row per token.
+func writeTokenTable(w io.Writer, tokens []tokenizer.TokenInfo, color bool) {
+	header := []string{"INDEX", "ID", "KIND", "TOKEN", "PATH"}
+	rows := make([][]string, len(tokens))
+	widths := make([]int, len(header))
+	for i, h := range header {
+		widths[i] = len(h)
+	}
+	for i, info := range tokens {
+		rows[i] = []string{strconv.Itoa(info.Index), strconv.Itoa(info.ID), info.Kind.String(), tokenText(info), formatPath(info.Path)}
+		for j, cell := range rows[i] {
+			widths[j] = max(widths[j], len([]rune(cell)))
+		}
+	}
+
+	// Cells are padded before being colored so escape codes do not count
+	// towards the widths.
+	pad := func(s string, width int) string {
+		return s + strings.Repeat(" ", width-len([]rune(s)))
+	}
+	var line strings.Builder
+	for j, h := range header {
+		line.WriteString(pad(h, widths[j]) + "  ")
+	}
+	fmt.Fprintln(w, strings.TrimRight(line.String(), " "))
+	for i, row := range rows {
+		line.Reset()
+		for j, cell := range row {
+			cell = pad(cell, widths[j])
+			if j == 2 || j == 3 {
+				cell = colorize(cell, tokens[i].Kind, color)
+			}
+			line.WriteString(cell + "  ")
+		}
+		fmt.Fprintln(w, strings.TrimRight(line.String(), " "))
+	}
+}
+
+// nodeLabel renders a tree node: elements as tags, attributes with an @ and
+// text quoted.
+func nodeLabel(n *tokenizer.InspectNode) string {
+	switch n.Kind {
+	case tokenizer.KindStartTag:
+		return "<" + n.Label + ">"
+	case tokenizer.KindRegisteredAttr, tokenizer.KindUnregisteredAttr:
+		return "@" + n.Label
+	case tokenizer.KindContent:
+		return strconv.Quote(n.Label)
+	}
+	return n.Label
+}
+
+func nodeSpan(n *tokenizer.InspectNode) string {
+	if n.Start == n.End {
+		return fmt.Sprintf("token %d", n.Start)
+	}
+	return fmt.Sprintf("tokens %d-%d", n.Start, n.End)
+}
+
+// writeTree draws n and its children with box-drawing branches. prefix is
+// written before the node and childPrefix before the lines of its children.
+func writeTree(w io.Writer, n *tokenizer.InspectNode, prefix, childPrefix string, color bool) {
+	fmt.Fprintf(w, "%s%s %s %s\n", prefix, colorize(nodeLabel(n), n.Kind, color), formatPath(n.Path), nodeSpan(n))
+	for i, c := range n.Children {
+		if i == len(n.Children)-1 {
+			writeTree(w, c, childPrefix+"└── ", childPrefix+"    ", color)
+		} else {
+			writeTree(w, c, childPrefix+"├── ", childPrefix+"│   ", color)
+		}
+	}
+}
+
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+var inspectTemplate = template.Must(template.New("inspect").Funcs(template.FuncMap{
+	"label": nodeLabel,
+	"span":  nodeSpan,
+	"path":  formatPath,
+	"text":  tokenText,
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: ui-monospace, monospace; font-size: 13px; margin: 2em; }
+table { border-collapse: collapse; }
+th, td { padding: 2px 10px; text-align: left; white-space: pre; }
+tr:nth-child(even) { background: #f6f6f6; }
+ul { list-style: none; padding-left: 1.5em; border-left: 1px solid #ddd; }
+.meta { color: #888; }
+.start, .end { color: #1f4fd1; }
+.attr, .unregistered-attr, .unregistered-attr-end { color: #a66300; }
+.key, .key-end, .value, .value-end { color: #00838f; }
+.content { color: #2e7d32; }
+.typed, .typed-end, .boolean, .ordered, .unordered { color: #8e24aa; }
+.unknown { color: #c62828; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+{{if .Table}}<table>
+<tr><th>Index</th><th>ID</th><th>Kind</th><th>Token</th><th>Path</th></tr>
+{{range .Tokens}}<tr id="t{{.Index}}"><td>{{.Index}}</td><td>{{.ID}}</td><td class="{{.Kind}}">{{.Kind}}</td><td class="{{.Kind}}">{{text .}}</td><td>{{path .Path}}</td></tr>
+{{end}}</table>
+{{end}}{{if .Tree}}<ul>{{range .Roots}}{{template "node" .}}{{end}}</ul>
+{{end}}</body>
+</html>
+{{define "node"}}<li><span class="{{.Kind}}">{{label .}}</span> <span class="meta">{{path .Path}} <a href="#t{{.Start}}">{{span .}}</a></span>{{if .Children}}<ul>{{range .Children}}{{template "node" .}}{{end}}</ul>{{end}}</li>
+{{end}}`))
+
+// writeInspectionHTML writes the inspection as a standalone HTML page. Tree
+// nodes link to the table row of their first token.
+func writeInspectionHTML(w io.Writer, title string, ins *tokenizer.Inspection, table, tree bool) error {
+	return inspectTemplate.Execute(w, struct {
+		Title       string
+		Table, Tree bool
+		*tokenizer.Inspection
+	}{title, table, tree, ins})
+}
+
+func init() {
+	rootCmd.AddCommand(inspectCmd)
+
+	addTokenizerFlags(inspectCmd)
+	inspectCmd.Flags().StringVarP(&inputFormat, "input-format", "f", "xml", "Input format: xml, html, markdown, json or yaml")
+	inspectCmd.Flags().StringVar(&inspectView, "view", "all", "What to print: all, table or tree")
+	inspectCmd.Flags().StringVar(&inspectColor, "color", "auto", "Color tokens by kind: auto, always or never")
+	inspectCmd.Flags().BoolVar(&inspectHTML, "html", false, "Write a standalone HTML report instead of text")
+}
@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/clems4ever/arbor-encoder/tokenizer"
+	"github.com/spf13/cobra"
+)
+
+var (
+	schemaStartID  int
+	schemaRules    bool
+	schemaValidate []string
+)
+
+var schemaCmd = &cobra.Command{
+	Use:   "schema [file]",
+	Short: "Derive a vocab or ordering rules from an XSD or DTD",
+	Long: `Read an XSD or DTD and print a vocab with the tokens of every declared
+element and attribute, or the ordering rules derived from xs:all and
+sequence groups with --rules. With --validate, check documents against the
+schema instead and print the violations with their line numbers.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		schema, err := tokenizer.LoadSchema(args[0])
+		if err != nil {
+			fmt.Printf("Error loading schema: %v\n", err)
+			os.Exit(1)
+		}
+
+		if len(schemaValidate) > 0 {
+			failed := false
+			for _, path := range schemaValidate {
+				if err := validateFile(schema, path); err != nil {
+					fmt.Printf("%s: %v\n", path, err)
+					failed = true
+				}
+			}
+			if failed {
+				os.Exit(1)
+			}
+			return
+		}
+
+		var out interface{} = schema.Vocab(schemaStartID)
+		if schemaRules {
+			out = schema.OrderingRules()
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetEscapeHTML(false)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(out); err != nil {
+			fmt.Printf("Error encoding: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+func validateFile(schema *tokenizer.Schema, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return schema.Validate(f)
+}
+
+func init() {
+	rootCmd.AddCommand(schemaCmd)
+
+	schemaCmd.Flags().IntVar(&schemaStartID, "start-id", tokenizer.Cl100kBaseMaxID, "First token ID of the vocab")
+	schemaCmd.Flags().BoolVar(&schemaRules, "rules", false, "Print the ordering rules instead of the vocab")
+	schemaCmd.Flags().StringSliceVar(&schemaValidate, "validate", nil, "Validate these XML files against the schema")
+}
@@ -0,0 +1,136 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/clems4ever/arbor-encoder/grpcserver"
+	"github.com/clems4ever/arbor-encoder/grpcserver/arborpb"
+	"github.com/clems4ever/arbor-encoder/server"
+	"github.com/clems4ever/arbor-encoder/tokenizer"
+	"github.com/spf13/cobra"
+	"google.golang.org/grpc"
+)
+
+var (
+	serveAddr          string
+	serveMaxBodyBytes  int64
+	serveMaxDepth      int
+	serveMaxConcurrent int
+	serveGRPCAddr      string
+	serveGRPCWorkers   int
+)
+
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Serve tokenization over HTTP",
+	Long: `Serve the tokenizer over HTTP for services written in other languages.
+
+  GET  /healthz     health check
+  GET  /vocab       vocab and size
+  POST /tokenize    XML body; JSON response, or NumPy with ?format=npy
+                    (&array=tokens or padded_paths) or ?format=npz
+  POST /decode      {"tokens": [...]}
+  POST /decode-xml  {"tokens": [...], "repair": true}
+
+With --grpc-addr, the arbor.v1.Tokenizer gRPC service of
+proto/arbor/v1/tokenizer.proto is served as well.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		opts, err := tokenizerOptions()
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		tok, err := tokenizer.NewTokenizer(vocabPath, opts...)
+		if err != nil {
+			fmt.Printf("Error creating tokenizer: %v\n", err)
+			os.Exit(1)
+		}
+
+		srv := &http.Server{
+			Addr: serveAddr,
+			Handler: server.New(tok,
+				server.WithMaxBodyBytes(serveMaxBodyBytes),
+				server.WithMaxDepth(serveMaxDepth),
+				server.WithMaxConcurrent(serveMaxConcurrent),
+			),
+			ReadHeaderTimeout: 10 * time.Second,
+		}
+
+		var grpcSrv *grpc.Server
+		if serveGRPCAddr != "" {
+			lis, err := net.Listen("tcp", serveGRPCAddr)
+			if err != nil {
+				fmt.Printf("Error listening: %v\n", err)
+				os.Exit(1)
+			}
+			var opts []grpcserver.Option
+			if serveGRPCWorkers > 0 {
+				opts = append(opts, grpcserver.WithWorkers(serveGRPCWorkers))
+			}
+			grpcSrv = grpc.NewServer(grpc.MaxRecvMsgSize(int(serveMaxBodyBytes)))
+			arborpb.RegisterTokenizerServer(grpcSrv, grpcserver.New(tok, opts...))
+			go func() {
+				fmt.Printf("Serving gRPC on %s\n", serveGRPCAddr)
+				if err := grpcSrv.Serve(lis); err != nil {
+					fmt.Printf("Error serving gRPC: %v\n", err)
+					os.Exit(1)
+				}
+			}()
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		// ListenAndServe returns as soon as Shutdown starts, so done tells
+		// when the in-flight requests have been drained.
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			<-ctx.Done()
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			if err := srv.Shutdown(shutdownCtx); err != nil {
+				fmt.Printf("Error shutting down: %v\n", err)
+			}
+			if grpcSrv != nil {
+				stopped := make(chan struct{})
+				go func() {
+					grpcSrv.GracefulStop()
+					close(stopped)
+				}()
+				select {
+				case <-stopped:
+				case <-shutdownCtx.Done():
+					grpcSrv.Stop()
+				}
+			}
+		}()
+
+		fmt.Printf("Listening on %s\n", serveAddr)
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			fmt.Printf("Error serving: %v\n", err)
+			os.Exit(1)
+		}
+		<-done
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(serveCmd)
+
+	addTokenizerFlags(serveCmd)
+	serveCmd.Flags().StringVar(&serveAddr, "addr", ":8080", "Address to listen on")
+	serveCmd.Flags().Int64Var(&serveMaxBodyBytes, "max-body-bytes", 10<<20, "Reject request bodies larger than this")
+	serveCmd.Flags().IntVar(&serveMaxDepth, "max-depth", 256, "Reject documents whose elements nest deeper than this")
+	serveCmd.Flags().IntVar(&serveMaxConcurrent, "max-concurrent", 0, "Requests tokenized or decoded at once (0 for no limit)")
+	serveCmd.Flags().StringVar(&serveGRPCAddr, "grpc-addr", "", "Also serve gRPC on this address")
+	serveCmd.Flags().IntVar(&serveGRPCWorkers, "grpc-workers", 0, "Documents of one gRPC stream tokenized at once (0 for the number of CPUs)")
+}
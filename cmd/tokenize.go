@@ -1,20 +1,40 @@
 package cmd
 
 import (
+	"bytes"
 	"fmt"
+	"io"
 	"os"
+	"strings"
 
 	"github.com/clems4ever/arbor-encoder/tokenizer"
 	"github.com/spf13/cobra"
 )
 
-var vocabPath string
+var (
+	vocabPath      string
+	inputFormat    string
+	typedValues    bool
+	setAttrs       bool
+	attributeOrder string
+	orderingRules  string
+	schemaPath     string
+	defaultOrder   string
+	inheritOrder   bool
+
+	htmlRawText      string
+	htmlRawTextLimit int
+	htmlRemoveHidden bool
+	htmlAllowTags    []string
+	htmlDenyTags     []string
+)
 
 var tokenizeCmd = &cobra.Command{
-	Use:   "tokenize [xml_file]",
-	Short: "Tokenize an XML file",
-	Long:  `Tokenize an XML file and print the tokens and path embeddings.`,
-	Args:  cobra.ExactArgs(1),
+	Use:   "tokenize [file]",
+	Short: "Tokenize an XML, HTML, Markdown, JSON or YAML file",
+	Long: `Tokenize a file and print the tokens and path embeddings.
+HTML, Markdown, JSON and YAML inputs are converted to XML first, see --input-format.`,
+	Args: cobra.ExactArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
 		f, err := os.Open(args[0])
 		if err != nil {
@@ -23,27 +43,195 @@ var tokenizeCmd = &cobra.Command{
 		}
 		defer f.Close()
 
-		tok, err := tokenizer.NewTokenizer(vocabPath)
+		var input io.Reader = f
+		if schemaPath != "" && strings.ToLower(inputFormat) == "xml" {
+			input, err = validateInput(f)
+			if err != nil {
+				fmt.Printf("Error validating: %v\n", err)
+				os.Exit(1)
+			}
+		}
+
+		opts, err := tokenizerOptions()
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		tok, err := tokenizer.NewTokenizer(vocabPath, opts...)
 		if err != nil {
 			fmt.Printf("Error creating tokenizer: %v\n", err)
 			os.Exit(1)
 		}
 
-		res, err := tok.Tokenize(f)
+		res, err := tokenizeInput(tok, input, inputFormat)
 		if err != nil {
 			fmt.Printf("Error tokenizing: %v\n", err)
 			os.Exit(1)
 		}
 		fmt.Printf("Tokens (%d): %v\n", len(res.Tokens), res.Tokens)
 		fmt.Printf("PaddedPaths (%d): %v\n", len(res.PaddedPaths), res.PaddedPaths)
+		for _, l := range res.Labels {
+			fmt.Printf("Label %q: tokens %d-%d, path %v\n", l.Label, l.Start, l.End, l.Path)
+		}
 
 		decoded := tok.Decode(res.Tokens)
 		fmt.Printf("Decoded: %s\n", decoded)
 	},
 }
 
+// tokenizeInput converts the input to an Element tree according to its
+// format and tokenizes it.
+func tokenizeInput(tok *tokenizer.Tokenizer, r io.Reader, format string) (*tokenizer.TokenizationResult, error) {
+	switch strings.ToLower(format) {
+	case "xml":
+		return tok.Tokenize(r)
+	case "html":
+		opts, err := htmlOptions()
+		if err != nil {
+			return nil, err
+		}
+		el, err := tokenizer.ConvertHTMLToElement(r, opts...)
+		if err != nil {
+			return nil, err
+		}
+		return tok.TokenizeElement(el)
+	case "markdown", "md":
+		el, err := tokenizer.ConvertMarkdownToElement(r)
+		if err != nil {
+			return nil, err
+		}
+		return tok.TokenizeElement(el)
+	case "json":
+		el, err := tokenizer.ConvertJSONToElement(r)
+		if err != nil {
+			return nil, err
+		}
+		return tok.TokenizeElement(el)
+	case "yaml", "yml":
+		el, err := tokenizer.ConvertYAMLToElement(r)
+		if err != nil {
+			return nil, err
+		}
+		return tok.TokenizeElement(el)
+	}
+	return nil, fmt.Errorf("unknown input format %q (expected xml, html, markdown, json or yaml)", format)
+}
+
+// tokenizerOptions builds the tokenizer options from the command flags.
+func tokenizerOptions() ([]tokenizer.Option, error) {
+	var opts []tokenizer.Option
+	if typedValues {
+		opts = append(opts, tokenizer.WithTypedValues(nil))
+	}
+	if setAttrs {
+		opts = append(opts, tokenizer.WithSetAttributes())
+	}
+	switch {
+	case schemaPath != "":
+		// The rules file refines the ordering derived from the schema.
+		schema, err := tokenizer.LoadSchema(schemaPath)
+		if err != nil {
+			return nil, err
+		}
+		rules := schema.OrderingRules()
+		if orderingRules != "" {
+			fileRules, err := tokenizer.LoadOrderingRules(orderingRules)
+			if err != nil {
+				return nil, err
+			}
+			rules.Rules = append(rules.Rules, fileRules.Rules...)
+		}
+		opts = append(opts, tokenizer.WithOrderingRules(rules))
+	case orderingRules != "":
+		opts = append(opts, tokenizer.WithOrderingRulesFile(orderingRules))
+	}
+	switch defaultOrder {
+	case "unordered":
+	case "ordered":
+		opts = append(opts, tokenizer.WithDefaultOrdering(tokenizer.ChildrenOrdered))
+	default:
+		return nil, fmt.Errorf("unknown --default-ordering %q (expected ordered or unordered)", defaultOrder)
+	}
+	if inheritOrder {
+		opts = append(opts, tokenizer.WithInheritedOrdering())
+	}
+	switch attributeOrder {
+	case "sorted":
+	case "source":
+		opts = append(opts, tokenizer.WithAttributeOrder(tokenizer.AttributesSourceOrder))
+	case "unordered":
+		opts = append(opts, tokenizer.WithAttributeOrder(tokenizer.AttributesUnordered))
+	default:
+		return nil, fmt.Errorf("unknown --attribute-order %q (expected sorted, source or unordered)", attributeOrder)
+	}
+	return opts, nil
+}
+
+// validateInput checks an XML input against --schema and returns it for
+// tokenization.
+func validateInput(r io.Reader) (io.Reader, error) {
+	schema, err := tokenizer.LoadSchema(schemaPath)
+	if err != nil {
+		return nil, err
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if err := schema.Validate(bytes.NewReader(data)); err != nil {
+		return nil, err
+	}
+	return bytes.NewReader(data), nil
+}
+
+// htmlOptions builds the HTML converter options from the command flags.
+func htmlOptions() ([]tokenizer.HTMLOption, error) {
+	var mode tokenizer.RawTextMode
+	switch htmlRawText {
+	case "keep":
+		mode = tokenizer.RawTextKeep
+	case "drop":
+		mode = tokenizer.RawTextDrop
+	case "wrap":
+		mode = tokenizer.RawTextWrap
+	default:
+		return nil, fmt.Errorf("unknown --html-raw-text mode %q (expected keep, drop or wrap)", htmlRawText)
+	}
+
+	opts := []tokenizer.HTMLOption{tokenizer.WithRawText(mode, htmlRawTextLimit)}
+	if htmlRemoveHidden {
+		opts = append(opts, tokenizer.WithoutHiddenElements())
+	}
+	if len(htmlAllowTags) > 0 {
+		opts = append(opts, tokenizer.WithAllowedTags(htmlAllowTags...))
+	}
+	if len(htmlDenyTags) > 0 {
+		opts = append(opts, tokenizer.WithDeniedTags(htmlDenyTags...))
+	}
+	return opts, nil
+}
+
+// addTokenizerFlags registers the flags read by tokenizerOptions, shared by
+// the commands that create a Tokenizer.
+func addTokenizerFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVarP(&vocabPath, "vocab", "v", "examples/vocab.json", "Path to vocabulary file")
+	cmd.Flags().BoolVar(&typedValues, "typed-values", false, "Encode numbers, booleans and dates with typed value tokens")
+	cmd.Flags().BoolVar(&setAttrs, "set-attributes", false, "Split set-valued attributes such as class and rel into unordered items")
+	cmd.Flags().StringVar(&attributeOrder, "attribute-order", "sorted", "Attribute order: sorted, source or unordered")
+	cmd.Flags().StringVar(&orderingRules, "ordering-rules", "", "Path to a JSON file of ordering rules")
+	cmd.Flags().StringVar(&defaultOrder, "default-ordering", "unordered", "Ordering of elements without arbor-ordered: ordered or unordered")
+	cmd.Flags().BoolVar(&inheritOrder, "inherit-ordering", false, "Elements without arbor-ordered take the ordering of their parent")
+}
+
 func init() {
 	rootCmd.AddCommand(tokenizeCmd)
 
-	tokenizeCmd.Flags().StringVarP(&vocabPath, "vocab", "v", "examples/vocab.json", "Path to vocabulary file")
+	addTokenizerFlags(tokenizeCmd)
+	tokenizeCmd.Flags().StringVarP(&inputFormat, "input-format", "f", "xml", "Input format: xml, html, markdown, json or yaml")
+	tokenizeCmd.Flags().StringVar(&schemaPath, "schema", "", "Path to an XSD or DTD: validate XML input and derive the element ordering")
+	tokenizeCmd.Flags().StringVar(&htmlRawText, "html-raw-text", "keep", "HTML script and style bodies: keep, drop or wrap")
+	tokenizeCmd.Flags().IntVar(&htmlRawTextLimit, "html-raw-text-limit", 0, "Truncate HTML script and style bodies to this many characters (0 for no limit)")
+	tokenizeCmd.Flags().BoolVar(&htmlRemoveHidden, "html-remove-hidden", false, "Drop hidden HTML elements")
+	tokenizeCmd.Flags().StringSliceVar(&htmlAllowTags, "html-allow", nil, "Keep only these HTML tags, unwrapping the others")
+	tokenizeCmd.Flags().StringSliceVar(&htmlDenyTags, "html-deny", nil, "Drop these HTML tags and their content")
 }
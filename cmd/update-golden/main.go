@@ -39,5 +39,33 @@ func main() {
 		}
 	}
 
+	mdInputs, err := filepath.Glob("tokenizer/testdata/*.md")
+	if err != nil {
+		log.Fatalf("Failed to glob files: %v", err)
+	}
+
+	for _, inputFile := range mdInputs {
+		outputFile := strings.TrimSuffix(inputFile, ".md") + "_md.xml"
+
+		fmt.Printf("Processing %s -> %s\n", inputFile, outputFile)
+		f, err := os.Open(inputFile)
+		if err != nil {
+			log.Printf("Failed to read input file %s: %v", inputFile, err)
+			continue
+		}
+
+		converted, err := tokenizer.ConvertMarkdownToXML(f)
+		f.Close()
+		if err != nil {
+			log.Printf("Conversion failed for %s: %v", inputFile, err)
+			continue
+		}
+
+		if err := os.WriteFile(outputFile, []byte(converted+"\n"), 0644); err != nil {
+			log.Printf("Failed to write output file %s: %v", outputFile, err)
+			continue
+		}
+	}
+
 	fmt.Println("Done. Golden files updated.")
 }
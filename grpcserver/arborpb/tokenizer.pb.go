@@ -0,0 +1,546 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.12
+// 	protoc        (unknown)
+// source: arbor/v1/tokenizer.proto
+
+package arborpb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type TokenizeRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// id is chosen by the client and copied to the response.
+	Id string `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	// document is an XML document.
+	Document      []byte `protobuf:"bytes,2,opt,name=document,proto3" json:"document,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TokenizeRequest) Reset() {
+	*x = TokenizeRequest{}
+	mi := &file_arbor_v1_tokenizer_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TokenizeRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TokenizeRequest) ProtoMessage() {}
+
+func (x *TokenizeRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_arbor_v1_tokenizer_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TokenizeRequest.ProtoReflect.Descriptor instead.
+func (*TokenizeRequest) Descriptor() ([]byte, []int) {
+	return file_arbor_v1_tokenizer_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *TokenizeRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *TokenizeRequest) GetDocument() []byte {
+	if x != nil {
+		return x.Document
+	}
+	return nil
+}
+
+type TokenizeResponse struct {
+	state  protoimpl.MessageState `protogen:"open.v1"`
+	Id     string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Tokens []int32                `protobuf:"varint,2,rep,packed,name=tokens,proto3" json:"tokens,omitempty"`
+	// padded_paths holds one row of depth values per token, row-major, padded
+	// with -1: numpy.array(padded_paths).reshape(-1, depth).
+	PaddedPaths []int32  `protobuf:"varint,3,rep,packed,name=padded_paths,json=paddedPaths,proto3" json:"padded_paths,omitempty"`
+	Depth       int32    `protobuf:"varint,4,opt,name=depth,proto3" json:"depth,omitempty"`
+	Labels      []*Label `protobuf:"bytes,5,rep,name=labels,proto3" json:"labels,omitempty"`
+	// error is set by TokenizeStream when the document could not be
+	// tokenized. Tokenize returns an InvalidArgument status instead.
+	Error         string `protobuf:"bytes,6,opt,name=error,proto3" json:"error,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TokenizeResponse) Reset() {
+	*x = TokenizeResponse{}
+	mi := &file_arbor_v1_tokenizer_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TokenizeResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TokenizeResponse) ProtoMessage() {}
+
+func (x *TokenizeResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_arbor_v1_tokenizer_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TokenizeResponse.ProtoReflect.Descriptor instead.
+func (*TokenizeResponse) Descriptor() ([]byte, []int) {
+	return file_arbor_v1_tokenizer_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *TokenizeResponse) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *TokenizeResponse) GetTokens() []int32 {
+	if x != nil {
+		return x.Tokens
+	}
+	return nil
+}
+
+func (x *TokenizeResponse) GetPaddedPaths() []int32 {
+	if x != nil {
+		return x.PaddedPaths
+	}
+	return nil
+}
+
+func (x *TokenizeResponse) GetDepth() int32 {
+	if x != nil {
+		return x.Depth
+	}
+	return 0
+}
+
+func (x *TokenizeResponse) GetLabels() []*Label {
+	if x != nil {
+		return x.Labels
+	}
+	return nil
+}
+
+func (x *TokenizeResponse) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+// Label is an element carrying arbor-label.
+type Label struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Label string                 `protobuf:"bytes,1,opt,name=label,proto3" json:"label,omitempty"`
+	// start and end are the indices in tokens of the start and end tags.
+	Start         int32   `protobuf:"varint,2,opt,name=start,proto3" json:"start,omitempty"`
+	End           int32   `protobuf:"varint,3,opt,name=end,proto3" json:"end,omitempty"`
+	Path          []int32 `protobuf:"varint,4,rep,packed,name=path,proto3" json:"path,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Label) Reset() {
+	*x = Label{}
+	mi := &file_arbor_v1_tokenizer_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Label) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Label) ProtoMessage() {}
+
+func (x *Label) ProtoReflect() protoreflect.Message {
+	mi := &file_arbor_v1_tokenizer_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Label.ProtoReflect.Descriptor instead.
+func (*Label) Descriptor() ([]byte, []int) {
+	return file_arbor_v1_tokenizer_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *Label) GetLabel() string {
+	if x != nil {
+		return x.Label
+	}
+	return ""
+}
+
+func (x *Label) GetStart() int32 {
+	if x != nil {
+		return x.Start
+	}
+	return 0
+}
+
+func (x *Label) GetEnd() int32 {
+	if x != nil {
+		return x.End
+	}
+	return 0
+}
+
+func (x *Label) GetPath() []int32 {
+	if x != nil {
+		return x.Path
+	}
+	return nil
+}
+
+type DecodeRequest struct {
+	state  protoimpl.MessageState `protogen:"open.v1"`
+	Tokens []int32                `protobuf:"varint,1,rep,packed,name=tokens,proto3" json:"tokens,omitempty"`
+	// xml decodes the tokens into an XML document instead of the space-joined
+	// tokens.
+	Xml bool `protobuf:"varint,2,opt,name=xml,proto3" json:"xml,omitempty"`
+	// repair fixes truncated or malformed sequences before decoding them as
+	// XML, see RepairTokens.
+	Repair        bool `protobuf:"varint,3,opt,name=repair,proto3" json:"repair,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DecodeRequest) Reset() {
+	*x = DecodeRequest{}
+	mi := &file_arbor_v1_tokenizer_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DecodeRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DecodeRequest) ProtoMessage() {}
+
+func (x *DecodeRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_arbor_v1_tokenizer_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DecodeRequest.ProtoReflect.Descriptor instead.
+func (*DecodeRequest) Descriptor() ([]byte, []int) {
+	return file_arbor_v1_tokenizer_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *DecodeRequest) GetTokens() []int32 {
+	if x != nil {
+		return x.Tokens
+	}
+	return nil
+}
+
+func (x *DecodeRequest) GetXml() bool {
+	if x != nil {
+		return x.Xml
+	}
+	return false
+}
+
+func (x *DecodeRequest) GetRepair() bool {
+	if x != nil {
+		return x.Repair
+	}
+	return false
+}
+
+type DecodeResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Text  string                 `protobuf:"bytes,1,opt,name=text,proto3" json:"text,omitempty"`
+	// repairs describes the fixes applied with repair.
+	Repairs       []string `protobuf:"bytes,2,rep,name=repairs,proto3" json:"repairs,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DecodeResponse) Reset() {
+	*x = DecodeResponse{}
+	mi := &file_arbor_v1_tokenizer_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DecodeResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DecodeResponse) ProtoMessage() {}
+
+func (x *DecodeResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_arbor_v1_tokenizer_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DecodeResponse.ProtoReflect.Descriptor instead.
+func (*DecodeResponse) Descriptor() ([]byte, []int) {
+	return file_arbor_v1_tokenizer_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *DecodeResponse) GetText() string {
+	if x != nil {
+		return x.Text
+	}
+	return ""
+}
+
+func (x *DecodeResponse) GetRepairs() []string {
+	if x != nil {
+		return x.Repairs
+	}
+	return nil
+}
+
+type GetVocabRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetVocabRequest) Reset() {
+	*x = GetVocabRequest{}
+	mi := &file_arbor_v1_tokenizer_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetVocabRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetVocabRequest) ProtoMessage() {}
+
+func (x *GetVocabRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_arbor_v1_tokenizer_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetVocabRequest.ProtoReflect.Descriptor instead.
+func (*GetVocabRequest) Descriptor() ([]byte, []int) {
+	return file_arbor_v1_tokenizer_proto_rawDescGZIP(), []int{5}
+}
+
+type GetVocabResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Vocab map[string]int32       `protobuf:"bytes,1,rep,name=vocab,proto3" json:"vocab,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"varint,2,opt,name=value"`
+	// content_max_id is the bound below which IDs are content tokens.
+	ContentMaxId  int32 `protobuf:"varint,2,opt,name=content_max_id,json=contentMaxId,proto3" json:"content_max_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetVocabResponse) Reset() {
+	*x = GetVocabResponse{}
+	mi := &file_arbor_v1_tokenizer_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetVocabResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetVocabResponse) ProtoMessage() {}
+
+func (x *GetVocabResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_arbor_v1_tokenizer_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetVocabResponse.ProtoReflect.Descriptor instead.
+func (*GetVocabResponse) Descriptor() ([]byte, []int) {
+	return file_arbor_v1_tokenizer_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *GetVocabResponse) GetVocab() map[string]int32 {
+	if x != nil {
+		return x.Vocab
+	}
+	return nil
+}
+
+func (x *GetVocabResponse) GetContentMaxId() int32 {
+	if x != nil {
+		return x.ContentMaxId
+	}
+	return 0
+}
+
+var File_arbor_v1_tokenizer_proto protoreflect.FileDescriptor
+
+const file_arbor_v1_tokenizer_proto_rawDesc = "" +
+	"\n" +
+	"\x18arbor/v1/tokenizer.proto\x12\barbor.v1\"=\n" +
+	"\x0fTokenizeRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x1a\n" +
+	"\bdocument\x18\x02 \x01(\fR\bdocument\"\xb2\x01\n" +
+	"\x10TokenizeResponse\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x16\n" +
+	"\x06tokens\x18\x02 \x03(\x05R\x06tokens\x12!\n" +
+	"\fpadded_paths\x18\x03 \x03(\x05R\vpaddedPaths\x12\x14\n" +
+	"\x05depth\x18\x04 \x01(\x05R\x05depth\x12'\n" +
+	"\x06labels\x18\x05 \x03(\v2\x0f.arbor.v1.LabelR\x06labels\x12\x14\n" +
+	"\x05error\x18\x06 \x01(\tR\x05error\"Y\n" +
+	"\x05Label\x12\x14\n" +
+	"\x05label\x18\x01 \x01(\tR\x05label\x12\x14\n" +
+	"\x05start\x18\x02 \x01(\x05R\x05start\x12\x10\n" +
+	"\x03end\x18\x03 \x01(\x05R\x03end\x12\x12\n" +
+	"\x04path\x18\x04 \x03(\x05R\x04path\"Q\n" +
+	"\rDecodeRequest\x12\x16\n" +
+	"\x06tokens\x18\x01 \x03(\x05R\x06tokens\x12\x10\n" +
+	"\x03xml\x18\x02 \x01(\bR\x03xml\x12\x16\n" +
+	"\x06repair\x18\x03 \x01(\bR\x06repair\">\n" +
+	"\x0eDecodeResponse\x12\x12\n" +
+	"\x04text\x18\x01 \x01(\tR\x04text\x12\x18\n" +
+	"\arepairs\x18\x02 \x03(\tR\arepairs\"\x11\n" +
+	"\x0fGetVocabRequest\"\xaf\x01\n" +
+	"\x10GetVocabResponse\x12;\n" +
+	"\x05vocab\x18\x01 \x03(\v2%.arbor.v1.GetVocabResponse.VocabEntryR\x05vocab\x12$\n" +
+	"\x0econtent_max_id\x18\x02 \x01(\x05R\fcontentMaxId\x1a8\n" +
+	"\n" +
+	"VocabEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\x05R\x05value:\x028\x012\x9b\x02\n" +
+	"\tTokenizer\x12A\n" +
+	"\bTokenize\x12\x19.arbor.v1.TokenizeRequest\x1a\x1a.arbor.v1.TokenizeResponse\x12K\n" +
+	"\x0eTokenizeStream\x12\x19.arbor.v1.TokenizeRequest\x1a\x1a.arbor.v1.TokenizeResponse(\x010\x01\x12;\n" +
+	"\x06Decode\x12\x17.arbor.v1.DecodeRequest\x1a\x18.arbor.v1.DecodeResponse\x12A\n" +
+	"\bGetVocab\x12\x19.arbor.v1.GetVocabRequest\x1a\x1a.arbor.v1.GetVocabResponseB8Z6github.com/clems4ever/arbor-encoder/grpcserver/arborpbb\x06proto3"
+
+var (
+	file_arbor_v1_tokenizer_proto_rawDescOnce sync.Once
+	file_arbor_v1_tokenizer_proto_rawDescData []byte
+)
+
+func file_arbor_v1_tokenizer_proto_rawDescGZIP() []byte {
+	file_arbor_v1_tokenizer_proto_rawDescOnce.Do(func() {
+		file_arbor_v1_tokenizer_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_arbor_v1_tokenizer_proto_rawDesc), len(file_arbor_v1_tokenizer_proto_rawDesc)))
+	})
+	return file_arbor_v1_tokenizer_proto_rawDescData
+}
+
+var file_arbor_v1_tokenizer_proto_msgTypes = make([]protoimpl.MessageInfo, 8)
+var file_arbor_v1_tokenizer_proto_goTypes = []any{
+	(*TokenizeRequest)(nil),  // 0: arbor.v1.TokenizeRequest
+	(*TokenizeResponse)(nil), // 1: arbor.v1.TokenizeResponse
+	(*Label)(nil),            // 2: arbor.v1.Label
+	(*DecodeRequest)(nil),    // 3: arbor.v1.DecodeRequest
+	(*DecodeResponse)(nil),   // 4: arbor.v1.DecodeResponse
+	(*GetVocabRequest)(nil),  // 5: arbor.v1.GetVocabRequest
+	(*GetVocabResponse)(nil), // 6: arbor.v1.GetVocabResponse
+	nil,                      // 7: arbor.v1.GetVocabResponse.VocabEntry
+}
+var file_arbor_v1_tokenizer_proto_depIdxs = []int32{
+	2, // 0: arbor.v1.TokenizeResponse.labels:type_name -> arbor.v1.Label
+	7, // 1: arbor.v1.GetVocabResponse.vocab:type_name -> arbor.v1.GetVocabResponse.VocabEntry
+	0, // 2: arbor.v1.Tokenizer.Tokenize:input_type -> arbor.v1.TokenizeRequest
+	0, // 3: arbor.v1.Tokenizer.TokenizeStream:input_type -> arbor.v1.TokenizeRequest
+	3, // 4: arbor.v1.Tokenizer.Decode:input_type -> arbor.v1.DecodeRequest
+	5, // 5: arbor.v1.Tokenizer.GetVocab:input_type -> arbor.v1.GetVocabRequest
+	1, // 6: arbor.v1.Tokenizer.Tokenize:output_type -> arbor.v1.TokenizeResponse
+	1, // 7: arbor.v1.Tokenizer.TokenizeStream:output_type -> arbor.v1.TokenizeResponse
+	4, // 8: arbor.v1.Tokenizer.Decode:output_type -> arbor.v1.DecodeResponse
+	6, // 9: arbor.v1.Tokenizer.GetVocab:output_type -> arbor.v1.GetVocabResponse
+	6, // [6:10] is the sub-list for method output_type
+	2, // [2:6] is the sub-list for method input_type
+	2, // [2:2] is the sub-list for extension type_name
+	2, // [2:2] is the sub-list for extension extendee
+	0, // [0:2] is the sub-list for field type_name
+}
+
+func init() { file_arbor_v1_tokenizer_proto_init() }
+func file_arbor_v1_tokenizer_proto_init() {
+	if File_arbor_v1_tokenizer_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_arbor_v1_tokenizer_proto_rawDesc), len(file_arbor_v1_tokenizer_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   8,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_arbor_v1_tokenizer_proto_goTypes,
+		DependencyIndexes: file_arbor_v1_tokenizer_proto_depIdxs,
+		MessageInfos:      file_arbor_v1_tokenizer_proto_msgTypes,
+	}.Build()
+	File_arbor_v1_tokenizer_proto = out.File
+	file_arbor_v1_tokenizer_proto_goTypes = nil
+	file_arbor_v1_tokenizer_proto_depIdxs = nil
+}
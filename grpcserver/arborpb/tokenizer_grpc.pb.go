@@ -0,0 +1,252 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: arbor/v1/tokenizer.proto
+
+package arborpb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	Tokenizer_Tokenize_FullMethodName       = "/arbor.v1.Tokenizer/Tokenize"
+	Tokenizer_TokenizeStream_FullMethodName = "/arbor.v1.Tokenizer/TokenizeStream"
+	Tokenizer_Decode_FullMethodName         = "/arbor.v1.Tokenizer/Decode"
+	Tokenizer_GetVocab_FullMethodName       = "/arbor.v1.Tokenizer/GetVocab"
+)
+
+// TokenizerClient is the client API for Tokenizer service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// Tokenizer exposes an arbor-encoder Tokenizer, so data loaders written in
+// other languages can offload tokenization to a Go sidecar.
+type TokenizerClient interface {
+	// Tokenize tokenizes one XML document.
+	Tokenize(ctx context.Context, in *TokenizeRequest, opts ...grpc.CallOption) (*TokenizeResponse, error)
+	// TokenizeStream tokenizes a stream of XML documents concurrently and
+	// returns each result as soon as it is ready, so results may come back in
+	// a different order than the documents: match them with id. A document
+	// that cannot be tokenized gets a response with error set, the stream goes
+	// on.
+	TokenizeStream(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[TokenizeRequest, TokenizeResponse], error)
+	// Decode turns token IDs back into text or into an XML document.
+	Decode(ctx context.Context, in *DecodeRequest, opts ...grpc.CallOption) (*DecodeResponse, error)
+	// GetVocab returns the vocab of the tokenizer.
+	GetVocab(ctx context.Context, in *GetVocabRequest, opts ...grpc.CallOption) (*GetVocabResponse, error)
+}
+
+type tokenizerClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewTokenizerClient(cc grpc.ClientConnInterface) TokenizerClient {
+	return &tokenizerClient{cc}
+}
+
+func (c *tokenizerClient) Tokenize(ctx context.Context, in *TokenizeRequest, opts ...grpc.CallOption) (*TokenizeResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(TokenizeResponse)
+	err := c.cc.Invoke(ctx, Tokenizer_Tokenize_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *tokenizerClient) TokenizeStream(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[TokenizeRequest, TokenizeResponse], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Tokenizer_ServiceDesc.Streams[0], Tokenizer_TokenizeStream_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[TokenizeRequest, TokenizeResponse]{ClientStream: stream}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Tokenizer_TokenizeStreamClient = grpc.BidiStreamingClient[TokenizeRequest, TokenizeResponse]
+
+func (c *tokenizerClient) Decode(ctx context.Context, in *DecodeRequest, opts ...grpc.CallOption) (*DecodeResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DecodeResponse)
+	err := c.cc.Invoke(ctx, Tokenizer_Decode_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *tokenizerClient) GetVocab(ctx context.Context, in *GetVocabRequest, opts ...grpc.CallOption) (*GetVocabResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetVocabResponse)
+	err := c.cc.Invoke(ctx, Tokenizer_GetVocab_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// TokenizerServer is the server API for Tokenizer service.
+// All implementations must embed UnimplementedTokenizerServer
+// for forward compatibility.
+//
+// Tokenizer exposes an arbor-encoder Tokenizer, so data loaders written in
+// other languages can offload tokenization to a Go sidecar.
+type TokenizerServer interface {
+	// Tokenize tokenizes one XML document.
+	Tokenize(context.Context, *TokenizeRequest) (*TokenizeResponse, error)
+	// TokenizeStream tokenizes a stream of XML documents concurrently and
+	// returns each result as soon as it is ready, so results may come back in
+	// a different order than the documents: match them with id. A document
+	// that cannot be tokenized gets a response with error set, the stream goes
+	// on.
+	TokenizeStream(grpc.BidiStreamingServer[TokenizeRequest, TokenizeResponse]) error
+	// Decode turns token IDs back into text or into an XML document.
+	Decode(context.Context, *DecodeRequest) (*DecodeResponse, error)
+	// GetVocab returns the vocab of the tokenizer.
+	GetVocab(context.Context, *GetVocabRequest) (*GetVocabResponse, error)
+	mustEmbedUnimplementedTokenizerServer()
+}
+
+// UnimplementedTokenizerServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedTokenizerServer struct{}
+
+func (UnimplementedTokenizerServer) Tokenize(context.Context, *TokenizeRequest) (*TokenizeResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Tokenize not implemented")
+}
+func (UnimplementedTokenizerServer) TokenizeStream(grpc.BidiStreamingServer[TokenizeRequest, TokenizeResponse]) error {
+	return status.Errorf(codes.Unimplemented, "method TokenizeStream not implemented")
+}
+func (UnimplementedTokenizerServer) Decode(context.Context, *DecodeRequest) (*DecodeResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Decode not implemented")
+}
+func (UnimplementedTokenizerServer) GetVocab(context.Context, *GetVocabRequest) (*GetVocabResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetVocab not implemented")
+}
+func (UnimplementedTokenizerServer) mustEmbedUnimplementedTokenizerServer() {}
+func (UnimplementedTokenizerServer) testEmbeddedByValue()                   {}
+
+// UnsafeTokenizerServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to TokenizerServer will
+// result in compilation errors.
+type UnsafeTokenizerServer interface {
+	mustEmbedUnimplementedTokenizerServer()
+}
+
+func RegisterTokenizerServer(s grpc.ServiceRegistrar, srv TokenizerServer) {
+	// If the following call pancis, it indicates UnimplementedTokenizerServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&Tokenizer_ServiceDesc, srv)
+}
+
+func _Tokenizer_Tokenize_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(TokenizeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TokenizerServer).Tokenize(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Tokenizer_Tokenize_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TokenizerServer).Tokenize(ctx, req.(*TokenizeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Tokenizer_TokenizeStream_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(TokenizerServer).TokenizeStream(&grpc.GenericServerStream[TokenizeRequest, TokenizeResponse]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Tokenizer_TokenizeStreamServer = grpc.BidiStreamingServer[TokenizeRequest, TokenizeResponse]
+
+func _Tokenizer_Decode_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DecodeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TokenizerServer).Decode(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Tokenizer_Decode_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TokenizerServer).Decode(ctx, req.(*DecodeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Tokenizer_GetVocab_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetVocabRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TokenizerServer).GetVocab(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Tokenizer_GetVocab_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TokenizerServer).GetVocab(ctx, req.(*GetVocabRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// Tokenizer_ServiceDesc is the grpc.ServiceDesc for Tokenizer service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Tokenizer_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "arbor.v1.Tokenizer",
+	HandlerType: (*TokenizerServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Tokenize",
+			Handler:    _Tokenizer_Tokenize_Handler,
+		},
+		{
+			MethodName: "Decode",
+			Handler:    _Tokenizer_Decode_Handler,
+		},
+		{
+			MethodName: "GetVocab",
+			Handler:    _Tokenizer_GetVocab_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "TokenizeStream",
+			Handler:       _Tokenizer_TokenizeStream_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+	},
+	Metadata: "arbor/v1/tokenizer.proto",
+}
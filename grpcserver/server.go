@@ -0,0 +1,180 @@
+// Package grpcserver implements the arbor.v1.Tokenizer gRPC service defined in
+// proto/arbor/v1/tokenizer.proto on top of a Tokenizer.
+package grpcserver
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"runtime"
+	"sync"
+
+	"github.com/clems4ever/arbor-encoder/grpcserver/arborpb"
+	"github.com/clems4ever/arbor-encoder/tokenizer"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Options configures a Server.
+type Options struct {
+	// Workers bounds the documents of one TokenizeStream call tokenized at
+	// once, see WithWorkers.
+	Workers int
+}
+
+// Option sets a field of Options.
+type Option func(*Options)
+
+// WithWorkers makes TokenizeStream tokenize at most n documents of a stream
+// at once. The default is the number of CPUs.
+func WithWorkers(n int) Option {
+	return func(o *Options) {
+		o.Workers = n
+	}
+}
+
+// Server implements arborpb.TokenizerServer. The Tokenizer is shared by all
+// calls, which is safe as tokenization keeps no state between calls.
+type Server struct {
+	arborpb.UnimplementedTokenizerServer
+
+	tok     *tokenizer.Tokenizer
+	options Options
+}
+
+// New creates a Server for tok. Register it with
+// arborpb.RegisterTokenizerServer.
+func New(tok *tokenizer.Tokenizer, opts ...Option) *Server {
+	options := Options{Workers: runtime.NumCPU()}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	options.Workers = max(options.Workers, 1)
+	return &Server{tok: tok, options: options}
+}
+
+func (s *Server) Tokenize(ctx context.Context, req *arborpb.TokenizeRequest) (*arborpb.TokenizeResponse, error) {
+	resp, err := s.tokenize(req)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	return resp, nil
+}
+
+func (s *Server) TokenizeStream(stream arborpb.Tokenizer_TokenizeStreamServer) error {
+	ctx := stream.Context()
+	results := make(chan *arborpb.TokenizeResponse)
+	recvErr := make(chan error, 1)
+
+	// Documents are received and tokenized by up to Workers goroutines while
+	// this one sends the results, as gRPC streams do not allow concurrent
+	// sends.
+	go func() {
+		var wg sync.WaitGroup
+		defer func() {
+			wg.Wait()
+			close(results)
+		}()
+		slots := make(chan struct{}, s.options.Workers)
+		for {
+			req, err := stream.Recv()
+			if err != nil {
+				if err == io.EOF {
+					err = nil
+				}
+				recvErr <- err
+				return
+			}
+			select {
+			case slots <- struct{}{}:
+			case <-ctx.Done():
+				recvErr <- ctx.Err()
+				return
+			}
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				defer func() { <-slots }()
+				resp, err := s.tokenize(req)
+				if err != nil {
+					resp = &arborpb.TokenizeResponse{Id: req.Id, Error: err.Error()}
+				}
+				select {
+				case results <- resp:
+				case <-ctx.Done():
+				}
+			}()
+		}
+	}()
+
+	for resp := range results {
+		if err := stream.Send(resp); err != nil {
+			return err
+		}
+	}
+	return <-recvErr
+}
+
+func (s *Server) Decode(ctx context.Context, req *arborpb.DecodeRequest) (*arborpb.DecodeResponse, error) {
+	tokens := make([]int, len(req.Tokens))
+	for i, id := range req.Tokens {
+		tokens[i] = int(id)
+	}
+	if !req.Xml {
+		return &arborpb.DecodeResponse{Text: s.tok.Decode(tokens)}, nil
+	}
+
+	resp := &arborpb.DecodeResponse{}
+	if req.Repair {
+		var report *tokenizer.RepairReport
+		tokens, report = s.tok.RepairTokens(tokens)
+		for _, action := range report.Actions {
+			resp.Repairs = append(resp.Repairs, action.String())
+		}
+	}
+	root, err := s.tok.DecodeXML(tokens)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if root == nil {
+		return nil, status.Error(codes.InvalidArgument, "no element to decode")
+	}
+	resp.Text = root.String()
+	return resp, nil
+}
+
+func (s *Server) GetVocab(ctx context.Context, req *arborpb.GetVocabRequest) (*arborpb.GetVocabResponse, error) {
+	vocab := make(map[string]int32)
+	for tok, id := range s.tok.Vocab() {
+		vocab[tok] = int32(id)
+	}
+	return &arborpb.GetVocabResponse{Vocab: vocab, ContentMaxId: tokenizer.Cl100kBaseMaxID}, nil
+}
+
+func (s *Server) tokenize(req *arborpb.TokenizeRequest) (*arborpb.TokenizeResponse, error) {
+	res, err := s.tok.Tokenize(bytes.NewReader(req.Document))
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &arborpb.TokenizeResponse{Id: req.Id, Tokens: toInt32(res.Tokens)}
+	if len(res.PaddedPaths) > 0 {
+		resp.Depth = int32(len(res.PaddedPaths[0]))
+	}
+	resp.PaddedPaths = make([]int32, 0, len(res.PaddedPaths)*int(resp.Depth))
+	for _, row := range res.PaddedPaths {
+		resp.PaddedPaths = append(resp.PaddedPaths, toInt32(row)...)
+	}
+	for _, l := range res.Labels {
+		resp.Labels = append(resp.Labels, &arborpb.Label{Label: l.Label, Start: int32(l.Start), End: int32(l.End), Path: toInt32(l.Path)})
+	}
+	return resp, nil
+}
+
+func toInt32(values []int) []int32 {
+	out := make([]int32, len(values))
+	for i, v := range values {
+		out[i] = int32(v)
+	}
+	return out
+}
@@ -0,0 +1,155 @@
+package grpcserver
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/clems4ever/arbor-encoder/grpcserver/arborpb"
+	"github.com/clems4ever/arbor-encoder/tokenizer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func newTestClient(t *testing.T, opts ...Option) (arborpb.TokenizerClient, *tokenizer.Tokenizer) {
+	vocab := map[string]int{
+		"<Doc>": 200001, "</Doc>": 200002,
+		"<Item>": 200003, "</Item>": 200004,
+		"##id":                  200100,
+		tokenizer.TokenValueEnd: 200200,
+	}
+	data, err := json.Marshal(vocab)
+	require.NoError(t, err)
+	vocabPath := filepath.Join(t.TempDir(), "vocab.json")
+	require.NoError(t, os.WriteFile(vocabPath, data, 0o644))
+	tok, err := tokenizer.NewTokenizer(vocabPath)
+	require.NoError(t, err)
+
+	lis := bufconn.Listen(1 << 20)
+	srv := grpc.NewServer()
+	arborpb.RegisterTokenizerServer(srv, New(tok, opts...))
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return arborpb.NewTokenizerClient(conn), tok
+}
+
+const doc = `<Doc id="d1"><Item arbor-label="first">Hello</Item><Item>World</Item></Doc>`
+
+func TestServer_Tokenize(t *testing.T) {
+	client, tok := newTestClient(t)
+	expected, err := tok.Tokenize(strings.NewReader(doc))
+	require.NoError(t, err)
+
+	resp, err := client.Tokenize(context.Background(), &arborpb.TokenizeRequest{Id: "a", Document: []byte(doc)})
+	require.NoError(t, err)
+	assert.Equal(t, "a", resp.Id)
+	assert.Equal(t, toInt32(expected.Tokens), resp.Tokens)
+	depth := len(expected.PaddedPaths[0])
+	require.Equal(t, int32(depth), resp.Depth)
+	for i, row := range expected.PaddedPaths {
+		assert.Equal(t, toInt32(row), resp.PaddedPaths[i*depth:(i+1)*depth])
+	}
+	require.Len(t, resp.Labels, 1)
+	assert.Equal(t, "first", resp.Labels[0].Label)
+	assert.Equal(t, toInt32(expected.Labels[0].Path), resp.Labels[0].Path)
+
+	_, err = client.Tokenize(context.Background(), &arborpb.TokenizeRequest{Document: []byte(`<Doc><Item>`)})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestServer_TokenizeStream(t *testing.T) {
+	client, tok := newTestClient(t, WithWorkers(3))
+
+	stream, err := client.TokenizeStream(context.Background())
+	require.NoError(t, err)
+
+	const n = 20
+	expected := make(map[string][]int32)
+	for i := 0; i < n; i++ {
+		id := fmt.Sprint(i)
+		document := fmt.Sprintf(`<Doc id="%d">%s</Doc>`, i, strings.Repeat("<Item>x</Item>", i))
+		if i == 7 {
+			document = `<Doc>`
+		} else {
+			res, err := tok.Tokenize(strings.NewReader(document))
+			require.NoError(t, err)
+			expected[id] = toInt32(res.Tokens)
+		}
+		require.NoError(t, stream.Send(&arborpb.TokenizeRequest{Id: id, Document: []byte(document)}))
+	}
+	require.NoError(t, stream.CloseSend())
+
+	got := make(map[string]*arborpb.TokenizeResponse)
+	for {
+		resp, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		got[resp.Id] = resp
+	}
+
+	require.Len(t, got, n)
+	assert.NotEmpty(t, got["7"].Error, "a bad document does not end the stream")
+	for id, tokens := range expected {
+		assert.Empty(t, got[id].Error)
+		assert.Equal(t, tokens, got[id].Tokens, id)
+	}
+}
+
+func TestServer_Decode(t *testing.T) {
+	client, tok := newTestClient(t)
+	res, err := tok.Tokenize(strings.NewReader(doc))
+	require.NoError(t, err)
+	tokens := toInt32(res.Tokens)
+
+	resp, err := client.Decode(context.Background(), &arborpb.DecodeRequest{Tokens: tokens})
+	require.NoError(t, err)
+	assert.Equal(t, tok.Decode(res.Tokens), resp.Text)
+
+	resp, err = client.Decode(context.Background(), &arborpb.DecodeRequest{Tokens: tokens, Xml: true})
+	require.NoError(t, err)
+	assert.Equal(t, `<Doc id="d1"><Item>Hello</Item><Item>World</Item></Doc>`, resp.Text)
+
+	resp, err = client.Decode(context.Background(), &arborpb.DecodeRequest{Tokens: tokens[:len(tokens)-3], Xml: true, Repair: true})
+	require.NoError(t, err)
+	assert.True(t, strings.HasSuffix(resp.Text, "</Item></Doc>"), resp.Text)
+	assert.NotEmpty(t, resp.Repairs)
+
+	_, err = client.Decode(context.Background(), &arborpb.DecodeRequest{Tokens: []int32{200002}, Xml: true})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	// Sequences without an element have no document to return.
+	for _, tokens := range [][]int32{nil, {100}} {
+		_, err = client.Decode(context.Background(), &arborpb.DecodeRequest{Tokens: tokens, Xml: true})
+		assert.Equal(t, codes.InvalidArgument, status.Code(err), "%v", tokens)
+		assert.Equal(t, "no element to decode", status.Convert(err).Message())
+	}
+}
+
+func TestServer_GetVocab(t *testing.T) {
+	client, tok := newTestClient(t)
+
+	resp, err := client.GetVocab(context.Background(), &arborpb.GetVocabRequest{})
+	require.NoError(t, err)
+	assert.Len(t, resp.Vocab, len(tok.Vocab()))
+	assert.Equal(t, int32(200001), resp.Vocab["<Doc>"])
+	assert.Equal(t, int32(tokenizer.Cl100kBaseMaxID), resp.ContentMaxId)
+}
@@ -0,0 +1,156 @@
+"""ctypes bindings for libarbor, the C shared library built from ./capi.
+
+Build the library with `make capi`, then:
+
+    from arbor_encoder import Tokenizer
+
+    with Tokenizer("examples/vocab.json") as tok:
+        res = tok.tokenize(b"<Doc>Hello</Doc>")
+        res.tokens        # [n_tokens] token IDs
+        res.padded_paths  # [n_tokens][depth] path indices
+        tok.decode_xml(res.tokens)
+
+The library is looked up in $ARBOR_LIB, then in build/ at the repository
+root. Results are plain lists; pass numpy=True to tokenize to get int32
+arrays instead.
+"""
+
+import ctypes
+import os
+import sys
+
+__all__ = ["ArborError", "Tokenizer", "TokenizationResult", "load_library"]
+
+
+class ArborError(Exception):
+    """An error reported by libarbor."""
+
+
+class _Result(ctypes.Structure):
+    _fields_ = [
+        ("tokens", ctypes.POINTER(ctypes.c_int32)),
+        ("paths", ctypes.POINTER(ctypes.c_int32)),
+        ("n_tokens", ctypes.c_int64),
+        ("depth", ctypes.c_int64),
+    ]
+
+
+class TokenizationResult:
+    """Tokens and padded paths of a document, as lists or numpy arrays."""
+
+    def __init__(self, tokens, padded_paths):
+        self.tokens = tokens
+        self.padded_paths = padded_paths
+
+    def __repr__(self):
+        return "TokenizationResult(tokens=%r, padded_paths=%r)" % (self.tokens, self.padded_paths)
+
+
+def _default_library_path():
+    name = {"darwin": "libarbor.dylib", "win32": "arbor.dll"}.get(sys.platform, "libarbor.so")
+    root = os.path.dirname(os.path.dirname(os.path.abspath(__file__)))
+    return os.path.join(root, "build", name)
+
+
+def load_library(path=None):
+    """Loads libarbor and declares its function signatures."""
+    lib = ctypes.CDLL(path or os.environ.get("ARBOR_LIB") or _default_library_path())
+    err = ctypes.POINTER(ctypes.c_char_p)
+
+    lib.arbor_tokenizer_new.argtypes = [ctypes.c_char_p, err]
+    lib.arbor_tokenizer_new.restype = ctypes.c_size_t
+    lib.arbor_tokenizer_free.argtypes = [ctypes.c_size_t]
+    lib.arbor_tokenizer_free.restype = None
+    lib.arbor_tokenize.argtypes = [ctypes.c_size_t, ctypes.c_char_p, ctypes.c_int64, ctypes.POINTER(_Result), err]
+    lib.arbor_tokenize.restype = ctypes.c_int
+    lib.arbor_result_free.argtypes = [ctypes.POINTER(_Result)]
+    lib.arbor_result_free.restype = None
+    # Strings returned by the library are kept as raw pointers so that they
+    # can be released with arbor_string_free.
+    lib.arbor_decode.argtypes = [ctypes.c_size_t, ctypes.POINTER(ctypes.c_int32), ctypes.c_int64, ctypes.c_int, err]
+    lib.arbor_decode.restype = ctypes.c_void_p
+    lib.arbor_string_free.argtypes = [ctypes.c_void_p]
+    lib.arbor_string_free.restype = None
+    return lib
+
+
+class Tokenizer:
+    """A tokenizer loaded in-process from libarbor.
+
+    A Tokenizer can be shared between threads. Call close, or use it as a
+    context manager, to release it.
+    """
+
+    def __init__(self, vocab_path, library=None):
+        self._lib = library if isinstance(library, ctypes.CDLL) else load_library(library)
+        err = ctypes.c_char_p()
+        self._handle = self._lib.arbor_tokenizer_new(os.fsencode(vocab_path), ctypes.byref(err))
+        if not self._handle:
+            raise ArborError(self._take_error(err))
+
+    def close(self):
+        if self._handle:
+            self._lib.arbor_tokenizer_free(self._handle)
+            self._handle = 0
+
+    def __enter__(self):
+        return self
+
+    def __exit__(self, *exc):
+        self.close()
+
+    def __del__(self):
+        if getattr(self, "_handle", 0):
+            self.close()
+
+    def tokenize(self, document, numpy=False):
+        """Tokenizes an XML document given as bytes or str."""
+        if isinstance(document, str):
+            document = document.encode("utf-8")
+        res = _Result()
+        err = ctypes.c_char_p()
+        if self._lib.arbor_tokenize(self._check(), document, len(document), ctypes.byref(res), ctypes.byref(err)) != 0:
+            raise ArborError(self._take_error(err))
+        try:
+            n, depth = res.n_tokens, res.depth
+            if numpy:
+                import numpy as np
+
+                tokens = np.ctypeslib.as_array(res.tokens, shape=(n,)).copy()
+                paths = np.ctypeslib.as_array(res.paths, shape=(n, depth)).copy()
+                return TokenizationResult(tokens, paths)
+            flat = res.paths[: n * depth]
+            paths = [flat[i * depth : (i + 1) * depth] for i in range(n)]
+            return TokenizationResult(res.tokens[:n], paths)
+        finally:
+            self._lib.arbor_result_free(ctypes.byref(res))
+
+    def decode(self, tokens):
+        """Decodes token IDs to their space-joined string forms."""
+        return self._decode(tokens, False)
+
+    def decode_xml(self, tokens):
+        """Decodes token IDs back to an XML document."""
+        return self._decode(tokens, True)
+
+    def _decode(self, tokens, xml):
+        ids = (ctypes.c_int32 * len(tokens))(*[int(t) for t in tokens])
+        err = ctypes.c_char_p()
+        ptr = self._lib.arbor_decode(self._check(), ids, len(ids), int(xml), ctypes.byref(err))
+        if not ptr:
+            raise ArborError(self._take_error(err))
+        try:
+            return ctypes.string_at(ptr).decode("utf-8")
+        finally:
+            self._lib.arbor_string_free(ptr)
+
+    def _check(self):
+        if not self._handle:
+            raise ArborError("tokenizer is closed")
+        return self._handle
+
+    def _take_error(self, err):
+        msg = err.value.decode("utf-8", "replace") if err.value else "unknown error"
+        # err is a char * owned by the library: release it through its address.
+        self._lib.arbor_string_free(ctypes.cast(err, ctypes.c_void_p))
+        return msg
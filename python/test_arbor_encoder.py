@@ -0,0 +1,103 @@
+"""Tests for the libarbor ctypes bindings. Run with `make test-python`."""
+
+import json
+import os
+import tempfile
+import threading
+import unittest
+
+from arbor_encoder import ArborError, Tokenizer
+
+VOCAB = {
+    "<Doc>": 200001,
+    "</Doc>": 200002,
+    "<Item>": 200003,
+    "</Item>": 200004,
+    "##id": 200100,
+    "</__Value>": 200200,
+}
+
+DOC = '<Doc id="d1"><Item>Hello</Item><Item>World</Item></Doc>'
+
+
+class TokenizerTest(unittest.TestCase):
+    @classmethod
+    def setUpClass(cls):
+        cls.tmp = tempfile.TemporaryDirectory()
+        cls.vocab_path = os.path.join(cls.tmp.name, "vocab.json")
+        with open(cls.vocab_path, "w") as f:
+            json.dump(VOCAB, f)
+        cls.tok = Tokenizer(cls.vocab_path)
+
+    @classmethod
+    def tearDownClass(cls):
+        cls.tok.close()
+        cls.tmp.cleanup()
+
+    def test_tokenize(self):
+        res = self.tok.tokenize(DOC)
+        self.assertEqual(res.tokens[0], VOCAB["<Doc>"])
+        self.assertEqual(res.tokens[-1], VOCAB["</Doc>"])
+        self.assertIn(VOCAB["##id"], res.tokens)
+        self.assertEqual(len(res.padded_paths), len(res.tokens))
+        depth = len(res.padded_paths[0])
+        self.assertTrue(all(len(row) == depth for row in res.padded_paths))
+        # The root element has path [0], padded with -1.
+        self.assertEqual(res.padded_paths[0], [0] + [-1] * (depth - 1))
+        self.assertEqual(self.tok.tokenize(DOC.encode("utf-8")).tokens, res.tokens)
+
+    def test_tokenize_error(self):
+        with self.assertRaisesRegex(ArborError, "."):
+            self.tok.tokenize("<Doc><Item>")
+
+    def test_decode(self):
+        res = self.tok.tokenize(DOC)
+        self.assertEqual(self.tok.decode_xml(res.tokens), DOC)
+        self.assertTrue(self.tok.decode(res.tokens).startswith("<Doc> ##id"))
+        with self.assertRaises(ArborError):
+            self.tok.decode_xml([VOCAB["</Doc>"]])
+        # Sequences without an element have no document to return.
+        for tokens in ([], [100]):
+            with self.assertRaisesRegex(ArborError, "no element to decode"):
+                self.tok.decode_xml(tokens)
+
+    def test_numpy(self):
+        try:
+            import numpy as np
+        except ImportError:
+            self.skipTest("numpy is not installed")
+        res = self.tok.tokenize(DOC, numpy=True)
+        expected = self.tok.tokenize(DOC)
+        self.assertEqual(res.tokens.dtype, np.int32)
+        self.assertEqual(res.padded_paths.shape, (len(expected.tokens), len(expected.padded_paths[0])))
+        self.assertEqual(res.padded_paths.tolist(), expected.padded_paths)
+
+    def test_threads(self):
+        expected = self.tok.tokenize(DOC).tokens
+        results = []
+
+        def work():
+            for _ in range(20):
+                results.append(self.tok.tokenize(DOC).tokens == expected)
+
+        threads = [threading.Thread(target=work) for _ in range(4)]
+        for t in threads:
+            t.start()
+        for t in threads:
+            t.join()
+        self.assertEqual(len(results), 80)
+        self.assertTrue(all(results))
+
+    def test_bad_vocab(self):
+        with self.assertRaises(ArborError):
+            Tokenizer(os.path.join(self.tmp.name, "missing.json"))
+
+    def test_closed(self):
+        tok = Tokenizer(self.vocab_path)
+        tok.close()
+        with self.assertRaisesRegex(ArborError, "closed"):
+            tok.tokenize(DOC)
+
+
+if __name__ == "__main__":
+    unittest.main()
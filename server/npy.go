@@ -0,0 +1,72 @@
+package server
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// writeNPY writes data as a little-endian int32 array in the NumPy .npy
+// format (version 1.0). shape has one or two dimensions and data holds
+// shape[0]*shape[1] values in row-major order.
+func writeNPY(w io.Writer, shape []int, data []int) error {
+	dims := make([]string, len(shape))
+	for i, d := range shape {
+		dims[i] = fmt.Sprint(d)
+	}
+	shapeStr := strings.Join(dims, ", ")
+	if len(shape) == 1 {
+		shapeStr += ","
+	}
+	header := fmt.Sprintf("{'descr': '<i4', 'fortran_order': False, 'shape': (%s), }", shapeStr)
+	// The magic string, version and header length take 10 bytes, and the
+	// header is padded with spaces and a newline to a multiple of 64.
+	header += strings.Repeat(" ", 63-(10+len(header))%64) + "\n"
+
+	var buf bytes.Buffer
+	buf.WriteString("\x93NUMPY\x01\x00")
+	binary.Write(&buf, binary.LittleEndian, uint16(len(header)))
+	buf.WriteString(header)
+	for _, v := range data {
+		binary.Write(&buf, binary.LittleEndian, int32(v))
+	}
+	_, err := buf.WriteTo(w)
+	return err
+}
+
+// writeMatrixNPY writes rows of equal length as a 2D .npy array.
+func writeMatrixNPY(w io.Writer, rows [][]int) error {
+	width := 0
+	if len(rows) > 0 {
+		width = len(rows[0])
+	}
+	data := make([]int, 0, len(rows)*width)
+	for _, row := range rows {
+		data = append(data, row...)
+	}
+	return writeNPY(w, []int{len(rows), width}, data)
+}
+
+// writeNPZ writes the tokens and padded paths as tokens.npy and
+// padded_paths.npy in an uncompressed .npz archive, as numpy.savez does.
+func writeNPZ(w io.Writer, tokens []int, paths [][]int) error {
+	zw := zip.NewWriter(w)
+	f, err := zw.CreateHeader(&zip.FileHeader{Name: "tokens.npy", Method: zip.Store})
+	if err != nil {
+		return err
+	}
+	if err := writeNPY(f, []int{len(tokens)}, tokens); err != nil {
+		return err
+	}
+	f, err = zw.CreateHeader(&zip.FileHeader{Name: "padded_paths.npy", Method: zip.Store})
+	if err != nil {
+		return err
+	}
+	if err := writeMatrixNPY(f, paths); err != nil {
+		return err
+	}
+	return zw.Close()
+}
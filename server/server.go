@@ -0,0 +1,319 @@
+// Package server exposes a Tokenizer over HTTP, for services written in other
+// languages such as Python trainers or annotation UIs.
+//
+//	GET  /healthz     {"status": "ok"}
+//	GET  /vocab       the vocab and its size
+//	POST /tokenize    XML body; JSON, or NumPy with ?format=npy or npz
+//	POST /decode      {"tokens": [...]} to the space-joined tokens
+//	POST /decode-xml  {"tokens": [...], "repair": false} to the XML document
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+
+	"github.com/clems4ever/arbor-encoder/tokenizer"
+)
+
+// Options configures a Server.
+type Options struct {
+	// MaxBodyBytes bounds the size of request bodies, see WithMaxBodyBytes.
+	MaxBodyBytes int64
+	// MaxDepth bounds the element nesting of documents, see WithMaxDepth.
+	MaxDepth int
+	// MaxConcurrent bounds the requests handled at once, see
+	// WithMaxConcurrent.
+	MaxConcurrent int
+}
+
+// Option sets a field of Options.
+type Option func(*Options)
+
+// WithMaxBodyBytes rejects request bodies larger than n bytes with 413. The
+// default is 10 MiB.
+func WithMaxBodyBytes(n int64) Option {
+	return func(o *Options) {
+		o.MaxBodyBytes = n
+	}
+}
+
+// WithMaxDepth rejects documents whose elements nest deeper than n with 422.
+// The default is 256.
+func WithMaxDepth(n int) Option {
+	return func(o *Options) {
+		o.MaxDepth = n
+	}
+}
+
+// WithMaxConcurrent makes at most n requests tokenize or decode at once;
+// the others wait until a slot frees up or the client gives up. 0, the
+// default, leaves concurrency to net/http.
+func WithMaxConcurrent(n int) Option {
+	return func(o *Options) {
+		o.MaxConcurrent = n
+	}
+}
+
+// Server is an http.Handler serving one Tokenizer. The Tokenizer is shared by
+// all requests, which is safe as tokenization keeps no state between calls.
+type Server struct {
+	tok     *tokenizer.Tokenizer
+	options Options
+	slots   chan struct{}
+	mux     *http.ServeMux
+}
+
+// New creates a Server for tok.
+func New(tok *tokenizer.Tokenizer, opts ...Option) *Server {
+	options := Options{MaxBodyBytes: 10 << 20, MaxDepth: 256}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	s := &Server{tok: tok, options: options, mux: http.NewServeMux()}
+	if options.MaxConcurrent > 0 {
+		s.slots = make(chan struct{}, options.MaxConcurrent)
+	}
+	s.mux.HandleFunc("GET /healthz", s.handleHealth)
+	s.mux.HandleFunc("GET /vocab", s.handleVocab)
+	s.mux.HandleFunc("POST /tokenize", s.limited(s.handleTokenize))
+	s.mux.HandleFunc("POST /decode", s.limited(s.handleDecode))
+	s.mux.HandleFunc("POST /decode-xml", s.limited(s.handleDecodeXML))
+	return s
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// TokenizeResponse is the JSON response of /tokenize.
+type TokenizeResponse struct {
+	Tokens      []int   `json:"tokens"`
+	PaddedPaths [][]int `json:"padded_paths"`
+	Labels      []Label `json:"labels,omitempty"`
+}
+
+// Label is a tokenizer.NodeLabel in a TokenizeResponse.
+type Label struct {
+	Label string `json:"label"`
+	Start int    `json:"start"`
+	End   int    `json:"end"`
+	Path  []int  `json:"path"`
+}
+
+// DecodeRequest is the body of /decode and /decode-xml.
+type DecodeRequest struct {
+	Tokens []int `json:"tokens"`
+	// Repair passes the tokens through RepairTokens before decoding, for
+	// /decode-xml.
+	Repair bool `json:"repair,omitempty"`
+}
+
+// DecodeXMLResponse is the JSON response of /decode-xml.
+type DecodeXMLResponse struct {
+	XML     string   `json:"xml"`
+	Repairs []string `json:"repairs,omitempty"`
+}
+
+// VocabResponse is the JSON response of /vocab.
+type VocabResponse struct {
+	Size int `json:"size"`
+	// ContentMaxID is the bound below which IDs are content tokens.
+	ContentMaxID int            `json:"content_max_id"`
+	Vocab        map[string]int `json:"vocab"`
+}
+
+func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+func (s *Server) handleVocab(w http.ResponseWriter, r *http.Request) {
+	vocab := s.tok.Vocab()
+	writeJSON(w, http.StatusOK, VocabResponse{Size: len(vocab), ContentMaxID: tokenizer.Cl100kBaseMaxID, Vocab: vocab})
+}
+
+func (s *Server) handleTokenize(w http.ResponseWriter, r *http.Request) {
+	format, array := r.URL.Query().Get("format"), r.URL.Query().Get("array")
+	switch format {
+	case "", "json", "npy", "npz":
+	default:
+		writeError(w, http.StatusBadRequest, fmt.Errorf("unknown format %q (expected json, npy or npz)", format))
+		return
+	}
+	switch array {
+	case "", "tokens", "padded_paths":
+	default:
+		writeError(w, http.StatusBadRequest, fmt.Errorf("unknown array %q (expected tokens or padded_paths)", array))
+		return
+	}
+
+	body, err := s.readBody(w, r)
+	if err != nil {
+		return
+	}
+	if err := checkDepth(body, s.options.MaxDepth); err != nil {
+		writeError(w, http.StatusUnprocessableEntity, err)
+		return
+	}
+	res, err := s.tok.Tokenize(bytes.NewReader(body))
+	if err != nil {
+		writeError(w, http.StatusUnprocessableEntity, err)
+		return
+	}
+
+	switch format {
+	case "npy":
+		writeBinary(w, "application/octet-stream", func(w io.Writer) error {
+			if array == "padded_paths" {
+				return writeMatrixNPY(w, res.PaddedPaths)
+			}
+			return writeNPY(w, []int{len(res.Tokens)}, res.Tokens)
+		})
+	case "npz":
+		writeBinary(w, "application/zip", func(w io.Writer) error {
+			return writeNPZ(w, res.Tokens, res.PaddedPaths)
+		})
+	default:
+		resp := TokenizeResponse{Tokens: res.Tokens, PaddedPaths: res.PaddedPaths}
+		for _, l := range res.Labels {
+			resp.Labels = append(resp.Labels, Label{Label: l.Label, Start: l.Start, End: l.End, Path: l.Path})
+		}
+		writeJSON(w, http.StatusOK, resp)
+	}
+}
+
+func (s *Server) handleDecode(w http.ResponseWriter, r *http.Request) {
+	req, err := s.readDecodeRequest(w, r)
+	if err != nil {
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"text": s.tok.Decode(req.Tokens)})
+}
+
+func (s *Server) handleDecodeXML(w http.ResponseWriter, r *http.Request) {
+	req, err := s.readDecodeRequest(w, r)
+	if err != nil {
+		return
+	}
+
+	var resp DecodeXMLResponse
+	tokens := req.Tokens
+	if req.Repair {
+		var report *tokenizer.RepairReport
+		tokens, report = s.tok.RepairTokens(tokens)
+		for _, action := range report.Actions {
+			resp.Repairs = append(resp.Repairs, action.String())
+		}
+	}
+	root, err := s.tok.DecodeXML(tokens)
+	if err != nil {
+		writeError(w, http.StatusUnprocessableEntity, err)
+		return
+	}
+	if root == nil {
+		writeError(w, http.StatusUnprocessableEntity, errors.New("no element to decode"))
+		return
+	}
+	resp.XML = root.String()
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// limited makes the handler wait for a free slot when MaxConcurrent is set.
+func (s *Server) limited(h http.HandlerFunc) http.HandlerFunc {
+	if s.slots == nil {
+		return h
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case s.slots <- struct{}{}:
+			defer func() { <-s.slots }()
+			h(w, r)
+		case <-r.Context().Done():
+			writeError(w, http.StatusServiceUnavailable, errors.New("server busy"))
+		}
+	}
+}
+
+// readBody reads the request body within MaxBodyBytes. On error, the
+// response has been written.
+func (s *Server) readBody(w http.ResponseWriter, r *http.Request) ([]byte, error) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, s.options.MaxBodyBytes))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			writeError(w, http.StatusRequestEntityTooLarge, fmt.Errorf("request body exceeds %d bytes", tooLarge.Limit))
+		} else {
+			writeError(w, http.StatusBadRequest, err)
+		}
+		return nil, err
+	}
+	return body, nil
+}
+
+func (s *Server) readDecodeRequest(w http.ResponseWriter, r *http.Request) (*DecodeRequest, error) {
+	body, err := s.readBody(w, r)
+	if err != nil {
+		return nil, err
+	}
+	var req DecodeRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid request: %w", err))
+		return nil, err
+	}
+	return &req, nil
+}
+
+// checkDepth returns an error when elements nest deeper than maxDepth. XML
+// syntax errors are left to the tokenizer.
+func checkDepth(doc []byte, maxDepth int) error {
+	dec := xml.NewDecoder(bytes.NewReader(doc))
+	depth := 0
+	for {
+		tok, err := dec.RawToken()
+		if err != nil {
+			return nil
+		}
+		switch tok.(type) {
+		case xml.StartElement:
+			depth++
+			if depth > maxDepth {
+				line, _ := dec.InputPos()
+				return fmt.Errorf("line %d: elements nest deeper than %d", line, maxDepth)
+			}
+		case xml.EndElement:
+			depth--
+		}
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("server: writing response: %v", err)
+	}
+}
+
+// writeBinary encodes a response with write before sending it, so that an
+// encoding error is answered with a 500 instead of a truncated body.
+func writeBinary(w http.ResponseWriter, contentType string, write func(io.Writer) error) {
+	var buf bytes.Buffer
+	if err := write(&buf); err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	if _, err := buf.WriteTo(w); err != nil {
+		log.Printf("server: writing response: %v", err)
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
@@ -0,0 +1,264 @@
+package server
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/clems4ever/arbor-encoder/tokenizer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestServer(t *testing.T, opts ...Option) (*httptest.Server, *tokenizer.Tokenizer) {
+	vocab := map[string]int{
+		"<Doc>": 200001, "</Doc>": 200002,
+		"<Item>": 200003, "</Item>": 200004,
+		"##id":                  200100,
+		tokenizer.TokenValueEnd: 200200,
+	}
+	data, err := json.Marshal(vocab)
+	require.NoError(t, err)
+	vocabPath := filepath.Join(t.TempDir(), "vocab.json")
+	require.NoError(t, os.WriteFile(vocabPath, data, 0o644))
+
+	tok, err := tokenizer.NewTokenizer(vocabPath)
+	require.NoError(t, err)
+	ts := httptest.NewServer(New(tok, opts...))
+	t.Cleanup(ts.Close)
+	return ts, tok
+}
+
+func post(t *testing.T, url, body string) *http.Response {
+	resp, err := http.Post(url, "application/octet-stream", strings.NewReader(body))
+	require.NoError(t, err)
+	t.Cleanup(func() { resp.Body.Close() })
+	return resp
+}
+
+func decodeJSON(t *testing.T, resp *http.Response, v interface{}) {
+	require.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(v))
+}
+
+const doc = `<Doc id="d1"><Item arbor-label="first">Hello</Item><Item>World</Item></Doc>`
+
+func TestServer_Tokenize(t *testing.T) {
+	ts, tok := newTestServer(t)
+	expected, err := tok.Tokenize(strings.NewReader(doc))
+	require.NoError(t, err)
+
+	resp := post(t, ts.URL+"/tokenize", doc)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var res TokenizeResponse
+	decodeJSON(t, resp, &res)
+	assert.Equal(t, expected.Tokens, res.Tokens)
+	assert.Equal(t, expected.PaddedPaths, res.PaddedPaths)
+	require.Len(t, res.Labels, 1)
+	assert.Equal(t, "first", res.Labels[0].Label)
+
+	resp = post(t, ts.URL+"/tokenize", `<Doc><Item>`)
+	assert.Equal(t, http.StatusUnprocessableEntity, resp.StatusCode)
+	var errResp map[string]string
+	decodeJSON(t, resp, &errResp)
+	assert.NotEmpty(t, errResp["error"])
+
+	resp = post(t, ts.URL+"/tokenize?format=csv", doc)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
+
+// readNPY parses a .npy array written by writeNPY.
+func readNPY(t *testing.T, data []byte) (string, []int32) {
+	require.Equal(t, "\x93NUMPY\x01\x00", string(data[:8]))
+	headerLen := int(binary.LittleEndian.Uint16(data[8:10]))
+	assert.Zero(t, (10+headerLen)%64, "header is aligned")
+	header := string(data[10 : 10+headerLen])
+	values := make([]int32, (len(data)-10-headerLen)/4)
+	require.NoError(t, binary.Read(bytes.NewReader(data[10+headerLen:]), binary.LittleEndian, values))
+	return header, values
+}
+
+func TestServer_TokenizeNumPy(t *testing.T) {
+	ts, tok := newTestServer(t)
+	expected, err := tok.Tokenize(strings.NewReader(doc))
+	require.NoError(t, err)
+	depth := len(expected.PaddedPaths[0])
+
+	var flatPaths []int32
+	for _, row := range expected.PaddedPaths {
+		for _, v := range row {
+			flatPaths = append(flatPaths, int32(v))
+		}
+	}
+	var tokens []int32
+	for _, id := range expected.Tokens {
+		tokens = append(tokens, int32(id))
+	}
+
+	resp := post(t, ts.URL+"/tokenize?format=npy", doc)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	data, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	header, values := readNPY(t, data)
+	assert.Contains(t, header, "'descr': '<i4'")
+	assert.Contains(t, header, fmt.Sprintf("'shape': (%d,)", len(tokens)))
+	assert.Equal(t, tokens, values)
+
+	resp = post(t, ts.URL+"/tokenize?format=npy&array=padded_paths", doc)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	data, err = io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	header, values = readNPY(t, data)
+	assert.Contains(t, header, fmt.Sprintf("'shape': (%d, %d)", len(tokens), depth))
+	assert.Equal(t, flatPaths, values)
+
+	resp = post(t, ts.URL+"/tokenize?format=npz", doc)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	data, err = io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	require.NoError(t, err)
+	require.Len(t, zr.File, 2)
+	assert.Equal(t, "tokens.npy", zr.File[0].Name)
+	assert.Equal(t, "padded_paths.npy", zr.File[1].Name)
+}
+
+func TestServer_Decode(t *testing.T) {
+	ts, tok := newTestServer(t)
+	res, err := tok.Tokenize(strings.NewReader(doc))
+	require.NoError(t, err)
+	body, err := json.Marshal(DecodeRequest{Tokens: res.Tokens})
+	require.NoError(t, err)
+
+	resp := post(t, ts.URL+"/decode", string(body))
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var text map[string]string
+	decodeJSON(t, resp, &text)
+	assert.Equal(t, tok.Decode(res.Tokens), text["text"])
+
+	resp = post(t, ts.URL+"/decode-xml", string(body))
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var decoded DecodeXMLResponse
+	decodeJSON(t, resp, &decoded)
+	assert.Equal(t, `<Doc id="d1"><Item>Hello</Item><Item>World</Item></Doc>`, decoded.XML)
+	assert.Empty(t, decoded.Repairs)
+
+	resp = post(t, ts.URL+"/decode-xml", `{"tokens": [200002]}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, resp.StatusCode)
+
+	// Sequences without an element have no document to return.
+	for _, body := range []string{`{"tokens": []}`, `{"tokens": [100]}`} {
+		resp = post(t, ts.URL+"/decode-xml", body)
+		assert.Equal(t, http.StatusUnprocessableEntity, resp.StatusCode, body)
+		var e map[string]string
+		decodeJSON(t, resp, &e)
+		assert.Equal(t, "no element to decode", e["error"], body)
+	}
+
+	// Repair closes a truncated sequence.
+	repair, err := json.Marshal(DecodeRequest{Tokens: res.Tokens[:len(res.Tokens)-3], Repair: true})
+	require.NoError(t, err)
+	resp = post(t, ts.URL+"/decode-xml", string(repair))
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	decoded = DecodeXMLResponse{}
+	decodeJSON(t, resp, &decoded)
+	assert.True(t, strings.HasSuffix(decoded.XML, "</Item></Doc>"), decoded.XML)
+	assert.NotEmpty(t, decoded.Repairs)
+
+	resp = post(t, ts.URL+"/decode", `{"tokens": "nope"}`)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
+
+func TestServer_VocabAndHealth(t *testing.T) {
+	ts, tok := newTestServer(t)
+
+	resp, err := http.Get(ts.URL + "/healthz")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	resp, err = http.Get(ts.URL + "/vocab")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	var vocab VocabResponse
+	decodeJSON(t, resp, &vocab)
+	assert.Equal(t, tok.Vocab(), vocab.Vocab)
+	assert.Equal(t, 6, vocab.Size)
+	assert.Equal(t, tokenizer.Cl100kBaseMaxID, vocab.ContentMaxID)
+
+	resp, err = http.Get(ts.URL + "/tokenize")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+}
+
+func TestServer_Limits(t *testing.T) {
+	ts, _ := newTestServer(t, WithMaxBodyBytes(64), WithMaxDepth(3))
+
+	resp := post(t, ts.URL+"/tokenize", `<Doc>`+strings.Repeat("x", 100)+`</Doc>`)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, resp.StatusCode)
+
+	resp = post(t, ts.URL+"/tokenize", `<Doc><Item><Item>ok</Item></Item></Doc>`)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	resp = post(t, ts.URL+"/tokenize", "<Doc>\n<Item><Item><Item>deep</Item></Item></Item></Doc>")
+	assert.Equal(t, http.StatusUnprocessableEntity, resp.StatusCode)
+	var errResp map[string]string
+	decodeJSON(t, resp, &errResp)
+	assert.Equal(t, "line 2: elements nest deeper than 3", errResp["error"])
+}
+
+func TestServer_Concurrent(t *testing.T) {
+	ts, tok := newTestServer(t, WithMaxConcurrent(2))
+	expected, err := tok.Tokenize(strings.NewReader(doc))
+	require.NoError(t, err)
+
+	var wg sync.WaitGroup
+	results := make([]TokenizeResponse, 16)
+	for i := range results {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resp, err := http.Post(ts.URL+"/tokenize", "application/xml", strings.NewReader(doc))
+			if !assert.NoError(t, err) {
+				return
+			}
+			defer resp.Body.Close()
+			assert.NoError(t, json.NewDecoder(resp.Body).Decode(&results[i]))
+		}()
+	}
+	wg.Wait()
+	for _, res := range results {
+		assert.Equal(t, expected.Tokens, res.Tokens)
+	}
+}
+
+func TestWriteBinary(t *testing.T) {
+	rec := httptest.NewRecorder()
+	writeBinary(rec, "application/zip", func(w io.Writer) error {
+		io.WriteString(w, "partial")
+		return fmt.Errorf("zip failed")
+	})
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+	assert.JSONEq(t, `{"error": "zip failed"}`, rec.Body.String(), "no partial body is sent")
+
+	rec = httptest.NewRecorder()
+	writeBinary(rec, "application/octet-stream", func(w io.Writer) error {
+		_, err := io.WriteString(w, "data")
+		return err
+	})
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "application/octet-stream", rec.Header().Get("Content-Type"))
+	assert.Equal(t, "data", rec.Body.String())
+}
@@ -0,0 +1,115 @@
+package tokenizer
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAttributeOrder_Golden(t *testing.T) {
+	data, err := os.ReadFile("testdata/mixed_attrs_golden_vocab.json")
+	require.NoError(t, err)
+	vocabPath := filepath.Join(t.TempDir(), "vocab.json")
+	require.NoError(t, os.WriteFile(vocabPath, data, 0644))
+	input, err := os.ReadFile("testdata/mixed_attrs_golden.xml")
+	require.NoError(t, err)
+
+	policies := map[string]AttributeOrder{
+		"sorted":    AttributesSorted,
+		"source":    AttributesSourceOrder,
+		"unordered": AttributesUnordered,
+	}
+
+	for name, order := range policies {
+		t.Run(name, func(t *testing.T) {
+			tokenizer, err := NewTokenizer(vocabPath, WithAttributeOrder(order))
+			require.NoError(t, err)
+
+			res, err := tokenizer.Tokenize(bytes.NewReader(input))
+			require.NoError(t, err)
+
+			decoded, err := tokenizer.DecodeXML(res.Tokens)
+			require.NoError(t, err)
+
+			// The vocab has no ordering markers, so the tracker is told which
+			// tags are ordered. The golden lists structural tokens with their
+			// paths and runs of content tokens as one line with the path of
+			// their first token, so that it does not depend on the BPE.
+			tracker := tokenizer.NewPathTracker(map[string]bool{"FakeOrderedList": true})
+			var buf bytes.Buffer
+			var text strings.Builder
+			var textPath []int
+			flush := func() {
+				if text.Len() > 0 {
+					fmt.Fprintf(&buf, "%v %s\n", textPath, strconv.Quote(text.String()))
+					text.Reset()
+				}
+			}
+			for i, id := range res.Tokens {
+				path, err := tracker.Push(id)
+				require.NoError(t, err)
+				assert.Equal(t, trimPadding(res.PaddedPaths[i]), path, "token %d", i)
+
+				s, ok := tokenizer.vocabInv[id]
+				if !ok {
+					if text.Len() == 0 {
+						textPath = trimPadding(res.PaddedPaths[i])
+					}
+					text.WriteString(tokenizer.contentTokenizer.Decode([]int{id}))
+					continue
+				}
+				flush()
+				fmt.Fprintf(&buf, "%v %s\n", trimPadding(res.PaddedPaths[i]), s)
+			}
+			flush()
+			buf.WriteString("\n")
+			decoded.PrettyPrint(&buf, 0)
+
+			goldenFile := filepath.Join("testdata", "mixed_attrs_order_"+name+".txt")
+			if *update {
+				require.NoError(t, os.WriteFile(goldenFile, buf.Bytes(), 0644))
+			}
+			expected, err := os.ReadFile(goldenFile)
+			require.NoError(t, err, "run with -update to generate")
+			assert.Equal(t, string(expected), buf.String())
+		})
+	}
+}
+
+func TestAttributeOrder_Decode(t *testing.T) {
+	vocab := map[string]int{"<a>": 200000, "</a>": 200001, "<b>": 200002, "</b>": 200003, "##z": 200004, "##a": 200005}
+	input := `<a z="1" a="2"><b></b></a>`
+
+	tests := []struct {
+		order    AttributeOrder
+		expected string
+		child    []int
+	}{
+		{AttributesSorted, `<a a="2" z="1"><b></b></a>`, []int{0, 1}},
+		{AttributesSourceOrder, `<a z="1" a="2"><b></b></a>`, []int{0, 2}},
+		{AttributesUnordered, `<a z="1" a="2"><b></b></a>`, []int{0, 1}},
+	}
+
+	for _, tt := range tests {
+		tokenizer := newTypedTokenizer(t, vocab, WithAttributeOrder(tt.order))
+		res, err := tokenizer.Tokenize(strings.NewReader(input))
+		require.NoError(t, err)
+
+		decoded, err := tokenizer.DecodeXML(res.Tokens)
+		require.NoError(t, err)
+		assert.Equal(t, tt.expected, decoded.String())
+
+		for i, id := range res.Tokens {
+			if id == vocab["<b>"] {
+				assert.Equal(t, tt.child, trimPadding(res.PaddedPaths[i]))
+			}
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package tokenizer
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"strconv"
+
+	"github.com/pkoukk/tiktoken-go"
+)
+
+// cl100kBaseURL is the ranks file tiktoken downloads for cl100k_base.
+const cl100kBaseURL = "https://openaipublic.blob.core.windows.net/encodings/cl100k_base.tiktoken"
+
+// cl100kBasePattern is the pre-tokenization pattern of cl100k_base, as set by
+// tiktoken.GetEncoding.
+const cl100kBasePattern = `(?i:'s|'t|'re|'ve|'m|'ll|'d)|[^\r\n\p{L}\p{N}]?\p{L}+|\p{N}{1,3}| ?[^\s\p{L}\p{N}]+[\r\n]*|\s*[\r\n]+|\s+(?!\S)|\s+`
+
+// cl100kBaseSpecialTokens are the special tokens of cl100k_base.
+var cl100kBaseSpecialTokens = map[string]int{
+	tiktoken.ENDOFTEXT:   100257,
+	tiktoken.FIM_PREFIX:  100258,
+	tiktoken.FIM_MIDDLE:  100259,
+	tiktoken.FIM_SUFFIX:  100260,
+	tiktoken.ENDOFPROMPT: 100276,
+}
+
+// parseBPERanks parses a tiktoken ranks file, one base64 token and its rank
+// per line.
+func parseBPERanks(data []byte) (map[string]int, error) {
+	ranks := make(map[string]int)
+	for i, line := range bytes.Split(data, []byte("\n")) {
+		line = bytes.TrimSuffix(line, []byte("\r"))
+		if len(line) == 0 {
+			continue
+		}
+		token, rank, ok := bytes.Cut(line, []byte(" "))
+		if !ok {
+			return nil, fmt.Errorf("BPE ranks line %d: expected a token and a rank", i+1)
+		}
+		decoded, err := base64.StdEncoding.DecodeString(string(token))
+		if err != nil {
+			return nil, fmt.Errorf("BPE ranks line %d: %w", i+1, err)
+		}
+		r, err := strconv.Atoi(string(rank))
+		if err != nil {
+			return nil, fmt.Errorf("BPE ranks line %d: %w", i+1, err)
+		}
+		ranks[string(decoded)] = r
+	}
+	if len(ranks) == 0 {
+		return nil, fmt.Errorf("BPE ranks are empty")
+	}
+	return ranks, nil
+}
+
+// newCl100kBase builds the cl100k_base encoding from its ranks instead of
+// letting tiktoken download them.
+func newCl100kBase(ranks map[string]int) (*tiktoken.Tiktoken, error) {
+	special := cl100kBaseSpecialTokens
+	bpe, err := tiktoken.NewCoreBPE(ranks, special, cl100kBasePattern)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build BPE: %w", err)
+	}
+	specialSet := make(map[string]any, len(special))
+	for k := range special {
+		specialSet[k] = true
+	}
+	encoding := &tiktoken.Encoding{
+		Name:           tiktoken.MODEL_CL100K_BASE,
+		PatStr:         cl100kBasePattern,
+		MergeableRanks: ranks,
+		SpecialTokens:  special,
+	}
+	return tiktoken.NewTiktoken(bpe, encoding, specialSet), nil
+}
+
+// contentRanks returns the BPE ranks of the content tokenizer, loading them
+// as tiktoken does when they were not given to NewTokenizerFromBytes.
+func (t *Tokenizer) contentRanks() (map[string]int, error) {
+	if t.bpeRanks != nil {
+		return t.bpeRanks, nil
+	}
+	return tiktoken.NewDefaultBpeLoader().LoadTiktokenBpe(cl100kBaseURL)
+}
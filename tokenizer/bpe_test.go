@@ -0,0 +1,81 @@
+package tokenizer
+
+import (
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// byteRanks returns a ranks file mapping every byte to its value, so content
+// tokens are the bytes of the text.
+func byteRanks() []byte {
+	var b strings.Builder
+	for i := 0; i < 256; i++ {
+		fmt.Fprintf(&b, "%s %d\n", base64.StdEncoding.EncodeToString([]byte{byte(i)}), i)
+	}
+	return []byte(b.String())
+}
+
+func TestNewTokenizerFromBytes(t *testing.T) {
+	vocab, err := json.Marshal(map[string]int{"<Test>": 200001, "</Test>": 200002})
+	require.NoError(t, err)
+
+	tok, err := NewTokenizerFromBytes(vocab, byteRanks())
+	require.NoError(t, err)
+	res, err := tok.Tokenize(strings.NewReader("<Test>ab</Test>"))
+	require.NoError(t, err)
+	assert.Equal(t, []int{200001, 'a', 'b', 200002}, res.Tokens)
+	assert.Equal(t, "<Test> a b </Test>", tok.Decode(res.Tokens))
+
+	_, err = NewTokenizerFromBytes([]byte("{"), byteRanks())
+	assert.ErrorContains(t, err, "failed to decode vocab")
+	_, err = NewTokenizerFromBytes(vocab, nil)
+	assert.ErrorContains(t, err, "BPE ranks are empty")
+	_, err = NewTokenizerFromBytes(vocab, []byte("YQ== 0\nnope\n"))
+	assert.ErrorContains(t, err, "BPE ranks line 2")
+	_, err = NewTokenizerFromBytes([]byte(`{"<Test>": 5}`), byteRanks())
+	assert.ErrorContains(t, err, "overlaps")
+}
+
+// cachedRanks returns the cl100k_base ranks file from the tiktoken cache, which
+// stores it under the SHA-1 of its URL, or skips the test.
+func cachedRanks(t *testing.T) []byte {
+	dir := os.Getenv("TIKTOKEN_CACHE_DIR")
+	if dir == "" {
+		t.Skip("TIKTOKEN_CACHE_DIR is not set")
+	}
+	ranks, err := os.ReadFile(filepath.Join(dir, fmt.Sprintf("%x", sha1.Sum([]byte(cl100kBaseURL)))))
+	if err != nil {
+		t.Skip("cl100k_base ranks are not cached")
+	}
+	return ranks
+}
+
+func TestNewTokenizerFromBytes_MatchesNewTokenizer(t *testing.T) {
+	ranks := cachedRanks(t)
+
+	vocab := map[string]int{"<Doc>": 200001, "</Doc>": 200002, "##id": 200003, TokenValueEnd: 200004}
+	vocabPath := createTempVocab(t, vocab)
+	defer os.Remove(vocabPath)
+	expected, err := NewTokenizer(vocabPath)
+	require.NoError(t, err)
+	vocabData, err := json.Marshal(vocab)
+	require.NoError(t, err)
+	tok, err := NewTokenizerFromBytes(vocabData, ranks)
+	require.NoError(t, err)
+
+	const doc = `<Doc id="x-1">Hello, world! It's 2024 and    ünïcode  works.</Doc>`
+	want, err := expected.Tokenize(strings.NewReader(doc))
+	require.NoError(t, err)
+	got, err := tok.Tokenize(strings.NewReader(doc))
+	require.NoError(t, err)
+	assert.Equal(t, want, got)
+}
@@ -0,0 +1,489 @@
+package tokenizer
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+)
+
+// ConstraintSchema optionally narrows what a Constraint accepts beyond
+// well-formedness. A nil map, or a tag missing from a map, leaves that aspect
+// unrestricted.
+type ConstraintSchema struct {
+	// Roots lists the tag names allowed as the document element.
+	Roots []string
+	// Children maps a tag name to the tag names allowed as its direct children.
+	Children map[string][]string
+	// Attributes maps a tag name to the registered attribute names it accepts.
+	// Tags listed here do not accept unregistered attributes.
+	Attributes map[string][]string
+	// Model applies the content models of an XSD or DTD, see
+	// Schema.ConstraintSchema: children follow their declared order and
+	// occurrences, required attributes come before the body, and content is
+	// allowed only where the element accepts text. It takes precedence over
+	// Children and Attributes for the elements it declares.
+	Model *Schema
+}
+
+// AllowedTokens is the set of token IDs permitted at the current position.
+type AllowedTokens struct {
+	// Content reports whether content tokens (IDs produced by the content
+	// tokenizer) are allowed.
+	Content bool
+	// IDs lists the allowed vocab token IDs in ascending order.
+	IDs []int
+}
+
+type constraintState int
+
+const (
+	stateElement constraintState = iota
+	stateRegisteredValue
+	stateRegisteredEmpty
+	stateUnregisteredStart
+	stateKey
+	stateKeyContent
+	stateAfterKey
+	stateUnregisteredValue
+	stateAfterValue
+)
+
+type constraintFrame struct {
+	name    string
+	fresh   bool // nothing was emitted since the start tag
+	hasBody bool // a child or content was emitted, attributes are closed
+	attrs   map[string]bool
+	decl    *ElementDecl   // declaration from ConstraintSchema.Model, if any
+	matcher contentMatcher // children consumed so far, with decl
+}
+
+// Constraint consumes token IDs one at a time and reports which IDs may come
+// next, following the grammar produced by the Encoder and read by DecodeXML.
+type Constraint struct {
+	vocab    map[string]int
+	vocabInv map[int]string
+	schema   *ConstraintSchema
+
+	startTags   []int
+	attributes  []int
+	typedValues []int
+
+	stack    []*constraintFrame
+	state    constraintState
+	valueLen int
+	typedEnd string // closing token of the open typed value, if any
+	inSet    bool   // the attribute value holds set items
+	done     bool
+}
+
+// NewConstraint builds a Constraint for the given vocab. The schema may be nil.
+func NewConstraint(vocab map[string]int, schema *ConstraintSchema) *Constraint {
+	c := &Constraint{
+		vocab:    vocab,
+		vocabInv: make(map[int]string, len(vocab)),
+		schema:   schema,
+	}
+	for k, v := range vocab {
+		c.vocabInv[v] = k
+	}
+	for id := range c.vocabInv {
+		switch kind, _ := classifyToken(c.vocabInv, id); kind {
+		case KindStartTag:
+			c.startTags = append(c.startTags, id)
+		case KindRegisteredAttr:
+			c.attributes = append(c.attributes, id)
+		case KindBoolean:
+			c.typedValues = append(c.typedValues, id)
+		case KindTypedValue:
+			if _, ok := vocab[typedValueEnd(c.vocabInv[id])]; ok {
+				c.typedValues = append(c.typedValues, id)
+			}
+		}
+	}
+	sort.Ints(c.startTags)
+	sort.Ints(c.attributes)
+	sort.Ints(c.typedValues)
+	return c
+}
+
+// NewConstraint builds a Constraint over the tokenizer vocab.
+func (t *Tokenizer) NewConstraint(schema *ConstraintSchema) *Constraint {
+	return NewConstraint(t.vocab, schema)
+}
+
+// Done reports whether the document element has been closed.
+func (c *Constraint) Done() bool {
+	return c.done
+}
+
+// Depth returns the number of currently open elements.
+func (c *Constraint) Depth() int {
+	return len(c.stack)
+}
+
+// CanClose reports whether the end tag of the current element may come next,
+// that is, whether its content is complete.
+func (c *Constraint) CanClose() bool {
+	if len(c.stack) == 0 {
+		return false
+	}
+	id, ok := c.vocab["</"+c.stack[len(c.stack)-1].name+">"]
+	return ok && c.Allows(id)
+}
+
+// Allowed returns the set of token IDs that may legally come next.
+func (c *Constraint) Allowed() *AllowedTokens {
+	allowed := &AllowedTokens{}
+	if c.done {
+		return allowed
+	}
+
+	if len(c.stack) == 0 {
+		allowed.IDs = c.filterTags(c.startTags, c.schemaRoots())
+		return allowed
+	}
+
+	if c.typedEnd != "" {
+		allowed.Content = true
+		allowed.IDs = c.appendIfInVocab(allowed.IDs, c.typedEnd)
+		return allowed
+	}
+
+	switch c.state {
+	case stateElement:
+		return c.elementAllowed()
+	case stateRegisteredValue:
+		if !c.inSet {
+			allowed.Content = true
+			allowed.IDs = append(allowed.IDs, c.typedValues...)
+			if c.valueLen == 0 {
+				allowed.IDs = c.appendIfInVocab(allowed.IDs, TokenEmpty)
+			}
+		}
+		allowed.IDs = c.appendSetItem(allowed.IDs)
+		return c.withValueEnd(allowed)
+	case stateRegisteredEmpty:
+		return c.withValueEnd(allowed)
+	case stateUnregisteredStart:
+		allowed.IDs = c.appendIfInVocab(allowed.IDs, TokenKey)
+	case stateKey:
+		allowed.Content = true
+	case stateKeyContent:
+		allowed.Content = true
+		allowed.IDs = c.appendIfInVocab(allowed.IDs, TokenKeyEnd)
+	case stateAfterKey:
+		allowed.IDs = c.appendIfInVocab(allowed.IDs, TokenValue)
+	case stateUnregisteredValue:
+		if !c.inSet {
+			allowed.Content = true
+			allowed.IDs = append(allowed.IDs, c.typedValues...)
+		}
+		allowed.IDs = c.appendSetItem(allowed.IDs)
+		allowed.IDs = c.appendIfInVocab(allowed.IDs, TokenValueEnd)
+		sort.Ints(allowed.IDs)
+	case stateAfterValue:
+		allowed.IDs = c.appendIfInVocab(allowed.IDs, TokenUnregisteredAttrEnd)
+	}
+	return allowed
+}
+
+// Allows reports whether the given token ID may legally come next.
+func (c *Constraint) Allows(id int) bool {
+	allowed := c.Allowed()
+	kind, _ := classifyToken(c.vocabInv, id)
+	if kind == KindContent {
+		return allowed.Content
+	}
+	i := sort.SearchInts(allowed.IDs, id)
+	return i < len(allowed.IDs) && allowed.IDs[i] == id
+}
+
+// Consume advances the constraint by one token. It returns an error and
+// leaves the state unchanged if the token is not allowed at this position.
+func (c *Constraint) Consume(id int) error {
+	if !c.Allows(id) {
+		return fmt.Errorf("token %d (%s) is not allowed at this position", id, c.describe(id))
+	}
+
+	kind, name := classifyToken(c.vocabInv, id)
+
+	// Without </__Value> in the vocab, a registered value ends implicitly at
+	// the next structural token, exactly as DecodeXML reads it.
+	if (c.state == stateRegisteredValue || c.state == stateRegisteredEmpty) &&
+		kind != KindContent && kind != KindEmpty && kind != KindValueEnd &&
+		kind != KindTypedValue && kind != KindTypedValueEnd && kind != KindBoolean &&
+		kind != KindItem && kind != KindItemEnd {
+		c.state = stateElement
+	}
+
+	if len(c.stack) > 0 {
+		c.stack[len(c.stack)-1].fresh = false
+	}
+
+	switch kind {
+	case KindStartTag:
+		if len(c.stack) > 0 {
+			parent := c.stack[len(c.stack)-1]
+			parent.hasBody = true
+			if parent.matcher != nil {
+				parent.matcher.next(name)
+			}
+		}
+		frame := &constraintFrame{name: name, fresh: true, attrs: make(map[string]bool), decl: c.elementDecl(name)}
+		if frame.decl != nil {
+			frame.matcher = frame.decl.newMatcher()
+		}
+		c.stack = append(c.stack, frame)
+	case KindEndTag:
+		c.stack = c.stack[:len(c.stack)-1]
+		if len(c.stack) == 0 {
+			c.done = true
+		}
+	case KindRegisteredAttr:
+		c.stack[len(c.stack)-1].attrs[name] = true
+		c.state = stateRegisteredValue
+		c.valueLen = 0
+		c.inSet = false
+	case KindEmpty:
+		c.state = stateRegisteredEmpty
+	case KindUnregisteredAttr:
+		c.state = stateUnregisteredStart
+	case KindKey:
+		c.state = stateKey
+	case KindKeyEnd:
+		c.state = stateAfterKey
+	case KindValue:
+		c.state = stateUnregisteredValue
+		c.valueLen = 0
+		c.inSet = false
+	case KindValueEnd:
+		if c.state == stateUnregisteredValue {
+			c.state = stateAfterValue
+		} else {
+			c.state = stateElement
+		}
+	case KindUnregisteredAttrEnd:
+		c.state = stateElement
+	case KindItem:
+		c.inSet = true
+		c.typedEnd = TokenItemEnd
+	case KindTypedValueEnd, KindItemEnd:
+		c.typedEnd = ""
+	case KindContent, KindTypedValue, KindBoolean:
+		if kind == KindTypedValue {
+			c.typedEnd = typedValueEnd(c.vocabInv[id])
+		}
+		switch c.state {
+		case stateElement:
+			c.stack[len(c.stack)-1].hasBody = true
+		case stateRegisteredValue, stateUnregisteredValue:
+			if !c.inSet {
+				c.valueLen++
+			}
+		case stateKey:
+			c.state = stateKeyContent
+		}
+	}
+	return nil
+}
+
+func (c *Constraint) clone() *Constraint {
+	clone := *c
+	clone.stack = make([]*constraintFrame, len(c.stack))
+	for i, frame := range c.stack {
+		attrs := make(map[string]bool, len(frame.attrs))
+		for k, v := range frame.attrs {
+			attrs[k] = v
+		}
+		clone.stack[i] = &constraintFrame{name: frame.name, fresh: frame.fresh, hasBody: frame.hasBody, attrs: attrs, decl: frame.decl}
+		if frame.matcher != nil {
+			clone.stack[i].matcher = frame.matcher.clone()
+		}
+	}
+	return &clone
+}
+
+// elementAllowed returns the tokens allowed directly inside the current element.
+func (c *Constraint) elementAllowed() *AllowedTokens {
+	frame := c.stack[len(c.stack)-1]
+	allowed := &AllowedTokens{Content: frame.decl == nil || frame.decl.Text || frame.decl.Any}
+
+	if frame.fresh {
+		allowed.IDs = c.appendIfInVocab(allowed.IDs, TokenOrdered)
+		allowed.IDs = c.appendIfInVocab(allowed.IDs, TokenUnordered)
+	}
+
+	if !frame.hasBody {
+		names, restricted := c.schemaAttributes(frame)
+		for _, id := range c.attributes {
+			_, name := classifyToken(c.vocabInv, id)
+			if frame.attrs[name] || (restricted && !slices.Contains(names, name)) {
+				continue
+			}
+			allowed.IDs = append(allowed.IDs, id)
+		}
+		if !restricted && c.hasUnregisteredTokens() {
+			allowed.IDs = c.appendIfInVocab(allowed.IDs, TokenUnregisteredAttr)
+		}
+		// The body cannot start before the required attributes.
+		if c.missingAttributes(frame) {
+			allowed.Content = false
+			sort.Ints(allowed.IDs)
+			return allowed
+		}
+	}
+
+	if allowed.Content {
+		allowed.IDs = append(allowed.IDs, c.typedValues...)
+	}
+	allowed.IDs = append(allowed.IDs, c.filterTags(c.startTags, c.schemaChildren(frame))...)
+	if frame.matcher == nil || frame.matcher.canClose() {
+		allowed.IDs = c.appendIfInVocab(allowed.IDs, "</"+frame.name+">")
+	}
+	sort.Ints(allowed.IDs)
+	return allowed
+}
+
+// withValueEnd completes the allowed set of a registered attribute value.
+func (c *Constraint) withValueEnd(allowed *AllowedTokens) *AllowedTokens {
+	if _, ok := c.vocab[TokenValueEnd]; ok {
+		allowed.IDs = c.appendIfInVocab(allowed.IDs, TokenValueEnd)
+		sort.Ints(allowed.IDs)
+		return allowed
+	}
+	element := c.elementAllowed()
+	element.Content = allowed.Content
+	element.IDs = append(element.IDs, allowed.IDs...)
+	sort.Ints(element.IDs)
+	element.IDs = slices.Compact(element.IDs)
+	return element
+}
+
+// appendSetItem appends <__Item> while the attribute value holds no text, so
+// a value is either text or a set of items.
+func (c *Constraint) appendSetItem(ids []int) []int {
+	if _, ok := c.vocab[TokenItemEnd]; !ok || c.valueLen > 0 {
+		return ids
+	}
+	return c.appendIfInVocab(ids, TokenItem)
+}
+
+func (c *Constraint) hasUnregisteredTokens() bool {
+	for _, tok := range []string{TokenUnregisteredAttr, TokenUnregisteredAttrEnd, TokenKey, TokenKeyEnd, TokenValue, TokenValueEnd} {
+		if _, ok := c.vocab[tok]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func (c *Constraint) appendIfInVocab(ids []int, tok string) []int {
+	if id, ok := c.vocab[tok]; ok {
+		return append(ids, id)
+	}
+	return ids
+}
+
+// filterTags keeps the start tag IDs whose name is in names. A nil names
+// slice keeps every tag.
+func (c *Constraint) filterTags(ids []int, names []string) []int {
+	var out []int
+	for _, id := range ids {
+		_, name := classifyToken(c.vocabInv, id)
+		if names == nil || slices.Contains(names, name) {
+			out = append(out, id)
+		}
+	}
+	return out
+}
+
+func (c *Constraint) schemaRoots() []string {
+	if c.schema == nil {
+		return nil
+	}
+	if len(c.schema.Roots) == 0 && c.schema.Model != nil {
+		return c.schema.Model.ElementNames()
+	}
+	if len(c.schema.Roots) == 0 {
+		return nil
+	}
+	return c.schema.Roots
+}
+
+// elementDecl returns the declaration of tag in the schema model, if any.
+func (c *Constraint) elementDecl(tag string) *ElementDecl {
+	if c.schema == nil || c.schema.Model == nil {
+		return nil
+	}
+	return c.schema.Model.Elements[tag]
+}
+
+func (c *Constraint) schemaChildren(frame *constraintFrame) []string {
+	if frame.matcher != nil {
+		names := frame.matcher.allowed()
+		if slices.Contains(names, "*") {
+			return nil
+		}
+		if names == nil {
+			return []string{}
+		}
+		return names
+	}
+	if c.schema == nil || c.schema.Children == nil {
+		return nil
+	}
+	children, ok := c.schema.Children[frame.name]
+	if !ok {
+		return nil
+	}
+	if children == nil {
+		return []string{}
+	}
+	return children
+}
+
+func (c *Constraint) schemaAttributes(frame *constraintFrame) ([]string, bool) {
+	if frame.decl != nil {
+		if frame.decl.AnyAttribute {
+			return nil, false
+		}
+		names := make([]string, len(frame.decl.Attributes))
+		for i, a := range frame.decl.Attributes {
+			names[i] = a.Name
+		}
+		return names, true
+	}
+	if c.schema == nil || c.schema.Attributes == nil {
+		return nil, false
+	}
+	names, ok := c.schema.Attributes[frame.name]
+	return names, ok
+}
+
+// missingAttributes reports whether a required attribute of the element was
+// not emitted yet. Attributes missing from the vocab cannot be emitted and
+// are not waited for.
+func (c *Constraint) missingAttributes(frame *constraintFrame) bool {
+	if frame.decl == nil {
+		return false
+	}
+	for _, a := range frame.decl.Attributes {
+		if _, ok := c.vocab["##"+a.Name]; ok && a.Required && !frame.attrs[a.Name] {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *Constraint) describe(id int) string {
+	if s, ok := c.vocabInv[id]; ok {
+		return s
+	}
+	kind, _ := classifyToken(c.vocabInv, id)
+	return kind.String()
+}
+
+// typedValueEnd returns the closing token of a typed value start token.
+func typedValueEnd(start string) string {
+	return "</" + start[1:]
+}
@@ -0,0 +1,305 @@
+package tokenizer
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func createConstraintVocab() map[string]int {
+	return map[string]int{
+		"<root>":                 100,
+		"</root>":                101,
+		"<child>":                102,
+		"</child>":               103,
+		"<other>":                104,
+		"</other>":               105,
+		"##attr":                 110,
+		"##attr2":                111,
+		TokenUnregisteredAttr:    120,
+		TokenUnregisteredAttrEnd: 121,
+		TokenKey:                 122,
+		TokenKeyEnd:              123,
+		TokenValue:               124,
+		TokenValueEnd:            125,
+		TokenEmpty:               126,
+		TokenRegisteredAttr:      127,
+	}
+}
+
+func TestConstraint_AllowedSets(t *testing.T) {
+	c := NewConstraint(createConstraintVocab(), nil)
+	const content = 42
+
+	t.Run("Root", func(t *testing.T) {
+		allowed := c.Allowed()
+		assert.False(t, allowed.Content)
+		assert.Equal(t, []int{100, 102, 104}, allowed.IDs)
+		assert.False(t, c.Allows(content))
+		assert.False(t, c.Allows(101))
+	})
+
+	require.NoError(t, c.Consume(100))
+
+	t.Run("AttributePhase", func(t *testing.T) {
+		allowed := c.Allowed()
+		assert.True(t, allowed.Content)
+		assert.Equal(t, []int{100, 101, 102, 104, 110, 111, 120}, allowed.IDs)
+		assert.False(t, c.Allows(103), "only the matching close tag is allowed")
+		assert.False(t, c.Allows(127), "<__RegisteredAttr> is never emitted")
+	})
+
+	t.Run("RegisteredAttr", func(t *testing.T) {
+		require.NoError(t, c.Consume(110))
+		assert.Equal(t, []int{125, 126}, c.Allowed().IDs)
+		assert.True(t, c.Allows(content))
+
+		require.NoError(t, c.Consume(content))
+		assert.Equal(t, []int{125}, c.Allowed().IDs, "<__Empty/> only as the whole value")
+
+		require.NoError(t, c.Consume(125))
+		assert.False(t, c.Allows(110), "an attribute cannot be repeated")
+		assert.True(t, c.Allows(111))
+	})
+
+	t.Run("RegisteredAttr_Empty", func(t *testing.T) {
+		require.NoError(t, c.Consume(111))
+		require.NoError(t, c.Consume(126))
+		allowed := c.Allowed()
+		assert.False(t, allowed.Content)
+		assert.Equal(t, []int{125}, allowed.IDs)
+		require.NoError(t, c.Consume(125))
+	})
+
+	t.Run("UnregisteredAttr", func(t *testing.T) {
+		require.NoError(t, c.Consume(120))
+		assert.Equal(t, []int{122}, c.Allowed().IDs)
+		require.NoError(t, c.Consume(122))
+
+		allowed := c.Allowed()
+		assert.True(t, allowed.Content)
+		assert.Empty(t, allowed.IDs, "a key needs at least one content token")
+
+		require.NoError(t, c.Consume(content))
+		assert.Equal(t, []int{123}, c.Allowed().IDs)
+		require.NoError(t, c.Consume(123))
+		assert.Equal(t, []int{124}, c.Allowed().IDs)
+		require.NoError(t, c.Consume(124))
+		assert.Equal(t, []int{125}, c.Allowed().IDs)
+		require.NoError(t, c.Consume(125))
+		assert.Equal(t, []int{121}, c.Allowed().IDs)
+		require.NoError(t, c.Consume(121))
+	})
+
+	t.Run("Body", func(t *testing.T) {
+		require.NoError(t, c.Consume(content))
+		assert.Equal(t, []int{100, 101, 102, 104}, c.Allowed().IDs, "attributes are closed once the body starts")
+
+		require.NoError(t, c.Consume(102))
+		assert.True(t, c.Allows(103))
+		assert.False(t, c.Allows(101))
+		require.NoError(t, c.Consume(103))
+		assert.Equal(t, 1, c.Depth())
+	})
+
+	t.Run("Done", func(t *testing.T) {
+		require.NoError(t, c.Consume(101))
+		assert.True(t, c.Done())
+		allowed := c.Allowed()
+		assert.False(t, allowed.Content)
+		assert.Empty(t, allowed.IDs)
+	})
+}
+
+func TestConstraint_RejectsInvalidToken(t *testing.T) {
+	c := NewConstraint(createConstraintVocab(), nil)
+	require.NoError(t, c.Consume(100))
+
+	err := c.Consume(103)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "</child>")
+
+	// State is unchanged after a rejected token.
+	assert.True(t, c.Allows(101))
+	assert.Equal(t, 1, c.Depth())
+
+	err = c.Consume(Cl100kBaseMaxID + 1)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "unknown")
+}
+
+func TestConstraint_ImplicitValueEnd(t *testing.T) {
+	vocab := map[string]int{
+		"<root>":   100,
+		"</root>":  101,
+		"<child>":  102,
+		"</child>": 103,
+		"##attr":   110,
+	}
+	c := NewConstraint(vocab, nil)
+	require.NoError(t, c.Consume(100))
+	require.NoError(t, c.Consume(110))
+	require.NoError(t, c.Consume(42))
+
+	// Without </__Value>, the value ends at the next structural token.
+	assert.True(t, c.Allows(102))
+	require.NoError(t, c.Consume(102))
+	require.NoError(t, c.Consume(103))
+	assert.False(t, c.Allows(110))
+	require.NoError(t, c.Consume(101))
+	assert.True(t, c.Done())
+}
+
+func TestConstraint_Schema(t *testing.T) {
+	schema := &ConstraintSchema{
+		Roots:      []string{"root"},
+		Children:   map[string][]string{"root": {"child"}, "child": nil},
+		Attributes: map[string][]string{"root": {"attr2"}},
+	}
+	c := NewConstraint(createConstraintVocab(), schema)
+
+	assert.Equal(t, []int{100}, c.Allowed().IDs)
+	require.NoError(t, c.Consume(100))
+
+	// Only ##attr2, no unregistered attributes, only <child> as a child.
+	assert.Equal(t, []int{101, 102, 111}, c.Allowed().IDs)
+
+	// child declares no children and leaves its attributes unrestricted.
+	require.NoError(t, c.Consume(102))
+	assert.Equal(t, []int{103, 110, 111, 120}, c.Allowed().IDs)
+	assert.True(t, c.Allows(42))
+}
+
+func TestConstraint_AcceptsEncoderOutput(t *testing.T) {
+	base := 200000
+	vocab := map[string]int{
+		"<Root>":                base + 1,
+		"</Root>":               base + 2,
+		"<Child>":               base + 3,
+		"</Child>":              base + 4,
+		"<Leaf>":                base + 5,
+		"</Leaf>":               base + 6,
+		"##id":                  base + 100,
+		"##extra":               base + 101,
+		"<__UnregisteredAttr>":  base + 200,
+		"</__UnregisteredAttr>": base + 201,
+		"<__Key>":               base + 202,
+		"</__Key>":              base + 203,
+		"<__Value>":             base + 204,
+		"</__Value>":            base + 205,
+		"<__Empty/>":            base + 206,
+	}
+	vocabPath := createTempVocab(t, vocab)
+	defer os.Remove(vocabPath)
+	tokenizer, err := NewTokenizer(vocabPath)
+	require.NoError(t, err)
+
+	inputs := []string{
+		`<Root><Child>A</Child><Child>B</Child></Root>`,
+		`<Root id="1" type="x"><Child extra="">text</Child></Root>`,
+		`<Root unknown="val"><Child><Child><Leaf>deep</Leaf></Child></Child></Root>`,
+		`<Root arbor-ordered="true" id="">mixed <Leaf>content</Leaf> here</Root>`,
+	}
+
+	for _, input := range inputs {
+		res, err := tokenizer.Tokenize(strings.NewReader(input))
+		require.NoError(t, err)
+
+		c := tokenizer.NewConstraint(nil)
+		for i, id := range res.Tokens {
+			require.NoError(t, c.Consume(id), "token %d of %s", i, input)
+		}
+		assert.True(t, c.Done(), input)
+	}
+}
+
+func TestConstraint_OrderingMarkers(t *testing.T) {
+	vocab := createConstraintVocab()
+	vocab[TokenOrdered] = 130
+	vocab[TokenUnordered] = 131
+	c := NewConstraint(vocab, nil)
+
+	require.NoError(t, c.Consume(100))
+	assert.True(t, c.Allows(130))
+	assert.True(t, c.Allows(131))
+
+	require.NoError(t, c.Consume(131))
+	assert.False(t, c.Allows(130), "only one marker, right after the start tag")
+	assert.True(t, c.Allows(110))
+
+	require.NoError(t, c.Consume(110))
+	require.NoError(t, c.Consume(125))
+	assert.False(t, c.Allows(131))
+}
+
+func TestConstraint_SchemaModel(t *testing.T) {
+	schema, err := LoadSchema("testdata/library.xsd")
+	require.NoError(t, err)
+	tokenizer := newTypedTokenizer(t, schema.Vocab(200000), WithOrderingRules(schema.OrderingRules()))
+	vocab := tokenizer.vocab
+	ids := func(toks ...string) []int {
+		out := make([]int, len(toks))
+		for i, tok := range toks {
+			out[i] = vocab[tok]
+		}
+		return out
+	}
+	const content = 42
+
+	c := tokenizer.NewConstraint(schema.ConstraintSchema())
+	assert.Equal(t, ids("<Book>", "<Library>"), c.Allowed().IDs)
+
+	// The required id comes before anything else.
+	require.NoError(t, c.Consume(vocab["<Library>"]))
+	assert.Equal(t, ids("##id"), c.Allowed().IDs)
+	assert.False(t, c.Allows(content))
+	assert.False(t, c.CanClose())
+	for _, id := range []int{vocab["##id"], content, vocab[TokenValueEnd]} {
+		require.NoError(t, c.Consume(id))
+	}
+
+	// The sequence starts with Name and Library has no text.
+	assert.Equal(t, ids("<Name>"), c.Allowed().IDs)
+	assert.False(t, c.Allows(content))
+	for _, id := range []int{vocab["<Name>"], content, vocab["</Name>"]} {
+		require.NoError(t, c.Consume(id))
+	}
+	assert.Equal(t, ids("<Book>", "</Library>"), c.Allowed().IDs)
+	assert.True(t, c.CanClose())
+
+	// Book is an xs:all group: any order, each child once, Title and Author
+	// required.
+	require.NoError(t, c.Consume(vocab["<Book>"]))
+	assert.Equal(t, ids("<Author>", "<Title>", "<Year>", "##isbn", "##lang"), c.Allowed().IDs)
+	assert.False(t, c.CanClose())
+	for _, id := range []int{vocab["<Title>"], content, vocab["</Title>"]} {
+		require.NoError(t, c.Consume(id))
+	}
+	assert.Equal(t, ids("<Author>", "<Year>"), c.Allowed().IDs)
+
+	trial := c.clone()
+	require.NoError(t, trial.Consume(vocab["<Author>"]))
+	assert.Equal(t, ids("<Author>", "<Year>"), c.Allowed().IDs, "clones do not share content state")
+
+	for _, id := range []int{vocab["<Author>"], content, vocab["</Author>"]} {
+		require.NoError(t, c.Consume(id))
+	}
+	assert.Equal(t, ids("</Book>", "<Year>"), c.Allowed().IDs)
+	assert.True(t, c.CanClose())
+	assert.Error(t, c.Consume(vocab["<Title>"]))
+
+	// The encoding of a valid document is accepted.
+	f, err := os.Open("testdata/library.xml")
+	require.NoError(t, err)
+	defer f.Close()
+	res, err := tokenizer.Tokenize(f)
+	require.NoError(t, err)
+	c = tokenizer.NewConstraint(schema.ConstraintSchema())
+	for i, id := range res.Tokens {
+		require.NoError(t, c.Consume(id), "token %d", i)
+	}
+	assert.True(t, c.Done())
+}
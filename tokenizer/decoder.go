@@ -3,6 +3,7 @@ package tokenizer
 import (
 	"encoding/xml"
 	"fmt"
+	"slices"
 	"strings"
 )
 
@@ -29,10 +30,32 @@ func (t *Tokenizer) DecodeXML(tokens []int) (*Element, error) {
 		s, isVocab := getTokenInfo(id)
 		i++
 
+		// Ordering marker restores the arbor-ordered attribute
+		if isVocab && (s == TokenOrdered || s == TokenUnordered) {
+			if len(stack) > 0 {
+				current := stack[len(stack)-1]
+				current.Attributes = append(current.Attributes, xml.Attr{
+					Name:  xml.Name{Local: ArborOrderedAttribute},
+					Value: fmt.Sprint(s == TokenOrdered),
+				})
+			}
+			continue
+		}
+
+		// Typed value tokens decode to their literal text
+		if isVocab {
+			if lit, ok := typedLiteral(s); ok {
+				if lit == "" {
+					continue
+				}
+				s, isVocab = lit, false
+			}
+		}
+
 		// Start Element (Must be in Vocab)
 		if isVocab && strings.HasPrefix(s, "<") && !strings.HasPrefix(s, "</") &&
 			s != TokenUnregisteredAttr && s != TokenKey && s != TokenValue &&
-			s != TokenKeyEnd && s != TokenValueEnd && s != TokenUnregisteredAttrEnd && s != TokenEmpty {
+			s != TokenKeyEnd && s != TokenValueEnd && s != TokenUnregisteredAttrEnd && s != TokenEmpty && s != TokenItem {
 
 			// Clean tag name
 			tagName := strings.TrimSuffix(strings.TrimPrefix(s, "<"), ">")
@@ -49,7 +72,7 @@ func (t *Tokenizer) DecodeXML(tokens []int) (*Element, error) {
 		}
 
 		// End Element (Must be in Vocab)
-		if isVocab && strings.HasPrefix(s, "</") && s != TokenUnregisteredAttrEnd && s != TokenKeyEnd && s != TokenValueEnd {
+		if isVocab && strings.HasPrefix(s, "</") && s != TokenUnregisteredAttrEnd && s != TokenKeyEnd && s != TokenValueEnd && s != TokenItemEnd {
 			if len(stack) == 0 {
 				return nil, fmt.Errorf("unexpected end tag: %s", s)
 			}
@@ -76,6 +99,9 @@ func (t *Tokenizer) DecodeXML(tokens []int) (*Element, error) {
 				i++
 
 				if subIsVocab {
+					if lit, ok := typedLiteral(subS); ok {
+						subS = lit
+					}
 					if subS == TokenUnregisteredAttrEnd {
 						break
 					}
@@ -95,6 +121,16 @@ func (t *Tokenizer) DecodeXML(tokens []int) (*Element, error) {
 						state = 0
 						continue
 					}
+					// Set members are joined with single spaces.
+					if subS == TokenItem {
+						if val.Len() > 0 {
+							val.WriteString(" ")
+						}
+						continue
+					}
+					if subS == TokenItemEnd {
+						continue
+					}
 				}
 
 				switch state {
@@ -134,6 +170,23 @@ func (t *Tokenizer) DecodeXML(tokens []int) (*Element, error) {
 				subId := tokens[i]
 				subS, subIsVocab := getTokenInfo(subId)
 
+				// Typed value tokens are part of the value
+				if subIsVocab {
+					if lit, ok := typedLiteral(subS); ok {
+						i++
+						valSb.WriteString(lit)
+						continue
+					}
+					// Set members are joined with single spaces.
+					if subS == TokenItem || subS == TokenItemEnd {
+						i++
+						if subS == TokenItem && valSb.Len() > 0 {
+							valSb.WriteString(" ")
+						}
+						continue
+					}
+				}
+
 				// Stop if delimiter (Must be Vocab)
 				if subIsVocab && subS == TokenValueEnd {
 					i++ // consume delimiter
@@ -163,7 +216,8 @@ func (t *Tokenizer) DecodeXML(tokens []int) (*Element, error) {
 		}
 
 		// Skip special tokens if they appear out of place
-		if isVocab && (s == TokenValueEnd || s == TokenUnregisteredAttrEnd || s == TokenKey || s == TokenKeyEnd || s == TokenValue || s == TokenEmpty) {
+		if isVocab && (s == TokenValueEnd || s == TokenUnregisteredAttrEnd || s == TokenKey || s == TokenKeyEnd || s == TokenValue || s == TokenEmpty ||
+			s == TokenItem || s == TokenItemEnd) {
 			continue
 		}
 
@@ -180,3 +234,49 @@ func (t *Tokenizer) DecodeXML(tokens []int) (*Element, error) {
 
 	return root, nil
 }
+
+// PathMismatch reports a token whose path differs from the one the Encoder
+// assigns when re-encoding the reconstructed tree.
+type PathMismatch struct {
+	Index    int
+	Token    int
+	Expected []int // nil if the token has no place in the reconstructed tree
+	Actual   []int
+}
+
+// DecodeXMLWithPaths reconstructs the XML structure from tokens like DecodeXML
+// and checks that each token's path matches what re-encoding the
+// reconstructed tree would produce. Expected paths are computed token by
+// token, so content split differently than the content tokenizer would split
+// it still lines up. Padding in paths is ignored.
+func (t *Tokenizer) DecodeXMLWithPaths(tokens []int, paths [][]int) (*Element, []PathMismatch, error) {
+	if len(tokens) != len(paths) {
+		return nil, nil, fmt.Errorf("got %d tokens but %d paths", len(tokens), len(paths))
+	}
+
+	root, err := t.DecodeXML(tokens)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var mismatches []PathMismatch
+	tracker := t.NewPathTracker(nil)
+	for i, id := range tokens {
+		actual := paths[i]
+		for len(actual) > 0 && actual[len(actual)-1] == -1 {
+			actual = actual[:len(actual)-1]
+		}
+
+		expected, err := tracker.Push(id)
+		if err != nil || !slices.Equal(expected, actual) {
+			mismatches = append(mismatches, PathMismatch{
+				Index:    i,
+				Token:    id,
+				Expected: expected,
+				Actual:   actual,
+			})
+		}
+	}
+
+	return root, mismatches, nil
+}
@@ -346,3 +346,101 @@ func TestDecoder_Coverage_EdgeCases(t *testing.T) {
 		assert.Nil(t, el)
 	})
 }
+
+func TestDecoder_OrderingMarkers_FixedPoint(t *testing.T) {
+	base := 200000
+	vocab := map[string]int{
+		"<Root>":       base + 1,
+		"</Root>":      base + 2,
+		"<List>":       base + 3,
+		"</List>":      base + 4,
+		"<Item>":       base + 5,
+		"</Item>":      base + 6,
+		"##id":         base + 100,
+		"</__Value>":   base + 200,
+		TokenOrdered:   base + 201,
+		TokenUnordered: base + 202,
+	}
+	vocabPath := createTempVocab(t, vocab)
+	defer os.Remove(vocabPath)
+	tokenizer, err := NewTokenizer(vocabPath)
+	require.NoError(t, err)
+
+	input := `<Root arbor-ordered="true"><List arbor-ordered="false" id="a"><Item>A</Item><Item>B</Item></List><List><Item>C</Item></List></Root>`
+
+	first, err := tokenizer.Tokenize(strings.NewReader(input))
+	require.NoError(t, err)
+	assert.Equal(t, []int{base + 1, base + 201, base + 3, base + 202, base + 100}, first.Tokens[:5])
+	assert.Equal(t, []int{0, 0}, trimPadding(first.PaddedPaths[1]), "marker sits in the attribute bucket")
+
+	decoded, err := tokenizer.DecodeXML(first.Tokens)
+	require.NoError(t, err)
+	assert.Equal(t, []xml.Attr{{Name: xml.Name{Local: ArborOrderedAttribute}, Value: "true"}}, decoded.Attributes)
+
+	list := decoded.Children[0].(*Element)
+	assert.Equal(t, []xml.Attr{
+		{Name: xml.Name{Local: ArborOrderedAttribute}, Value: "false"},
+		{Name: xml.Name{Local: "id"}, Value: "a"},
+	}, list.Attributes)
+	assert.Empty(t, decoded.Children[1].(*Element).Attributes, "no marker without an explicit attribute")
+
+	second, err := tokenizer.Tokenize(strings.NewReader(decoded.String()))
+	require.NoError(t, err)
+	assert.Equal(t, first.Tokens, second.Tokens)
+	assert.Equal(t, first.PaddedPaths, second.PaddedPaths)
+}
+
+func TestDecoder_DecodeXMLWithPaths(t *testing.T) {
+	vocabPath := createComprehensiveVocab(t)
+	defer os.Remove(vocabPath)
+	tokenizer, err := NewTokenizer(vocabPath)
+	require.NoError(t, err)
+
+	input := `<Root arbor-ordered="true" unknown="val"><Child>A</Child><Child><Leaf>B</Leaf></Child></Root>`
+	res, err := tokenizer.Tokenize(strings.NewReader(input))
+	require.NoError(t, err)
+
+	t.Run("Consistent", func(t *testing.T) {
+		// Without ordering markers the decoded tree is unordered, so encode an
+		// input that already is.
+		unordered, err := tokenizer.Tokenize(strings.NewReader(`<Root unknown="val"><Child>A</Child><Child><Leaf>B</Leaf></Child></Root>`))
+		require.NoError(t, err)
+
+		el, mismatches, err := tokenizer.DecodeXMLWithPaths(unordered.Tokens, unordered.PaddedPaths)
+		require.NoError(t, err)
+		require.NotNil(t, el)
+		assert.Equal(t, "Root", el.Name)
+		assert.Empty(t, mismatches)
+	})
+
+	t.Run("Ordering_Lost", func(t *testing.T) {
+		// The second <Child> was encoded at index 2 under an ordered parent,
+		// the reconstructed tree puts it at index 1.
+		_, mismatches, err := tokenizer.DecodeXMLWithPaths(res.Tokens, res.PaddedPaths)
+		require.NoError(t, err)
+		require.NotEmpty(t, mismatches)
+		assert.Equal(t, []int{0, 1}, mismatches[0].Expected)
+		assert.Equal(t, []int{0, 2}, mismatches[0].Actual)
+	})
+
+	t.Run("Corrupted_Path", func(t *testing.T) {
+		unordered, err := tokenizer.Tokenize(strings.NewReader(`<Root><Child>A</Child></Root>`))
+		require.NoError(t, err)
+
+		paths := make([][]int, len(unordered.PaddedPaths))
+		for i, p := range unordered.PaddedPaths {
+			paths[i] = append([]int(nil), p...)
+		}
+		paths[1][1] = 7
+
+		_, mismatches, err := tokenizer.DecodeXMLWithPaths(unordered.Tokens, paths)
+		require.NoError(t, err)
+		require.Len(t, mismatches, 1)
+		assert.Equal(t, PathMismatch{Index: 1, Token: unordered.Tokens[1], Expected: []int{0, 1}, Actual: []int{0, 7}}, mismatches[0])
+	})
+
+	t.Run("Length_Mismatch", func(t *testing.T) {
+		_, _, err := tokenizer.DecodeXMLWithPaths(res.Tokens, res.PaddedPaths[1:])
+		assert.Error(t, err)
+	})
+}
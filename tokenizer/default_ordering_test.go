@@ -0,0 +1,102 @@
+package tokenizer
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDefaultOrdering(t *testing.T) {
+	vocab := map[string]int{
+		"<Doc>": 200001, "</Doc>": 200002,
+		"<List>": 200003, "</List>": 200004,
+		"<Item>": 200005, "</Item>": 200006,
+		TokenOrdered: 200010, TokenUnordered: 200011,
+	}
+
+	tests := []struct {
+		name     string
+		opts     []Option
+		input    string
+		expected [][]int // paths of the <Item> start tags
+	}{
+		{
+			name:     "UnorderedByDefault",
+			input:    `<Doc><List><Item/><Item/></List></Doc>`,
+			expected: [][]int{{0, 1, 1}, {0, 1, 1}},
+		},
+		{
+			name:     "Ordered",
+			opts:     []Option{WithDefaultOrdering(ChildrenOrdered)},
+			input:    `<Doc><List><Item/><Item/></List></Doc>`,
+			expected: [][]int{{0, 1, 1}, {0, 1, 2}},
+		},
+		{
+			name:     "ExplicitFalseWinsOverDefault",
+			opts:     []Option{WithDefaultOrdering(ChildrenOrdered)},
+			input:    `<Doc><List arbor-ordered="false"><Item/><Item/></List></Doc>`,
+			expected: [][]int{{0, 1, 1}, {0, 1, 1}},
+		},
+		{
+			name:     "ExplicitTrueWinsOverDefault",
+			input:    `<Doc><List arbor-ordered="true"><Item/><Item/></List></Doc>`,
+			expected: [][]int{{0, 1, 1}, {0, 1, 2}},
+		},
+		{
+			name:     "InvalidValueKeepsDefault",
+			opts:     []Option{WithDefaultOrdering(ChildrenOrdered)},
+			input:    `<Doc><List arbor-ordered="yes"><Item/><Item/></List></Doc>`,
+			expected: [][]int{{0, 1, 1}, {0, 1, 2}},
+		},
+		{
+			name:     "NotInherited",
+			input:    `<Doc arbor-ordered="true"><List><Item/><Item/></List><List><Item/></List></Doc>`,
+			expected: [][]int{{0, 1, 1}, {0, 1, 1}, {0, 2, 1}},
+		},
+		{
+			name:     "Inherited",
+			opts:     []Option{WithInheritedOrdering()},
+			input:    `<Doc arbor-ordered="true"><List><Item/><Item/></List><List arbor-ordered="false"><Item><Item/><Item/></Item></List></Doc>`,
+			expected: [][]int{{0, 1, 1}, {0, 1, 2}, {0, 2, 1}, {0, 2, 1, 1}, {0, 2, 1, 1}},
+		},
+		{
+			name:     "InheritedDefault",
+			opts:     []Option{WithDefaultOrdering(ChildrenOrdered), WithInheritedOrdering()},
+			input:    `<Doc><List arbor-ordered="false"><Item><Item/><Item/></Item></List><List><Item/><Item/></List></Doc>`,
+			expected: [][]int{{0, 1, 1}, {0, 1, 1, 1}, {0, 1, 1, 1}, {0, 2, 1}, {0, 2, 2}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tokenizer := newTypedTokenizer(t, vocab, tt.opts...)
+			res, err := tokenizer.Tokenize(strings.NewReader(tt.input))
+			require.NoError(t, err)
+
+			var items [][]int
+			for i, id := range res.Tokens {
+				if id == vocab["<Item>"] {
+					items = append(items, trimPadding(res.PaddedPaths[i]))
+				}
+			}
+			assert.Equal(t, tt.expected, items)
+
+			// The tracker follows the same defaults, explicit values reach it
+			// through the markers.
+			tracker := tokenizer.NewPathTracker(nil)
+			for i, id := range res.Tokens {
+				path, err := tracker.Push(id)
+				require.NoError(t, err)
+				assert.Equal(t, trimPadding(res.PaddedPaths[i]), path, "token %d", i)
+			}
+
+			decoded, err := tokenizer.DecodeXML(res.Tokens)
+			require.NoError(t, err)
+			again, err := tokenizer.TokenizeElement(decoded)
+			require.NoError(t, err)
+			assert.Equal(t, res.PaddedPaths, again.PaddedPaths)
+		})
+	}
+}
@@ -0,0 +1,132 @@
+package tokenizer
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// isDirective reports whether an attribute is an arbor-* directive rather
+// than data.
+func isDirective(name string) bool {
+	switch name {
+	case ArborOrderedAttribute, ArborSkipAttribute, ArborOpaqueAttribute, ArborMaxTokensAttribute, ArborLabelAttribute:
+		return true
+	}
+	return false
+}
+
+// directiveValue returns the value of the directive name, if set.
+func directiveValue(attrs []xml.Attr, name string) (string, bool) {
+	for _, attr := range attrs {
+		if attr.Name.Local == name {
+			return attr.Value, true
+		}
+	}
+	return "", false
+}
+
+// hasDirective reports whether the directive name is set to "true".
+func hasDirective(attrs []xml.Attr, name string) bool {
+	v, ok := directiveValue(attrs, name)
+	return ok && v == "true"
+}
+
+// skipElement consumes the tokens of the current element up to and including
+// its end tag.
+func skipElement(src tokenSource) error {
+	for depth := 1; depth > 0; {
+		tok, err := src.Token()
+		if err == io.EOF {
+			return fmt.Errorf("unexpected end of input in %s element", ArborSkipAttribute)
+		}
+		if err != nil {
+			return err
+		}
+		switch tok.(type) {
+		case xml.StartElement:
+			depth++
+		case xml.EndElement:
+			depth--
+		}
+	}
+	return nil
+}
+
+// innerXML consumes the tokens of the current element up to and including its
+// end tag, and returns its content serialized as XML.
+func innerXML(src tokenSource) (string, error) {
+	var buf bytes.Buffer
+	enc := xml.NewEncoder(&buf)
+	for depth := 1; ; {
+		tok, err := src.Token()
+		if err == io.EOF {
+			return "", fmt.Errorf("unexpected end of input in %s element", ArborOpaqueAttribute)
+		}
+		if err != nil {
+			return "", err
+		}
+		switch se := tok.(type) {
+		case xml.StartElement:
+			depth++
+			start := xml.StartElement{Name: xml.Name{Local: se.Name.Local}}
+			for _, a := range se.Attr {
+				start.Attr = append(start.Attr, xml.Attr{Name: xml.Name{Local: a.Name.Local}, Value: a.Value})
+			}
+			tok = start
+		case xml.EndElement:
+			depth--
+			if depth == 0 {
+				if err := enc.Flush(); err != nil {
+					return "", err
+				}
+				return strings.TrimSpace(buf.String()), nil
+			}
+			tok = xml.EndElement{Name: xml.Name{Local: se.Name.Local}}
+		case xml.ProcInst, xml.Directive:
+			continue
+		}
+		if err := enc.EncodeToken(xml.CopyToken(tok)); err != nil {
+			return "", err
+		}
+	}
+}
+
+// rawText returns the child holding opaque content: a <__RawText> element when
+// the vocab has its tokens, the text itself otherwise.
+func (t *Transformer) rawText(s string) interface{} {
+	if !t.hasTokens(TokenRawText, TokenRawTextEnd) {
+		return s
+	}
+	return &Element{Name: strings.Trim(TokenRawText, "<>"), Children: []interface{}{s}}
+}
+
+// maxTokens parses the arbor-max-tokens directive, if any.
+func maxTokens(attrs []xml.Attr) (int, bool, error) {
+	v, ok := directiveValue(attrs, ArborMaxTokensAttribute)
+	if !ok {
+		return 0, false, nil
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(v))
+	if err != nil || n < 0 {
+		return 0, false, fmt.Errorf("invalid %s value %q", ArborMaxTokensAttribute, v)
+	}
+	return n, true, nil
+}
+
+// takeBudget reports whether one more content token fits in all budgets, and
+// if so consumes it.
+func takeBudget(budgets []*int) bool {
+	for _, b := range budgets {
+		if *b <= 0 {
+			return false
+		}
+	}
+	for _, b := range budgets {
+		*b--
+	}
+	return true
+}
@@ -0,0 +1,141 @@
+package tokenizer
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func createDirectiveVocab() map[string]int {
+	base := 200000
+	return map[string]int{
+		"<Doc>":         base + 1,
+		"</Doc>":        base + 2,
+		"<Section>":     base + 3,
+		"</Section>":    base + 4,
+		"<Note>":        base + 5,
+		"</Note>":       base + 6,
+		"##id":          base + 100,
+		TokenValueEnd:   base + 200,
+		TokenRawText:    base + 300,
+		TokenRawTextEnd: base + 301,
+	}
+}
+
+func TestDirectives_Transform(t *testing.T) {
+	vocab := createDirectiveVocab()
+
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "Skip",
+			input:    `<Doc><Section>kept</Section><Unknown arbor-skip="true"><Deep>dropped</Deep></Unknown><Note arbor-skip="false">also kept</Note></Doc>`,
+			expected: `<Doc><Section>kept</Section><Note>also kept</Note></Doc>`,
+		},
+		{
+			name:     "Opaque",
+			input:    `<Doc><Section id="s1" arbor-opaque="true"><table><tr><td class="x">1 &amp; 2</td></tr></table></Section></Doc>`,
+			expected: `<Doc><Section><__RegisteredAttr><__Key>id</__Key><__Value>s1</__Value></__RegisteredAttr><__RawText>&lt;table&gt;&lt;tr&gt;&lt;td class=&#34;x&#34;&gt;1 &amp;amp; 2&lt;/td&gt;&lt;/tr&gt;&lt;/table&gt;</__RawText></Section></Doc>`,
+		},
+		{
+			name:     "PassThrough",
+			input:    `<Doc arbor-ordered="true"><Section arbor-label="intro" arbor-max-tokens="3">text</Section></Doc>`,
+			expected: `<Doc arbor-ordered="true"><Section arbor-label="intro" arbor-max-tokens="3">text</Section></Doc>`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			el, err := NewTransformer(vocab).Transform(strings.NewReader(tt.input))
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, el.String())
+		})
+	}
+
+	_, err := NewTransformer(vocab).Transform(strings.NewReader(`<Doc arbor-skip="true"><Section/></Doc>`))
+	assert.Error(t, err, "skipping the document element leaves nothing to encode")
+}
+
+func TestDirectives_Opaque(t *testing.T) {
+	vocab := createDirectiveVocab()
+	delete(vocab, TokenRawText)
+	tokenizer := newTypedTokenizer(t, vocab)
+
+	res, err := tokenizer.Tokenize(strings.NewReader(`<Doc><Note arbor-opaque="true">a <b>bold</b> move</Note></Doc>`))
+	require.NoError(t, err)
+	decoded, err := tokenizer.DecodeXML(res.Tokens)
+	require.NoError(t, err)
+	assert.Equal(t, `<Doc><Note>a &lt;b&gt;bold&lt;/b&gt; move</Note></Doc>`, decoded.String())
+}
+
+func TestDirectives_MaxTokens(t *testing.T) {
+	tokenizer := newTypedTokenizer(t, createDirectiveVocab())
+	vocab := tokenizer.vocab
+
+	countContent := func(tokens []int) int {
+		n := 0
+		for _, id := range tokens {
+			if tokenizer.TokenKind(id) == KindContent {
+				n++
+			}
+		}
+		return n
+	}
+
+	long := strings.Repeat("lorem ipsum dolor sit amet ", 20)
+	res, err := tokenizer.Tokenize(strings.NewReader(`<Doc><Section id="a long identifier" arbor-max-tokens="5">` + long + `<Note>` + long + `</Note></Section></Doc>`))
+	require.NoError(t, err)
+
+	// The attribute value is not truncated, the text shares the budget.
+	idValue := len(tokenizer.contentTokenizer.Encode("a long identifier", nil, nil))
+	assert.Equal(t, idValue+5, countContent(res.Tokens))
+	assert.Contains(t, res.Tokens, vocab["<Note>"])
+	assert.Contains(t, res.Tokens, vocab["</Section>"])
+
+	// The smallest enclosing budget wins.
+	res, err = tokenizer.Tokenize(strings.NewReader(`<Doc arbor-max-tokens="10"><Section arbor-max-tokens="2">` + long + `</Section><Note>` + long + `</Note></Doc>`))
+	require.NoError(t, err)
+	assert.Equal(t, 10, countContent(res.Tokens))
+
+	tracker := tokenizer.NewPathTracker(nil)
+	for i, id := range res.Tokens {
+		path, err := tracker.Push(id)
+		require.NoError(t, err)
+		assert.Equal(t, trimPadding(res.PaddedPaths[i]), path, "token %d", i)
+	}
+
+	_, err = tokenizer.Tokenize(strings.NewReader(`<Doc arbor-max-tokens="many">text</Doc>`))
+	assert.Error(t, err)
+}
+
+func TestDirectives_Label(t *testing.T) {
+	tokenizer := newTypedTokenizer(t, createDirectiveVocab())
+	vocab := tokenizer.vocab
+
+	res, err := tokenizer.Tokenize(strings.NewReader(`<Doc arbor-ordered="true"><Section arbor-label="intro">Hello</Section><Section><Note arbor-label="spam">Buy now</Note></Section></Doc>`))
+	require.NoError(t, err)
+
+	require.Len(t, res.Labels, 2)
+	for _, l := range res.Labels {
+		assert.Equal(t, trimPadding(res.PaddedPaths[l.Start]), l.Path)
+		assert.Equal(t, l.Path, trimPadding(res.PaddedPaths[l.End]))
+	}
+	assert.Equal(t, "intro", res.Labels[0].Label)
+	assert.Equal(t, vocab["<Section>"], res.Tokens[res.Labels[0].Start])
+	assert.Equal(t, vocab["</Section>"], res.Tokens[res.Labels[0].End])
+	assert.Equal(t, []int{0, 1}, res.Labels[0].Path)
+
+	assert.Equal(t, "spam", res.Labels[1].Label)
+	assert.Equal(t, vocab["<Note>"], res.Tokens[res.Labels[1].Start])
+	assert.Equal(t, vocab["</Note>"], res.Tokens[res.Labels[1].End])
+	assert.Equal(t, []int{0, 2, 1}, res.Labels[1].Path)
+
+	decoded, err := tokenizer.DecodeXML(res.Tokens)
+	require.NoError(t, err)
+	assert.NotContains(t, decoded.String(), ArborLabelAttribute)
+}
@@ -4,6 +4,8 @@ import (
 	"encoding/xml"
 	"io"
 	"strings"
+	"unicode"
+	"unicode/utf8"
 )
 
 // Element represents an XML node structure
@@ -93,3 +95,60 @@ func (e *Element) PrettyPrint(w io.Writer, depth int) {
 
 	io.WriteString(w, "</"+e.Name+">\n")
 }
+
+// elementTokenReader yields the XML tokens of an Element tree, so it can be
+// read like an xml.Decoder.
+type elementTokenReader struct {
+	tokens []xml.Token
+}
+
+func newElementTokenReader(el *Element) *elementTokenReader {
+	r := &elementTokenReader{}
+	r.walk(el)
+	return r
+}
+
+func (r *elementTokenReader) walk(el *Element) {
+	name := xml.Name{Local: el.Name}
+	r.tokens = append(r.tokens, xml.StartElement{Name: name, Attr: append([]xml.Attr(nil), el.Attributes...)})
+	for _, child := range el.Children {
+		switch c := child.(type) {
+		case *Element:
+			r.walk(c)
+		case string:
+			r.tokens = append(r.tokens, xml.CharData(c))
+		}
+	}
+	r.tokens = append(r.tokens, xml.EndElement{Name: name})
+}
+
+func (r *elementTokenReader) Token() (xml.Token, error) {
+	if len(r.tokens) == 0 {
+		return nil, io.EOF
+	}
+	tok := r.tokens[0]
+	r.tokens = r.tokens[1:]
+	return tok, nil
+}
+
+// isValidXMLName reports whether s can be used as an element or attribute
+// name. Colons are rejected since the encoder ignores namespaces.
+func isValidXMLName(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i, r := range s {
+		if r == utf8.RuneError {
+			return false
+		}
+		if unicode.IsLetter(r) || r == '_' {
+			continue
+		}
+		if i > 0 && (unicode.IsDigit(r) || r == '-' || r == '.' ||
+			unicode.In(r, unicode.Mn, unicode.Mc, unicode.Nd)) {
+			continue
+		}
+		return false
+	}
+	return true
+}
@@ -4,6 +4,7 @@ import (
 	"encoding/xml"
 	"fmt"
 	"io"
+	"slices"
 	"strings"
 
 	"github.com/pkoukk/tiktoken-go"
@@ -12,12 +13,14 @@ import (
 type Encoder struct {
 	vocab            map[string]int
 	contentTokenizer *tiktoken.Tiktoken
+	options          Options
 }
 
-func NewEncoder(vocab map[string]int, contentTokenizer *tiktoken.Tiktoken) *Encoder {
+func NewEncoder(vocab map[string]int, contentTokenizer *tiktoken.Tiktoken, opts ...Option) *Encoder {
 	return &Encoder{
 		vocab:            vocab,
 		contentTokenizer: contentTokenizer,
+		options:          newOptions(opts),
 	}
 }
 
@@ -27,10 +30,15 @@ func (e *Encoder) Encode(r io.Reader) (*TokenizationResult, error) {
 
 	type stackItem struct {
 		childrenCounter  int // Counter for assigning indices to children
+		attrCounter      int // Counter for attribute indices in source order mode
 		ordered          bool
 		pathIndex        int // The index of this node in its parent's scope (or 0 for root)
 		isRegisteredAttr bool
+		digits           bool   // content is tokenized one character at a time
+		budgets          []*int // remaining content tokens of arbor-max-tokens elements
+		label            int    // index in labels of the arbor-label of this node, or -1
 	}
+	var labels []NodeLabel
 
 	// We assume a virtual root if we really wanted, but here we just start processing.
 	// But Wait! The `paths` usually start at depth 0 or 1?
@@ -49,53 +57,54 @@ func (e *Encoder) Encode(r io.Reader) (*TokenizationResult, error) {
 	}
 
 	// extractRegisteredAttrName reads the <__Key>...</__Key><__Value> sequence
-	// and returns the attribute name. It consumes the open tag of <__Value>.
-	extractRegisteredAttrName := func(dec *xml.Decoder) (string, error) {
+	// and returns the attribute name along with the <__Value> start element.
+	// It consumes the open tag of <__Value>.
+	extractRegisteredAttrName := func(dec *xml.Decoder) (string, xml.StartElement, error) {
 		// 1. Expect <__Key>
 		tok, err := dec.Token()
 		if err != nil {
-			return "", err
+			return "", xml.StartElement{}, err
 		}
 		se, ok := tok.(xml.StartElement)
 		if !ok || se.Name.Local != strings.Trim(TokenKey, "<>") {
-			return "", fmt.Errorf("expected %s after %s, got %v", TokenKey, VirtualAttrTag, tok)
+			return "", xml.StartElement{}, fmt.Errorf("expected %s after %s, got %v", TokenKey, VirtualAttrTag, tok)
 		}
 
 		// 2. Expect Name (CharData)
 		tok, err = dec.Token()
 		if err != nil {
-			return "", err
+			return "", xml.StartElement{}, err
 		}
 		cd, ok := tok.(xml.CharData)
 		if !ok {
-			return "", fmt.Errorf("expected CharData in %s", TokenKey)
+			return "", xml.StartElement{}, fmt.Errorf("expected CharData in %s", TokenKey)
 		}
 		name := string(cd)
 
 		// 3. Expect </__Key>
 		tok, err = dec.Token()
 		if err != nil {
-			return "", err
+			return "", xml.StartElement{}, err
 		}
 		ee, ok := tok.(xml.EndElement)
 		if !ok || ee.Name.Local != strings.Trim(TokenKey, "<>") {
 			// Handle case where CharData might be followed by EndElement directly
 			// But check if we missed EndElement above?
 			// The loop above consumed CharData. Next must be EndElement.
-			return "", fmt.Errorf("expected %s, got %v", TokenKeyEnd, tok)
+			return "", xml.StartElement{}, fmt.Errorf("expected %s, got %v", TokenKeyEnd, tok)
 		}
 
 		// 4. Expect <__Value>
 		tok, err = dec.Token()
 		if err != nil {
-			return "", err
+			return "", xml.StartElement{}, err
 		}
 		seVal, ok := tok.(xml.StartElement)
 		if !ok || seVal.Name.Local != strings.Trim(TokenValue, "<>") {
-			return "", fmt.Errorf("expected %s start, got %v", TokenValue, tok)
+			return "", xml.StartElement{}, fmt.Errorf("expected %s start, got %v", TokenValue, tok)
 		}
 
-		return name, nil
+		return name, seVal, nil
 	}
 
 	decoder := xml.NewDecoder(r)
@@ -119,14 +128,15 @@ func (e *Encoder) Encode(r io.Reader) (*TokenizationResult, error) {
 			if se.Name.Local == VirtualAttrTag {
 				// Registered Attribute wrapper: <__Attr>...
 				// Expect <__Key>name</__Key><__Value>
-				name, err := extractRegisteredAttrName(decoder)
+				name, value, err := extractRegisteredAttrName(decoder)
 				if err != nil {
 					return nil, err
 				}
 
 				tagName = "##" + name
 				isAttr = true
-				isOrdered = true // Attributes content is ordered
+				// Attributes content is ordered, unless the value is a set
+				isOrdered = !isUnorderedValue(value.Attr)
 			} else {
 				// Standard tag or Special Tag (Unregistered group)
 				tagName = "<" + se.Name.Local + ">"
@@ -141,9 +151,13 @@ func (e *Encoder) Encode(r io.Reader) (*TokenizationResult, error) {
 				// Note: <__Key> and <__Value> are children of <__AttrPair>.
 				// They should follow standard indexing (0 then 1) if AttrPair is ordered.
 
-				// Handle <__Empty> which maps to <__Empty/> in vocab
-				if tagName == "<__Empty>" {
-					tagName = TokenEmpty
+				// Handle special tags such as <__Empty> which map to
+				// self-closing tokens like <__Empty/> in vocab
+				if _, ok := e.vocab[tagName]; !ok && strings.HasPrefix(tagName, "<__") {
+					selfClosing := strings.TrimSuffix(tagName, ">") + "/>"
+					if _, ok := e.vocab[selfClosing]; ok {
+						tagName = selfClosing
+					}
 				}
 
 				// Note: <__Value> is not IsAttr (index 1), handled by default count=1 logic unless count was reset
@@ -151,15 +165,17 @@ func (e *Encoder) Encode(r io.Reader) (*TokenizationResult, error) {
 					isOrdered = true
 				}
 
-				// Check arbor-ordered
-				for _, attr := range se.Attr {
-					if attr.Name.Local == ArborOrderedAttribute {
-						if attr.Value == "true" {
-							isOrdered = true
-						}
-						break
+				// Elements without arbor-ordered take the default ordering, or
+				// the ordering of their parent.
+				if !strings.HasPrefix(tagName, "<__") {
+					isOrdered = e.options.DefaultOrdering == ChildrenOrdered
+					if e.options.InheritOrdering && len(stack) > 0 {
+						isOrdered = stack[len(stack)-1].ordered
 					}
 				}
+				if ordered, ok := explicitOrdering(se.Attr); ok {
+					isOrdered = ordered
+				}
 			}
 
 			// Vocab Lookup
@@ -181,9 +197,16 @@ func (e *Encoder) Encode(r io.Reader) (*TokenizationResult, error) {
 				// If we are starting a node that "belongs" to attribute bucket (index 0)
 				if isAttr {
 					myIndex = 0
+					if e.options.AttributeOrder == AttributesSourceOrder {
+						// Each attribute gets its own index, children follow.
+						myIndex = parent.attrCounter
+						parent.attrCounter++
+						parent.childrenCounter = max(parent.childrenCounter, parent.attrCounter)
+					}
 				} else {
 					myIndex = parent.childrenCounter
-					if parent.ordered {
+					// Typed values stand for content, which is always ordered.
+					if parent.ordered || isTypedValueToken(tagName) {
 						parent.childrenCounter++
 					}
 				}
@@ -208,12 +231,41 @@ func (e *Encoder) Encode(r io.Reader) (*TokenizationResult, error) {
 			if isAttr || strings.HasPrefix(tagName, "<__") {
 				childrenStart = 0
 			}
-			stack = append(stack, &stackItem{
+			item := &stackItem{
 				childrenCounter:  childrenStart,
 				ordered:          isOrdered,
 				pathIndex:        myIndex,
 				isRegisteredAttr: se.Name.Local == VirtualAttrTag,
-			})
+				digits:           tagName == TokenNumber || tagName == TokenDate,
+				label:            -1,
+			}
+			// Content budgets apply to text, not to attribute values.
+			if len(stack) > 0 && !isAttr {
+				item.budgets = stack[len(stack)-1].budgets
+			}
+			if !isAttr && !strings.HasPrefix(tagName, "<__") {
+				n, ok, err := maxTokens(se.Attr)
+				if err != nil {
+					return nil, err
+				}
+				if ok {
+					item.budgets = append(slices.Clip(item.budgets), &n)
+				}
+				if label, ok := directiveValue(se.Attr, ArborLabelAttribute); ok {
+					item.label = len(labels)
+					labels = append(labels, NodeLabel{Label: label, Start: len(tokens) - 1, End: -1, Path: nodePath})
+				}
+			}
+			stack = append(stack, item)
+
+			// Emit the ordering marker, if any, in the attribute bucket so that
+			// DecodeXML can restore arbor-ordered.
+			if !isAttr && !strings.HasPrefix(tagName, "<__") {
+				if markerID, ok := e.orderingMarker(se.Attr); ok {
+					tokens = append(tokens, markerID)
+					paths = append(paths, append(getCurrentPath(), 0))
+				}
+			}
 
 		case xml.EndElement:
 			if len(stack) == 0 {
@@ -247,6 +299,9 @@ func (e *Encoder) Encode(r io.Reader) (*TokenizationResult, error) {
 				tokens = append(tokens, id)
 				paths = append(paths, nodePath)
 			}
+			if popped.label >= 0 {
+				labels[popped.label].End = len(tokens) - 1
+			}
 			// If not in vocab (phantom), ignore. <__Empty/> handling often means no End token.
 
 		case xml.CharData:
@@ -264,8 +319,20 @@ func (e *Encoder) Encode(r io.Reader) (*TokenizationResult, error) {
 			}
 			parent := stack[len(stack)-1]
 
-			contentTokens := e.contentTokenizer.Encode(content, nil, nil)
+			var contentTokens []int
+			if parent.digits {
+				// Typed values are split into single characters so the same
+				// digit always maps to the same token.
+				for _, r := range content {
+					contentTokens = append(contentTokens, e.contentTokenizer.Encode(string(r), nil, nil)...)
+				}
+			} else {
+				contentTokens = e.contentTokenizer.Encode(content, nil, nil)
+			}
 			for _, t := range contentTokens {
+				if !takeBudget(parent.budgets) {
+					break
+				}
 				tokens = append(tokens, t)
 
 				// Path logic for content
@@ -285,5 +352,44 @@ func (e *Encoder) Encode(r io.Reader) (*TokenizationResult, error) {
 	return &TokenizationResult{
 		Tokens:      tokens,
 		PaddedPaths: paddedPaths,
+		Labels:      labels,
 	}, nil
 }
+
+// explicitOrdering returns the ordering set by arbor-ordered="true" or
+// "false". ok is false without the attribute; other values are ignored.
+func explicitOrdering(attrs []xml.Attr) (ordered bool, ok bool) {
+	switch value, _ := directiveValue(attrs, ArborOrderedAttribute); value {
+	case "true":
+		return true, true
+	case "false":
+		return false, true
+	}
+	return false, false
+}
+
+// isUnorderedValue reports whether a <__Value> element holds an unordered
+// set of items.
+func isUnorderedValue(attrs []xml.Attr) bool {
+	for _, attr := range attrs {
+		if attr.Name.Local == ArborOrderedAttribute {
+			return attr.Value == "false"
+		}
+	}
+	return false
+}
+
+// orderingMarker returns the marker token matching an explicit arbor-ordered
+// attribute, if the vocab has one.
+func (e *Encoder) orderingMarker(attrs []xml.Attr) (int, bool) {
+	ordered, ok := explicitOrdering(attrs)
+	if !ok {
+		return 0, false
+	}
+	marker := TokenUnordered
+	if ordered {
+		marker = TokenOrdered
+	}
+	id, ok := e.vocab[marker]
+	return id, ok
+}
@@ -0,0 +1,236 @@
+package tokenizer
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+)
+
+// hfTokenizer is the subset of the Hugging Face tokenizer.json format written
+// by WriteHFTokenizer.
+type hfTokenizer struct {
+	Version       string         `json:"version"`
+	Truncation    interface{}    `json:"truncation"`
+	Padding       interface{}    `json:"padding"`
+	AddedTokens   []hfAddedToken `json:"added_tokens"`
+	Normalizer    interface{}    `json:"normalizer"`
+	PreTokenizer  interface{}    `json:"pre_tokenizer"`
+	PostProcessor interface{}    `json:"post_processor"`
+	Decoder       interface{}    `json:"decoder"`
+	Model         hfBPEModel     `json:"model"`
+}
+
+type hfAddedToken struct {
+	ID         int    `json:"id"`
+	Content    string `json:"content"`
+	SingleWord bool   `json:"single_word"`
+	LStrip     bool   `json:"lstrip"`
+	RStrip     bool   `json:"rstrip"`
+	Normalized bool   `json:"normalized"`
+	Special    bool   `json:"special"`
+}
+
+type hfBPEModel struct {
+	Type                    string      `json:"type"`
+	Dropout                 interface{} `json:"dropout"`
+	UnkToken                interface{} `json:"unk_token"`
+	ContinuingSubwordPrefix string      `json:"continuing_subword_prefix"`
+	EndOfWordSuffix         string      `json:"end_of_word_suffix"`
+	FuseUnk                 bool        `json:"fuse_unk"`
+	ByteFallback            bool        `json:"byte_fallback"`
+	IgnoreMerges            bool        `json:"ignore_merges"`
+	Vocab                   hfVocab     `json:"vocab"`
+	Merges                  []string    `json:"merges"`
+}
+
+// hfVocab is a vocab written as a JSON object ordered by ID, as Hugging Face
+// tools write it.
+type hfVocab []hfVocabEntry
+
+type hfVocabEntry struct {
+	Token string
+	ID    int
+}
+
+func (v hfVocab) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, e := range v {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, err := json.Marshal(e.Token)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.WriteString(strconv.Itoa(e.ID))
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// WriteHFTokenizer writes the combined vocabulary of t as a Hugging Face
+// tokenizer.json: the cl100k_base BPE with its byte-level pre-tokenizer and
+// decoder, plus the cl100k_base special tokens and the vocab tokens as special
+// added tokens keeping their IDs. PreTrainedTokenizerFast loaded from it
+// decodes each ID to the same string as Decode, for content tokens that are
+// valid UTF-8 on their own.
+//
+// A Hugging Face vocab maps each string to a single ID, so a content token
+// spelled like a vocab token, such as "<p>", is left out in favor of the
+// vocab token; the IDs of these content tokens are returned. The byte-level
+// decoder only passes printable ASCII through unchanged, so vocab tokens with
+// other characters are rejected.
+func (t *Tokenizer) WriteHFTokenizer(w io.Writer) (shadowed []int, err error) {
+	ranks, err := t.contentRanks()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load BPE ranks: %w", err)
+	}
+
+	var added []hfAddedToken
+	for tok, id := range cl100kBaseSpecialTokens {
+		added = append(added, hfAddedToken{ID: id, Content: tok, Special: true})
+	}
+	for tok, id := range t.vocab {
+		for _, r := range tok {
+			if r <= ' ' || r > '~' {
+				return nil, fmt.Errorf("vocab token %q has characters the byte-level decoder cannot restore", tok)
+			}
+		}
+		added = append(added, hfAddedToken{ID: id, Content: tok, Special: true})
+	}
+	sort.Slice(added, func(i, j int) bool { return added[i].ID < added[j].ID })
+
+	content := ranks
+	for tok := range t.vocab {
+		if rank, ok := ranks[tok]; ok {
+			if len(shadowed) == 0 {
+				content = make(map[string]int, len(ranks))
+				for k, v := range ranks {
+					content[k] = v
+				}
+			}
+			delete(content, tok)
+			shadowed = append(shadowed, rank)
+		}
+	}
+	sort.Ints(shadowed)
+
+	// Added tokens only keep their IDs when the model vocab has them, so
+	// they are listed there too.
+	byteEncoder := bytesToUnicode()
+	vocab := make(hfVocab, 0, len(content)+len(added))
+	for tok, rank := range content {
+		vocab = append(vocab, hfVocabEntry{Token: byteLevelString(byteEncoder, tok), ID: rank})
+	}
+	for _, a := range added {
+		vocab = append(vocab, hfVocabEntry{Token: a.Content, ID: a.ID})
+	}
+	sort.Slice(vocab, func(i, j int) bool { return vocab[i].ID < vocab[j].ID })
+
+	var merges []string
+	for _, m := range bpeMerges(content) {
+		merges = append(merges, byteLevelString(byteEncoder, m[0])+" "+byteLevelString(byteEncoder, m[1]))
+	}
+
+	byteLevel := func(addPrefixSpace, trimOffsets, useRegex bool) map[string]interface{} {
+		return map[string]interface{}{"type": "ByteLevel", "add_prefix_space": addPrefixSpace, "trim_offsets": trimOffsets, "use_regex": useRegex}
+	}
+	hf := hfTokenizer{
+		Version:     "1.0",
+		AddedTokens: added,
+		PreTokenizer: map[string]interface{}{
+			"type": "Sequence",
+			"pretokenizers": []interface{}{
+				map[string]interface{}{"type": "Split", "pattern": map[string]string{"Regex": cl100kBasePattern}, "behavior": "Isolated", "invert": false},
+				byteLevel(false, true, false),
+			},
+		},
+		PostProcessor: byteLevel(true, false, true),
+		Decoder:       byteLevel(true, true, true),
+		Model: hfBPEModel{
+			Type: "BPE",
+			// Like tiktoken, pieces found in the vocab are not merged.
+			IgnoreMerges: true,
+			Vocab:        vocab,
+			Merges:       merges,
+		},
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(hf); err != nil {
+		return nil, err
+	}
+	return shadowed, nil
+}
+
+// bpeMerges derives the merges of a BPE from its ranks: every split of a token
+// into two tokens of lower rank, ordered by the rank of the token.
+func bpeMerges(ranks map[string]int) [][2]string {
+	type merge struct {
+		left, right string
+		rank        int
+	}
+	var merges []merge
+	for tok, rank := range ranks {
+		start := len(merges)
+		for i := 1; i < len(tok); i++ {
+			left, right := tok[:i], tok[i:]
+			leftRank, ok := ranks[left]
+			if !ok || leftRank >= rank {
+				continue
+			}
+			rightRank, ok := ranks[right]
+			if !ok || rightRank >= rank {
+				continue
+			}
+			merges = append(merges, merge{left, right, rank})
+		}
+		local := merges[start:]
+		sort.Slice(local, func(i, j int) bool {
+			if ranks[local[i].left] != ranks[local[j].left] {
+				return ranks[local[i].left] < ranks[local[j].left]
+			}
+			return ranks[local[i].right] < ranks[local[j].right]
+		})
+	}
+	sort.SliceStable(merges, func(i, j int) bool { return merges[i].rank < merges[j].rank })
+
+	out := make([][2]string, len(merges))
+	for i, m := range merges {
+		out[i] = [2]string{m.left, m.right}
+	}
+	return out
+}
+
+// bytesToUnicode returns the byte-level alphabet of GPT-2 and Hugging Face
+// tokenizers: printable bytes map to themselves, the others to code points
+// from 256 up.
+func bytesToUnicode() [256]rune {
+	var table [256]rune
+	n := 0
+	for b := 0; b < 256; b++ {
+		if (b >= '!' && b <= '~') || (b >= 0xA1 && b <= 0xAC) || (b >= 0xAE && b <= 0xFF) {
+			table[b] = rune(b)
+		} else {
+			table[b] = rune(256 + n)
+			n++
+		}
+	}
+	return table
+}
+
+func byteLevelString(table [256]rune, s string) string {
+	runes := make([]rune, len(s))
+	for i := 0; i < len(s); i++ {
+		runes[i] = table[s[i]]
+	}
+	return string(runes)
+}
@@ -0,0 +1,180 @@
+package tokenizer
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// readHFTokenizer parses a tokenizer.json written by WriteHFTokenizer.
+func readHFTokenizer(t *testing.T, data []byte) (added []hfAddedToken, vocab map[string]int, merges []string) {
+	var hf struct {
+		AddedTokens []hfAddedToken `json:"added_tokens"`
+		Model       struct {
+			Vocab  map[string]int `json:"vocab"`
+			Merges []string       `json:"merges"`
+		} `json:"model"`
+	}
+	require.NoError(t, json.Unmarshal(data, &hf))
+	return hf.AddedTokens, hf.Model.Vocab, hf.Model.Merges
+}
+
+// hfDecode decodes one ID as the byte-level decoder of Hugging Face
+// tokenizers does.
+func hfDecode(vocab map[string]int, id int) string {
+	byteDecoder := make(map[rune]byte)
+	for b, r := range bytesToUnicode() {
+		byteDecoder[r] = byte(b)
+	}
+	for tok, i := range vocab {
+		if i == id {
+			var out []byte
+			for _, r := range tok {
+				out = append(out, byteDecoder[r])
+			}
+			return string(out)
+		}
+	}
+	return ""
+}
+
+// hfEncodeWord encodes a pre-tokenized piece as the BPE model of Hugging
+// Face tokenizers does with ignore_merges: pieces in the vocab are kept whole,
+// the others start as bytes merged by increasing merge rank.
+func hfEncodeWord(vocab map[string]int, merges []string, piece string) []int {
+	table := bytesToUnicode()
+	word := byteLevelString(table, piece)
+	if id, ok := vocab[word]; ok {
+		return []int{id}
+	}
+	mergeRanks := make(map[string]int, len(merges))
+	for i, m := range merges {
+		mergeRanks[m] = i
+	}
+	var symbols []string
+	for _, r := range word {
+		symbols = append(symbols, string(r))
+	}
+	for {
+		best, bestRank := -1, len(merges)
+		for i := 0; i+1 < len(symbols); i++ {
+			if rank, ok := mergeRanks[symbols[i]+" "+symbols[i+1]]; ok && rank < bestRank {
+				best, bestRank = i, rank
+			}
+		}
+		if best < 0 {
+			break
+		}
+		symbols = append(symbols[:best+1], symbols[best+2:]...)
+		symbols[best] = strings.Join(strings.Fields(merges[bestRank]), "")
+	}
+	ids := make([]int, len(symbols))
+	for i, s := range symbols {
+		ids[i] = vocab[s]
+	}
+	return ids
+}
+
+func TestWriteHFTokenizer(t *testing.T) {
+	ranks := append(byteRanks(), []byte("YWI= 256\nYWJj 257\nPHA+ 258\n")...) // ab, abc, <p>
+	vocab, err := json.Marshal(map[string]int{"<p>": 200001, "</p>": 200002, "##id": 200003})
+	require.NoError(t, err)
+	tok, err := NewTokenizerFromBytes(vocab, ranks)
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	shadowed, err := tok.WriteHFTokenizer(&buf)
+	require.NoError(t, err)
+	assert.Equal(t, []int{258}, shadowed, "the content token <p> gives way to the tag")
+
+	added, hfVocab, merges := readHFTokenizer(t, buf.Bytes())
+	assert.Equal(t, []string{"a b", "ab c"}, merges)
+	require.Len(t, added, 8)
+	assert.Equal(t, hfAddedToken{ID: 100257, Content: "<|endoftext|>", Special: true}, added[0])
+	assert.Equal(t, hfAddedToken{ID: 200001, Content: "<p>", Special: true}, added[5])
+	for _, a := range added {
+		assert.Equal(t, a.ID, hfVocab[a.Content], "added tokens are in the model vocab with their ID")
+	}
+	assert.Equal(t, 200001, hfVocab["<p>"])
+	assert.Equal(t, 256, hfVocab["ab"])
+	assert.Equal(t, 32, hfVocab["Ġ"], "bytes use the byte-level alphabet")
+
+	for id := 0; id < 258; id++ {
+		assert.Equal(t, tok.Decode([]int{id}), hfDecode(hfVocab, id), "ID %d", id)
+	}
+	for _, id := range []int{200001, 200002, 200003} {
+		assert.Equal(t, tok.Decode([]int{id}), hfDecode(hfVocab, id))
+	}
+	for _, piece := range []string{"abcab", "cabab", "aabbcc", "ab", " abc"} {
+		assert.Equal(t, tok.contentTokenizer.Encode(piece, nil, nil), hfEncodeWord(hfVocab, merges, piece), piece)
+	}
+
+	// The order of the vocab object follows the IDs.
+	assert.Less(t, bytes.Index(buf.Bytes(), []byte(`"ab": 256`)), bytes.Index(buf.Bytes(), []byte(`"abc": 257`)))
+}
+
+func TestWriteHFTokenizer_Cl100kBase(t *testing.T) {
+	vocab, err := json.Marshal(map[string]int{"<Doc>": 200001, "</Doc>": 200002})
+	require.NoError(t, err)
+	tok, err := NewTokenizerFromBytes(vocab, cachedRanks(t))
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	_, err = tok.WriteHFTokenizer(&buf)
+	require.NoError(t, err)
+	_, hfVocab, merges := readHFTokenizer(t, buf.Bytes())
+
+	byteDecoder := make(map[rune]byte)
+	for b, r := range bytesToUnicode() {
+		byteDecoder[r] = byte(b)
+	}
+	for s, id := range hfVocab {
+		if id >= Cl100kBaseMaxID {
+			continue
+		}
+		var decoded []byte
+		for _, r := range s {
+			decoded = append(decoded, byteDecoder[r])
+		}
+		if _, ok := cl100kBaseSpecialTokens[s]; !ok {
+			assert.Equal(t, tok.Decode([]int{id}), string(decoded), "ID %d", id)
+		}
+	}
+	for _, piece := range []string{"Hello", " world", " tokenization", "!!!", " ünïcode", "\n\n", "202"} {
+		assert.Equal(t, tok.contentTokenizer.Encode(piece, nil, nil), hfEncodeWord(hfVocab, merges, piece), piece)
+	}
+}
+
+func TestWriteHFTokenizer_NonASCIITag(t *testing.T) {
+	vocab, err := json.Marshal(map[string]int{"<Café>": 200001})
+	require.NoError(t, err)
+	tok, err := NewTokenizerFromBytes(vocab, byteRanks())
+	require.NoError(t, err)
+	_, err = tok.WriteHFTokenizer(&bytes.Buffer{})
+	assert.ErrorContains(t, err, `vocab token "<Café>"`)
+}
+
+func TestBPEMerges(t *testing.T) {
+	ranks := map[string]int{"a": 0, "b": 1, "c": 2, "ab": 3, "abc": 4, "bc": 5, "abcab": 6}
+	// "abc" is not "a" + "bc" as "bc" comes after it.
+	assert.Equal(t, [][2]string{{"a", "b"}, {"ab", "c"}, {"b", "c"}, {"abc", "ab"}}, bpeMerges(ranks))
+
+	// Merges follow the ranks of the tokens they make, from parts ranked
+	// before them.
+	ranks, err := parseBPERanks(cachedRanks(t))
+	require.NoError(t, err)
+	merges := bpeMerges(ranks)
+	require.NotEmpty(t, merges)
+	prev := -1
+	for _, m := range merges {
+		rank := ranks[m[0]+m[1]]
+		assert.Less(t, ranks[m[0]], rank, m)
+		assert.Less(t, ranks[m[1]], rank, m)
+		assert.LessOrEqual(t, prev, rank, m)
+		prev = rank
+	}
+}
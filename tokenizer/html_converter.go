@@ -3,80 +3,288 @@ package tokenizer
 import (
 	"bytes"
 	"encoding/xml"
+	"fmt"
 	"io"
+	"slices"
 	"strings"
 
 	"golang.org/x/net/html"
 )
 
+// RawTextMode selects how ConvertHTMLToXML handles <script> and <style> bodies.
+type RawTextMode int
+
+const (
+	// RawTextKeep passes the body through as text.
+	RawTextKeep RawTextMode = iota
+	// RawTextDrop removes the element altogether.
+	RawTextDrop
+	// RawTextWrap keeps the body inside <__RawText>...</__RawText>, so models
+	// can tell code from prose.
+	RawTextWrap
+)
+
+// HTMLOptions configures ConvertHTMLToXML. The zero value keeps every element.
+// Comments, doctypes and processing instructions are always dropped.
+type HTMLOptions struct {
+	// RawText selects how <script> and <style> bodies are handled.
+	RawText RawTextMode
+	// RawTextLimit truncates kept <script> and <style> bodies to that many
+	// characters. Zero means no limit.
+	RawTextLimit int
+	// RemoveHidden drops elements that are not rendered: those with the
+	// hidden attribute, aria-hidden="true", an inline display:none or
+	// visibility:hidden style, and <input type="hidden">.
+	RemoveHidden bool
+	// AllowTags, when not empty, keeps only the listed elements. Other
+	// elements are unwrapped: their children take their place. The <html>
+	// root is always kept.
+	AllowTags []string
+	// DenyTags drops the listed elements together with their content, e.g.
+	// nav, footer or svg.
+	DenyTags []string
+}
+
+// HTMLOption sets a field of HTMLOptions.
+type HTMLOption func(*HTMLOptions)
+
+// WithRawText sets how <script> and <style> bodies are handled, and truncates
+// kept bodies to limit characters when limit is positive.
+func WithRawText(mode RawTextMode, limit int) HTMLOption {
+	return func(o *HTMLOptions) {
+		o.RawText = mode
+		o.RawTextLimit = limit
+	}
+}
+
+// WithoutHiddenElements drops elements that are not rendered.
+func WithoutHiddenElements() HTMLOption {
+	return func(o *HTMLOptions) {
+		o.RemoveHidden = true
+	}
+}
+
+// WithAllowedTags keeps only the given elements, unwrapping the others.
+func WithAllowedTags(tags ...string) HTMLOption {
+	return func(o *HTMLOptions) {
+		o.AllowTags = append(o.AllowTags, tags...)
+	}
+}
+
+// WithDeniedTags drops the given elements and their content.
+func WithDeniedTags(tags ...string) HTMLOption {
+	return func(o *HTMLOptions) {
+		o.DenyTags = append(o.DenyTags, tags...)
+	}
+}
+
 // ConvertHTMLToXML converts legacy HTML to generic XML structure (XHTML-like)
-// so that strict XML tokenizers/parsers can handle it.
-func ConvertHTMLToXML(r io.Reader) (string, error) {
-	doc, err := html.Parse(r)
+// so that strict XML tokenizers/parsers can handle it. See
+// ConvertHTMLToElement for the conversion rules.
+func ConvertHTMLToXML(r io.Reader, opts ...HTMLOption) (string, error) {
+	root, err := ConvertHTMLToElement(r, opts...)
 	if err != nil {
 		return "", err
 	}
 	var b bytes.Buffer
-	var traverse func(*html.Node, int, bool)
-	traverse = func(n *html.Node, depth int, insideComplex bool) {
+	writeIndentedXML(&b, root, 0)
+	return strings.TrimSpace(b.String()), nil
+}
+
+// ConvertHTMLToElement parses HTML into an Element tree that always tokenizes:
+// names that are not valid XML names (such as @click or :class) are dropped
+// along with xmlns, duplicate attributes keep their first value, and
+// characters XML cannot represent are removed. Elements with invalid names are
+// unwrapped. Names keep the case the HTML parser gives them, lowercase except
+// for SVG and MathML names such as viewBox. Options trim content that wastes
+// tokens on web crawl data, such as scripts, styles, hidden elements and
+// boilerplate sections.
+func ConvertHTMLToElement(r io.Reader, opts ...HTMLOption) (*Element, error) {
+	var o HTMLOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	doc, err := html.Parse(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var root *Element
+	var traverse func(n *html.Node, parent *Element)
+	traverse = func(n *html.Node, parent *Element) {
 		switch n.Type {
 		case html.ElementNode:
-			hasElementChildren := false
-			for c := n.FirstChild; c != nil; c = c.NextSibling {
-				if c.Type == html.ElementNode {
-					hasElementChildren = true
-					break
+			name := n.Data
+			if slices.Contains(o.DenyTags, name) || (o.RemoveHidden && isHiddenHTML(n)) {
+				return
+			}
+			unwrap := !isValidXMLName(name) ||
+				(len(o.AllowTags) > 0 && !slices.Contains(o.AllowTags, name) && name != "html")
+			if unwrap && parent != nil {
+				for c := n.FirstChild; c != nil; c = c.NextSibling {
+					traverse(c, parent)
 				}
+				return
 			}
-
-			indent := ""
-			if depth >= 0 {
-				indent = "\n" + strings.Repeat("  ", depth)
+			isRawText := name == "script" || name == "style"
+			if isRawText && o.RawText == RawTextDrop {
+				return
 			}
 
-			b.WriteString(indent + "<" + n.Data)
-			for _, a := range n.Attr {
-				if a.Key != "xmlns" { // avoid namespace issues if any
-					b.WriteString(" " + a.Key + "=\"")
-					xml.EscapeText(&b, []byte(a.Val))
-					b.WriteString("\"")
-				}
+			el := &Element{Name: name, Attributes: normalizeHTMLAttributes(n.Attr)}
+			if parent == nil {
+				root = el
+			} else {
+				parent.Children = append(parent.Children, el)
 			}
-			b.WriteString(">")
 
-			for c := n.FirstChild; c != nil; c = c.NextSibling {
-				if hasElementChildren {
-					if c.Type == html.TextNode && strings.TrimSpace(c.Data) == "" {
-						continue
-					}
-					traverse(c, depth+1, true)
-				} else {
-					traverse(c, depth, false)
-				}
+			if isRawText && (o.RawText == RawTextWrap || o.RawTextLimit > 0) {
+				appendRawText(el, n, o)
+				return
 			}
-
-			if hasElementChildren {
-				b.WriteString(indent + "</" + n.Data + ">")
-			} else {
-				b.WriteString("</" + n.Data + ">")
+			for c := n.FirstChild; c != nil; c = c.NextSibling {
+				traverse(c, el)
 			}
 			return
 		case html.TextNode:
-			data := strings.TrimSpace(n.Data)
+			if parent == nil {
+				return
+			}
+			data := strings.TrimSpace(sanitizeXMLText(n.Data))
 			if data != "" {
-				if insideComplex {
-					indent := "\n" + strings.Repeat("  ", depth)
-					b.WriteString(indent)
-				}
-				xml.EscapeText(&b, []byte(data))
+				parent.Children = append(parent.Children, data)
 			}
 			return
 		}
 
 		for c := n.FirstChild; c != nil; c = c.NextSibling {
-			traverse(c, depth, insideComplex)
+			traverse(c, parent)
 		}
 	}
-	traverse(doc, 0, true)
-	return strings.TrimSpace(b.String()), nil
+	traverse(doc, nil)
+
+	if root == nil {
+		return nil, fmt.Errorf("no root element in HTML document")
+	}
+	return root, nil
+}
+
+// normalizeHTMLAttributes drops the attributes that would not survive an XML
+// round trip.
+func normalizeHTMLAttributes(attrs []html.Attribute) []xml.Attr {
+	var out []xml.Attr
+	seen := make(map[string]bool, len(attrs))
+	for _, a := range attrs {
+		key := a.Key
+		if a.Namespace != "" || key == "xmlns" || !isValidXMLName(key) || seen[key] {
+			continue
+		}
+		seen[key] = true
+		out = append(out, xml.Attr{Name: xml.Name{Local: key}, Value: sanitizeXMLText(a.Val)})
+	}
+	return out
+}
+
+// sanitizeXMLText removes the characters that are not allowed in XML
+// documents, such as most control characters.
+func sanitizeXMLText(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r == 0x09 || r == 0x0A || r == 0x0D ||
+			(r >= 0x20 && r <= 0xD7FF) || (r >= 0xE000 && r <= 0xFFFD) || (r >= 0x10000 && r <= 0x10FFFF) {
+			return r
+		}
+		return -1
+	}, s)
+}
+
+// writeIndentedXML writes el with one element per line. Elements holding only
+// text are written on a single line.
+func writeIndentedXML(b *bytes.Buffer, el *Element, depth int) {
+	hasElementChildren := false
+	for _, c := range el.Children {
+		if _, ok := c.(*Element); ok {
+			hasElementChildren = true
+			break
+		}
+	}
+
+	indent := "\n" + strings.Repeat("  ", depth)
+	b.WriteString(indent + "<" + el.Name)
+	for _, a := range el.Attributes {
+		b.WriteString(" " + a.Name.Local + "=\"")
+		xml.EscapeText(b, []byte(a.Value))
+		b.WriteString("\"")
+	}
+	b.WriteString(">")
+
+	for _, c := range el.Children {
+		switch child := c.(type) {
+		case *Element:
+			writeIndentedXML(b, child, depth+1)
+		case string:
+			if hasElementChildren {
+				b.WriteString("\n" + strings.Repeat("  ", depth+1))
+			}
+			xml.EscapeText(b, []byte(child))
+		}
+	}
+
+	if hasElementChildren {
+		b.WriteString(indent)
+	}
+	b.WriteString("</" + el.Name + ">")
+}
+
+// appendRawText appends the body of a <script> or <style> element to el
+// according to the raw text options.
+func appendRawText(el *Element, n *html.Node, o HTMLOptions) {
+	var text strings.Builder
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.TextNode {
+			text.WriteString(c.Data)
+		}
+	}
+	body := strings.TrimSpace(sanitizeXMLText(text.String()))
+	if o.RawTextLimit > 0 {
+		if runes := []rune(body); len(runes) > o.RawTextLimit {
+			body = string(runes[:o.RawTextLimit])
+		}
+	}
+	if body == "" {
+		return
+	}
+
+	if o.RawText == RawTextWrap {
+		el.Children = append(el.Children, &Element{
+			Name:     strings.Trim(TokenRawText, "<>"),
+			Children: []interface{}{body},
+		})
+		return
+	}
+	el.Children = append(el.Children, body)
+}
+
+// isHiddenHTML reports whether an element is not rendered by browsers.
+func isHiddenHTML(n *html.Node) bool {
+	for _, a := range n.Attr {
+		switch a.Key {
+		case "hidden":
+			return true
+		case "aria-hidden":
+			if strings.EqualFold(strings.TrimSpace(a.Val), "true") {
+				return true
+			}
+		case "type":
+			if n.Data == "input" && strings.EqualFold(strings.TrimSpace(a.Val), "hidden") {
+				return true
+			}
+		case "style":
+			style := strings.ToLower(strings.ReplaceAll(a.Val, " ", ""))
+			if strings.Contains(style, "display:none") || strings.Contains(style, "visibility:hidden") {
+				return true
+			}
+		}
+	}
+	return false
 }
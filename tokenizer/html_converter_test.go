@@ -3,8 +3,12 @@ package tokenizer
 import (
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestConvertHTMLToXML(t *testing.T) {
@@ -43,3 +47,142 @@ func TestConvertHTMLToXML(t *testing.T) {
 		})
 	}
 }
+
+func TestConvertHTMLToXML_Options(t *testing.T) {
+	input := `<html><head><style>body { color: red; }</style><script>var x = 1;</script></head>` +
+		`<body><nav><a href="/">Home</a></nav><!-- comment --><div hidden>secret</div>` +
+		`<p style="display: none">invisible</p><input type="hidden" value="t"><span aria-hidden="true">*</span>` +
+		`<p>Hello <b>world</b></p></body></html>`
+
+	tests := []struct {
+		name     string
+		opts     []HTMLOption
+		contains []string
+		excludes []string
+	}{
+		{
+			name:     "Default",
+			contains: []string{"<style>body { color: red; }</style>", "<script>var x = 1;</script>", "<nav>", "secret", "invisible"},
+			excludes: []string{"comment"},
+		},
+		{
+			name:     "DropRawText",
+			opts:     []HTMLOption{WithRawText(RawTextDrop, 0)},
+			excludes: []string{"<style>", "<script>", "color"},
+		},
+		{
+			name:     "WrapRawText",
+			opts:     []HTMLOption{WithRawText(RawTextWrap, 0)},
+			contains: []string{"<__RawText>body { color: red; }</__RawText>", "<__RawText>var x = 1;</__RawText>"},
+		},
+		{
+			name:     "TruncateRawText",
+			opts:     []HTMLOption{WithRawText(RawTextKeep, 4)},
+			contains: []string{"<style>body</style>", "<script>var </script>"},
+		},
+		{
+			name:     "RemoveHidden",
+			opts:     []HTMLOption{WithoutHiddenElements()},
+			contains: []string{"<b>world</b>"},
+			excludes: []string{"secret", "invisible", "<input", "aria-hidden"},
+		},
+		{
+			name:     "DenyTags",
+			opts:     []HTMLOption{WithDeniedTags("nav", "head")},
+			contains: []string{"<body>"},
+			excludes: []string{"Home", "<head>", "<style>"},
+		},
+		{
+			name:     "AllowTags",
+			opts:     []HTMLOption{WithAllowedTags("body", "p"), WithRawText(RawTextDrop, 0)},
+			contains: []string{"<html>", "<body>", "Home", "Hello", "world"},
+			excludes: []string{"<nav>", "<a ", "<b>", "<div", "<head>"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actual, err := ConvertHTMLToXML(strings.NewReader(input), tt.opts...)
+			if err != nil {
+				t.Fatalf("ConvertHTMLToXML failed: %v", err)
+			}
+			for _, s := range tt.contains {
+				if !strings.Contains(actual, s) {
+					t.Errorf("expected %q in output:\n%s", s, actual)
+				}
+			}
+			for _, s := range tt.excludes {
+				if strings.Contains(actual, s) {
+					t.Errorf("unexpected %q in output:\n%s", s, actual)
+				}
+			}
+		})
+	}
+}
+
+func TestConvertHTMLToXML_WrapRawTextTokens(t *testing.T) {
+	xmlContent, err := ConvertHTMLToXML(strings.NewReader(`<style>p { margin: 0 }</style><p>Hi</p>`), WithRawText(RawTextWrap, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	vocab := map[string]int{TokenRawText: 200000, TokenRawTextEnd: 200001}
+	id := 200002
+	for _, tag := range []string{"html", "head", "body", "style", "p"} {
+		vocab["<"+tag+">"] = id
+		vocab["</"+tag+">"] = id + 1
+		id += 2
+	}
+	vocabPath := createTempVocab(t, vocab)
+	defer os.Remove(vocabPath)
+	tokenizer, err := NewTokenizer(vocabPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := tokenizer.Tokenize(strings.NewReader(xmlContent))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tracker := tokenizer.NewPathTracker(nil)
+	c := tokenizer.NewConstraint(nil)
+	for i, id := range res.Tokens {
+		path, err := tracker.Push(id)
+		if err != nil || !slices.Equal(path, trimPadding(res.PaddedPaths[i])) {
+			t.Errorf("token %d: path %v, expected %v (%v)", i, path, res.PaddedPaths[i], err)
+		}
+		if err := c.Consume(id); err != nil {
+			t.Errorf("token %d: %v", i, err)
+		}
+	}
+
+	decoded, err := tokenizer.DecodeXML(res.Tokens)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := decoded.String(); !strings.Contains(got, "<style>p { margin: 0 }</style>") {
+		t.Errorf("raw text wrapper should decode to plain text, got %s", got)
+	}
+}
+
+func TestConvertHTMLToElement_Normalization(t *testing.T) {
+	input := "<html xmlns=\"http://www.w3.org/1999/xhtml\"><body>" +
+		`<button @click="go()" :class="{a: b}" x-on:click="x" ID="b1" id="b2" Data-Role="btn">Go` + "\x0b" + `</button>` +
+		`<foo@bar>unwrapped</foo@bar><svg viewBox="0 0 1 1"></svg></body></html>`
+
+	root, err := ConvertHTMLToElement(strings.NewReader(input))
+	require.NoError(t, err)
+	assert.Equal(t, `<html><head></head><body><button id="b1" data-role="btn">Go</button>unwrapped<svg viewBox="0 0 1 1"></svg></body></html>`, root.String())
+
+	// The XML form parses back with encoding/xml.
+	xmlStr, err := ConvertHTMLToXML(strings.NewReader(input))
+	require.NoError(t, err)
+	parsed, err := NewTransformer(map[string]int{
+		"<html>": 1, "</html>": 2, "<head>": 3, "</head>": 4, "<body>": 5, "</body>": 6,
+		"<button>": 7, "</button>": 8, "<svg>": 9, "</svg>": 10,
+		"##id": 11, "##data-role": 12, "##viewBox": 13,
+	}).Transform(strings.NewReader(xmlStr))
+	require.NoError(t, err)
+	assert.Equal(t, "html", parsed.Name)
+}
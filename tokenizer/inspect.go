@@ -0,0 +1,121 @@
+package tokenizer
+
+import "strings"
+
+// TokenInfo describes a token of a TokenizationResult, see Inspect.
+type TokenInfo struct {
+	Index int
+	ID    int
+	// Text is the token as Decode renders it.
+	Text string
+	Kind TokenKind
+	// Path is the path of the token without its padding.
+	Path []int
+}
+
+// InspectNode is a node of the tree rebuilt from a token sequence: an
+// element, an attribute or one of its parts, a typed value, a marker, or a
+// run of content tokens.
+type InspectNode struct {
+	// Kind is the kind of the first token of the node.
+	Kind TokenKind
+	// Label is the element or attribute name, the decoded text of a content
+	// run, or the token itself for other nodes.
+	Label string
+	// Path is the path of the first token of the node.
+	Path []int
+	// Start and End are the indices of the first and last tokens of the node.
+	Start    int
+	End      int
+	Children []*InspectNode
+}
+
+// Inspection lays out a TokenizationResult for debugging path assignment.
+type Inspection struct {
+	Tokens []TokenInfo
+	// Roots are the top-level nodes of the tree, the root element alone for
+	// a well-formed sequence.
+	Roots []*InspectNode
+}
+
+// Inspect describes each token of res and rebuilds the tree the tokens
+// encode, with the path and token span of every node. The tokens do not need
+// to be well-formed: unmatched closing tokens become leaves and unclosed
+// nodes end at the last token.
+func (t *Tokenizer) Inspect(res *TokenizationResult) *Inspection {
+	ins := &Inspection{Tokens: make([]TokenInfo, len(res.Tokens))}
+	var stack []*InspectNode
+	add := func(n *InspectNode) {
+		if len(stack) > 0 {
+			parent := stack[len(stack)-1]
+			parent.Children = append(parent.Children, n)
+		} else {
+			ins.Roots = append(ins.Roots, n)
+		}
+	}
+
+	for i, id := range res.Tokens {
+		kind, name := classifyToken(t.vocabInv, id)
+		info := TokenInfo{Index: i, ID: id, Text: t.Decode([]int{id}), Kind: kind}
+		if i < len(res.PaddedPaths) {
+			info.Path = unpadPath(res.PaddedPaths[i])
+		}
+		ins.Tokens[i] = info
+
+		label := info.Text
+		switch kind {
+		case KindStartTag, KindRegisteredAttr:
+			label = name
+		}
+		node := &InspectNode{Kind: kind, Label: label, Path: info.Path, Start: i, End: i}
+
+		switch kind {
+		case KindStartTag, KindRegisteredAttr, KindUnregisteredAttr, KindKey, KindValue, KindTypedValue, KindItem:
+			add(node)
+			stack = append(stack, node)
+		case KindEndTag, KindUnregisteredAttrEnd, KindKeyEnd, KindValueEnd, KindTypedValueEnd, KindItemEnd:
+			if len(stack) == 0 {
+				add(node)
+				continue
+			}
+			closed := stack[len(stack)-1]
+			closed.End = i
+			stack = stack[:len(stack)-1]
+			// An unregistered attribute is named by its key.
+			if closed.Kind == KindKey && len(stack) > 0 && stack[len(stack)-1].Kind == KindUnregisteredAttr {
+				var key strings.Builder
+				for _, c := range closed.Children {
+					key.WriteString(c.Label)
+				}
+				stack[len(stack)-1].Label = key.String()
+			}
+		case KindContent:
+			// Consecutive content tokens form a single text node.
+			if len(stack) > 0 {
+				siblings := stack[len(stack)-1].Children
+				if n := len(siblings); n > 0 && siblings[n-1].Kind == KindContent && siblings[n-1].End == i-1 {
+					siblings[n-1].Label += info.Text
+					siblings[n-1].End = i
+					continue
+				}
+			}
+			add(node)
+		default:
+			add(node)
+		}
+	}
+
+	for _, n := range stack {
+		n.End = len(res.Tokens) - 1
+	}
+	return ins
+}
+
+// unpadPath strips the -1 padding of a padded path.
+func unpadPath(padded []int) []int {
+	n := len(padded)
+	for n > 0 && padded[n-1] == -1 {
+		n--
+	}
+	return padded[:n:n]
+}
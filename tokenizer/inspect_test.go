@@ -0,0 +1,105 @@
+package tokenizer
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInspect(t *testing.T) {
+	vocab := map[string]int{
+		"<Doc>": 200001, "</Doc>": 200002,
+		"<Item>": 200003, "</Item>": 200004,
+		"##id": 200005, TokenValueEnd: 200006,
+		TokenUnregisteredAttr: 200007, TokenUnregisteredAttrEnd: 200008,
+		TokenKey: 200009, TokenKeyEnd: 200010, TokenValue: 200011,
+	}
+	tok := newTypedTokenizer(t, vocab)
+	res, err := tok.Tokenize(strings.NewReader(`<Doc id="d1" lang="en"><Item>Hi there</Item><Item/></Doc>`))
+	require.NoError(t, err)
+	ins := tok.Inspect(res)
+
+	require.Len(t, ins.Tokens, len(res.Tokens))
+	for i, info := range ins.Tokens {
+		assert.Equal(t, i, info.Index)
+		assert.Equal(t, res.Tokens[i], info.ID)
+		assert.Equal(t, tok.Decode([]int{info.ID}), info.Text)
+		assert.Equal(t, tok.TokenKind(info.ID), info.Kind)
+		assert.Equal(t, trimPadding(res.PaddedPaths[i]), info.Path)
+	}
+
+	// The spans follow the content tokens of the BPE: span reserves the
+	// tokens of s and returns its first and last index.
+	pos := 0
+	next := func() int {
+		pos++
+		return pos - 1
+	}
+	span := func(s string) (int, int) {
+		start := pos
+		pos += len(tok.contentTokenizer.Encode(s, nil, nil))
+		return start, pos - 1
+	}
+	doc := next()
+	id := next()
+	d1Start, d1End := span("d1")
+	idEnd := next()
+	lang := next()
+	key := next()
+	keyStart, keyEnd := span("lang")
+	keyClose := next()
+	value := next()
+	enStart, enEnd := span("en")
+	valueClose := next()
+	langEnd := next()
+	item := next()
+	hiStart, hiEnd := span("Hi there")
+	itemEnd := next()
+	empty := next()
+	emptyEnd := next()
+	docEnd := next()
+	require.Equal(t, len(res.Tokens), pos)
+
+	// summary renders a node as kind, label, path and span, with its
+	// children indented.
+	var summary func(n *InspectNode, depth int) []string
+	summary = func(n *InspectNode, depth int) []string {
+		lines := []string{fmt.Sprintf("%s%s %s %v [%d %d]", strings.Repeat("  ", depth), n.Kind, n.Label, n.Path, n.Start, n.End)}
+		for _, c := range n.Children {
+			lines = append(lines, summary(c, depth+1)...)
+		}
+		return lines
+	}
+	require.Len(t, ins.Roots, 1)
+	assert.Equal(t, []string{
+		fmt.Sprintf("start Doc [0] [%d %d]", doc, docEnd),
+		fmt.Sprintf("  attr id [0 0] [%d %d]", id, idEnd),
+		fmt.Sprintf("    content d1 [0 0 0] [%d %d]", d1Start, d1End),
+		fmt.Sprintf("  unregistered-attr lang [0 0] [%d %d]", lang, langEnd),
+		fmt.Sprintf("    key <__Key> [0 0 0] [%d %d]", key, keyClose),
+		fmt.Sprintf("      content lang [0 0 0 0] [%d %d]", keyStart, keyEnd),
+		fmt.Sprintf("    value <__Value> [0 0 1] [%d %d]", value, valueClose),
+		fmt.Sprintf("      content en [0 0 1 0] [%d %d]", enStart, enEnd),
+		fmt.Sprintf("  start Item [0 1] [%d %d]", item, itemEnd),
+		fmt.Sprintf("    content Hi there [0 1 1] [%d %d]", hiStart, hiEnd),
+		fmt.Sprintf("  start Item [0 1] [%d %d]", empty, emptyEnd),
+	}, summary(ins.Roots[0], 0))
+}
+
+func TestInspect_Malformed(t *testing.T) {
+	vocab := map[string]int{"<Doc>": 200001, "</Doc>": 200002, "<Item>": 200003, "</Item>": 200004}
+	tok := newTypedTokenizer(t, vocab)
+
+	// An unmatched end tag is a leaf, unclosed elements end at the last token.
+	ins := tok.Inspect(&TokenizationResult{Tokens: []int{200002, 200001, 200003, 72}})
+	require.Len(t, ins.Roots, 2)
+	assert.Equal(t, KindEndTag, ins.Roots[0].Kind)
+	doc := ins.Roots[1]
+	assert.Equal(t, [2]int{1, 3}, [2]int{doc.Start, doc.End})
+	require.Len(t, doc.Children, 1)
+	assert.Equal(t, [2]int{2, 3}, [2]int{doc.Children[0].Start, doc.Children[0].End})
+	assert.Nil(t, ins.Tokens[0].Path)
+}
@@ -0,0 +1,245 @@
+package tokenizer
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+const (
+	// JSONRootTag wraps the top-level JSON value.
+	JSONRootTag = "JSON"
+	// DataItemTag wraps each element of an array.
+	DataItemTag = "Item"
+	// DataEntryTag wraps an object member whose key is not a valid XML name.
+	DataEntryTag = "Entry"
+	// DataKeyAttribute holds the key of a DataEntryTag member.
+	DataKeyAttribute = "key"
+	// DataTypeAttribute records the type of scalars that are not strings.
+	DataTypeAttribute = "type"
+)
+
+// ConvertJSONToElement converts a JSON document into an Element tree that can
+// be tokenized with the same path semantics as XML. Objects become
+// arbor-ordered="false" containers whose members are child elements named
+// after their key, or DataEntryTag elements carrying the key in an attribute
+// when it is not a valid XML name. Arrays become arbor-ordered="true"
+// containers of DataItemTag elements. Scalars become text, with numbers,
+// booleans and null marked by a DataTypeAttribute.
+func ConvertJSONToElement(r io.Reader) (*Element, error) {
+	dec := json.NewDecoder(r)
+	dec.UseNumber()
+
+	root := &Element{Name: JSONRootTag}
+	if err := readJSONValue(dec, root); err != nil {
+		return nil, err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, fmt.Errorf("unexpected data after top-level JSON value")
+	}
+	return root, nil
+}
+
+// readJSONValue reads the next JSON value from dec into el.
+func readJSONValue(dec *json.Decoder, el *Element) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+
+	switch v := tok.(type) {
+	case json.Delim:
+		switch v {
+		case '{':
+			el.Attributes = append(el.Attributes, orderedAttr(false))
+			for dec.More() {
+				keyTok, err := dec.Token()
+				if err != nil {
+					return err
+				}
+				child := memberElement(keyTok.(string))
+				if err := readJSONValue(dec, child); err != nil {
+					return err
+				}
+				el.Children = append(el.Children, child)
+			}
+		case '[':
+			el.Attributes = append(el.Attributes, orderedAttr(true))
+			for dec.More() {
+				child := &Element{Name: DataItemTag}
+				if err := readJSONValue(dec, child); err != nil {
+					return err
+				}
+				el.Children = append(el.Children, child)
+			}
+		}
+		// Consume the closing delimiter.
+		_, err := dec.Token()
+		return err
+	case string:
+		if v != "" {
+			el.Children = append(el.Children, v)
+		}
+	case json.Number:
+		el.Attributes = append(el.Attributes, typeAttr("number"))
+		el.Children = append(el.Children, v.String())
+	case bool:
+		el.Attributes = append(el.Attributes, typeAttr("boolean"))
+		el.Children = append(el.Children, fmt.Sprint(v))
+	case nil:
+		el.Attributes = append(el.Attributes, typeAttr("null"))
+	}
+	return nil
+}
+
+// memberElement returns the element holding the object member with the given key.
+func memberElement(key string) *Element {
+	// Names starting with "__" are reserved for the encoder's special tags.
+	if isValidXMLName(key) && !strings.HasPrefix(key, "__") {
+		return &Element{Name: key}
+	}
+	return &Element{
+		Name:       DataEntryTag,
+		Attributes: []xml.Attr{{Name: xml.Name{Local: DataKeyAttribute}, Value: key}},
+	}
+}
+
+func orderedAttr(ordered bool) xml.Attr {
+	return xml.Attr{Name: xml.Name{Local: ArborOrderedAttribute}, Value: fmt.Sprint(ordered)}
+}
+
+func typeAttr(typ string) xml.Attr {
+	return xml.Attr{Name: xml.Name{Local: DataTypeAttribute}, Value: typ}
+}
+
+// ConvertElementToJSON converts an Element tree produced by
+// ConvertJSONToElement, or decoded from its tokens by DecodeXML, back to JSON.
+// Containers are recognized by their arbor-ordered attribute when present,
+// which requires the ordering markers in the vocab to survive decoding.
+// Otherwise an element whose children are all DataItemTag elements is an
+// array and any other element with children is an object; empty objects and
+// arrays then decode as empty strings.
+func ConvertElementToJSON(el *Element) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := writeJSONValue(&buf, el); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func writeJSONValue(buf *bytes.Buffer, el *Element) error {
+	children := childElements(el)
+
+	kind := attrValue(el, DataTypeAttribute)
+	switch attrValue(el, ArborOrderedAttribute) {
+	case "false":
+		kind = "object"
+	case "true":
+		kind = "array"
+	default:
+		if kind == "" && len(children) > 0 {
+			kind = "object"
+			if isItemList(children) {
+				kind = "array"
+			}
+		}
+	}
+
+	switch kind {
+	case "object":
+		buf.WriteByte('{')
+		for i, child := range children {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			key := child.Name
+			if child.Name == DataEntryTag {
+				if k, ok := lookupAttr(child, DataKeyAttribute); ok {
+					key = k
+				}
+			}
+			writeJSONString(buf, key)
+			buf.WriteByte(':')
+			if err := writeJSONValue(buf, child); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('}')
+	case "array":
+		buf.WriteByte('[')
+		for i, child := range children {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := writeJSONValue(buf, child); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(']')
+	case "null":
+		buf.WriteString("null")
+	case "number", "boolean":
+		text := strings.TrimSpace(textContent(el))
+		if !json.Valid([]byte(text)) {
+			return fmt.Errorf("invalid %s %q in <%s>", kind, text, el.Name)
+		}
+		buf.WriteString(text)
+	default:
+		writeJSONString(buf, textContent(el))
+	}
+	return nil
+}
+
+func writeJSONString(buf *bytes.Buffer, s string) {
+	enc := json.NewEncoder(buf)
+	enc.SetEscapeHTML(false)
+	enc.Encode(s)
+	// json.Encoder terminates each value with a newline.
+	buf.Truncate(buf.Len() - 1)
+}
+
+func childElements(el *Element) []*Element {
+	var children []*Element
+	for _, c := range el.Children {
+		if child, ok := c.(*Element); ok {
+			children = append(children, child)
+		}
+	}
+	return children
+}
+
+func isItemList(children []*Element) bool {
+	for _, child := range children {
+		if child.Name != DataItemTag {
+			return false
+		}
+	}
+	return true
+}
+
+func textContent(el *Element) string {
+	var sb strings.Builder
+	for _, c := range el.Children {
+		if s, ok := c.(string); ok {
+			sb.WriteString(s)
+		}
+	}
+	return sb.String()
+}
+
+func lookupAttr(el *Element, name string) (string, bool) {
+	for _, attr := range el.Attributes {
+		if attr.Name.Local == name {
+			return attr.Value, true
+		}
+	}
+	return "", false
+}
+
+func attrValue(el *Element, name string) string {
+	v, _ := lookupAttr(el, name)
+	return v
+}
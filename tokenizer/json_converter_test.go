@@ -0,0 +1,130 @@
+package tokenizer
+
+import (
+	"encoding/xml"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConvertJSONToElement(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "Object",
+			input:    `{"name": "Paris", "population": 2100000, "capital": true, "mayor": null}`,
+			expected: `<JSON arbor-ordered="false"><name>Paris</name><population type="number">2100000</population><capital type="boolean">true</capital><mayor type="null"></mayor></JSON>`,
+		},
+		{
+			name:     "Array",
+			input:    `[1, "two", [3]]`,
+			expected: `<JSON arbor-ordered="true"><Item type="number">1</Item><Item>two</Item><Item arbor-ordered="true"><Item type="number">3</Item></Item></JSON>`,
+		},
+		{
+			name:     "InvalidKeys",
+			input:    `{"first name": "Ada", "1st": 1, "__Key": "x", "ok": {}}`,
+			expected: `<JSON arbor-ordered="false"><Entry key="first name">Ada</Entry><Entry key="1st" type="number">1</Entry><Entry key="__Key">x</Entry><ok arbor-ordered="false"></ok></JSON>`,
+		},
+		{
+			name:     "Scalar",
+			input:    `"hello"`,
+			expected: `<JSON>hello</JSON>`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			el, err := ConvertJSONToElement(strings.NewReader(tt.input))
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, el.String())
+		})
+	}
+}
+
+func TestConvertJSONToElement_Errors(t *testing.T) {
+	for _, input := range []string{``, `{"a": 1`, `{"a": 1} {"b": 2}`, `[1,]`} {
+		_, err := ConvertJSONToElement(strings.NewReader(input))
+		assert.Error(t, err, "input %q", input)
+	}
+}
+
+func TestConvertElementToJSON_Inference(t *testing.T) {
+	// Without arbor-ordered attributes, containers are inferred from their children.
+	el := &Element{Name: JSONRootTag, Children: []interface{}{
+		&Element{Name: "tags", Children: []interface{}{
+			&Element{Name: DataItemTag, Children: []interface{}{"a"}},
+			&Element{Name: DataItemTag, Children: []interface{}{"b"}},
+		}},
+		&Element{Name: "size", Attributes: []xml.Attr{{Name: xml.Name{Local: DataTypeAttribute}, Value: "number"}}, Children: []interface{}{"3"}},
+	}}
+
+	out, err := ConvertElementToJSON(el)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"tags": ["a", "b"], "size": 3}`, string(out))
+
+	bad := &Element{Name: JSONRootTag, Attributes: []xml.Attr{{Name: xml.Name{Local: DataTypeAttribute}, Value: "number"}}, Children: []interface{}{"abc"}}
+	_, err = ConvertElementToJSON(bad)
+	assert.Error(t, err)
+}
+
+func TestJSON_RoundTrip(t *testing.T) {
+	base := 200000
+	vocab := map[string]int{
+		"<JSON>":       base + 1,
+		"</JSON>":      base + 2,
+		"<Item>":       base + 3,
+		"</Item>":      base + 4,
+		"<Entry>":      base + 5,
+		"</Entry>":     base + 6,
+		"<city>":       base + 7,
+		"</city>":      base + 8,
+		"<tags>":       base + 9,
+		"</tags>":      base + 10,
+		"<nested>":     base + 11,
+		"</nested>":    base + 12,
+		"##type":       base + 100,
+		"##key":        base + 101,
+		TokenValueEnd:  base + 102,
+		TokenEmpty:     base + 103,
+		TokenOrdered:   base + 104,
+		TokenUnordered: base + 105,
+	}
+	vocabPath := createTempVocab(t, vocab)
+	defer os.Remove(vocabPath)
+	tokenizer, err := NewTokenizer(vocabPath)
+	require.NoError(t, err)
+
+	input := `{"city":"Paris","tags":["a",2,false,null,{}],"zip code":"75001","nested":{"tags":[]}}`
+	el, err := ConvertJSONToElement(strings.NewReader(input))
+	require.NoError(t, err)
+
+	res, err := tokenizer.TokenizeElement(el)
+	require.NoError(t, err)
+
+	// Object members share a path index, array items do not.
+	pathOf := func(id int) [][]int {
+		var paths [][]int
+		for i, tok := range res.Tokens {
+			if tok == id {
+				paths = append(paths, trimPadding(res.PaddedPaths[i]))
+			}
+		}
+		return paths
+	}
+	assert.Equal(t, pathOf(base + 7)[0], pathOf(base + 9)[0], "members of an object")
+	items := pathOf(base + 3)
+	require.GreaterOrEqual(t, len(items), 2)
+	assert.NotEqual(t, items[0], items[2], "items of an array")
+
+	decoded, err := tokenizer.DecodeXML(res.Tokens)
+	require.NoError(t, err)
+	out, err := ConvertElementToJSON(decoded)
+	require.NoError(t, err)
+	assert.Equal(t, input, string(out))
+}
@@ -0,0 +1,431 @@
+package tokenizer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/xml"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Element names produced by ConvertMarkdownToElement.
+const (
+	MarkdownDocumentTag   = "Document"
+	MarkdownSectionTag    = "Section"
+	MarkdownHeadingTag    = "Heading"
+	MarkdownParagraphTag  = "Paragraph"
+	MarkdownListTag       = "List"
+	MarkdownItemTag       = "Item"
+	MarkdownCodeBlockTag  = "CodeBlock"
+	MarkdownBlockQuoteTag = "BlockQuote"
+	MarkdownTableTag      = "Table"
+	MarkdownHeaderTag     = "Header"
+	MarkdownRowTag        = "Row"
+	MarkdownCellTag       = "Cell"
+)
+
+var (
+	mdATXHeading    = regexp.MustCompile(`^ {0,3}(#{1,6})(?:[ \t]+(.*?))?(?:[ \t]+#+)?[ \t]*$`)
+	mdSetextH1      = regexp.MustCompile(`^ {0,3}=+[ \t]*$`)
+	mdSetextH2      = regexp.MustCompile(`^ {0,3}-+[ \t]*$`)
+	mdThematicBreak = regexp.MustCompile(`^ {0,3}(?:(?:\*[ \t]*){3,}|(?:-[ \t]*){3,}|(?:_[ \t]*){3,})$`)
+	mdFence         = regexp.MustCompile("^( {0,3})(`{3,}|~{3,})[ \t]*([^`]*?)[ \t]*$")
+	mdBlockQuote    = regexp.MustCompile(`^ {0,3}> ?`)
+	mdListItem      = regexp.MustCompile(`^( {0,3})([-+*]|[0-9]{1,9}[.)])(?:([ \t]+)(.*))?$`)
+	mdTableDelim    = regexp.MustCompile(`^ {0,3}\|?[ \t]*:?-+:?[ \t]*(?:\|[ \t]*:?-+:?[ \t]*)*\|?[ \t]*$`)
+)
+
+// ConvertMarkdownToXML converts a Markdown document to an indented XML string,
+// see ConvertMarkdownToElement.
+func ConvertMarkdownToXML(r io.Reader) (string, error) {
+	root, err := ConvertMarkdownToElement(r)
+	if err != nil {
+		return "", err
+	}
+	var b bytes.Buffer
+	root.PrettyPrint(&b, 0)
+	return strings.TrimSpace(b.String()), nil
+}
+
+// ConvertMarkdownToElement parses the block structure of a CommonMark
+// document into an Element tree. Top-level headings open Section elements
+// nested by heading level, so the hierarchy follows the document outline.
+// Paragraphs, lists, code blocks, block quotes and GFM tables become elements
+// of their own; inline markup is kept verbatim in the text. Thematic breaks
+// carry no content and are dropped. Every container is arbor-ordered="true".
+func ConvertMarkdownToElement(r io.Reader) (*Element, error) {
+	var lines []string
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 10*1024*1024)
+	for scanner.Scan() {
+		lines = append(lines, expandLeadingTabs(scanner.Text()))
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	root := newMarkdownContainer(MarkdownDocumentTag)
+	sections := []*Element{root}
+	levels := []int{0}
+
+	for _, block := range parseMarkdownBlocks(lines) {
+		if block.level == 0 {
+			parent := sections[len(sections)-1]
+			parent.Children = append(parent.Children, block.el)
+			continue
+		}
+
+		for levels[len(levels)-1] >= block.level {
+			sections = sections[:len(sections)-1]
+			levels = levels[:len(levels)-1]
+		}
+		section := newMarkdownContainer(MarkdownSectionTag)
+		section.Attributes = append(section.Attributes, xml.Attr{Name: xml.Name{Local: "level"}, Value: strconv.Itoa(block.level)})
+		section.Children = append(section.Children, block.el)
+
+		parent := sections[len(sections)-1]
+		parent.Children = append(parent.Children, section)
+		sections = append(sections, section)
+		levels = append(levels, block.level)
+	}
+
+	return root, nil
+}
+
+// markdownBlock is a parsed block. level is the heading level of headings and
+// 0 for other blocks.
+type markdownBlock struct {
+	el    *Element
+	level int
+}
+
+func newMarkdownContainer(name string) *Element {
+	return &Element{Name: name, Attributes: []xml.Attr{orderedAttr(true)}}
+}
+
+func newMarkdownText(name, text string) *Element {
+	el := &Element{Name: name}
+	if text != "" {
+		el.Children = append(el.Children, text)
+	}
+	return el
+}
+
+func elementsOf(blocks []markdownBlock) []interface{} {
+	var children []interface{}
+	for _, b := range blocks {
+		children = append(children, b.el)
+	}
+	return children
+}
+
+// parseMarkdownBlocks parses lines into a flat sequence of blocks.
+func parseMarkdownBlocks(lines []string) []markdownBlock {
+	var blocks []markdownBlock
+	var paragraph []string
+
+	flush := func() {
+		if len(paragraph) > 0 {
+			blocks = append(blocks, markdownBlock{el: newMarkdownText(MarkdownParagraphTag, joinMarkdownLines(paragraph))})
+			paragraph = nil
+		}
+	}
+
+	for i := 0; i < len(lines); {
+		line := lines[i]
+
+		if strings.TrimSpace(line) == "" {
+			flush()
+			i++
+			continue
+		}
+
+		// Setext headings underline the paragraph they follow.
+		if len(paragraph) > 0 && (mdSetextH1.MatchString(line) || mdSetextH2.MatchString(line)) {
+			level := 1
+			if mdSetextH2.MatchString(line) {
+				level = 2
+			}
+			blocks = append(blocks, markdownBlock{el: newMarkdownText(MarkdownHeadingTag, joinMarkdownLines(paragraph)), level: level})
+			paragraph = nil
+			i++
+			continue
+		}
+
+		// Indented code cannot interrupt a paragraph.
+		if len(paragraph) == 0 && indentOf(line) >= 4 {
+			var code []string
+			for i < len(lines) && (indentOf(lines[i]) >= 4 || strings.TrimSpace(lines[i]) == "") {
+				code = append(code, stripIndent(lines[i], 4))
+				i++
+			}
+			for len(code) > 0 && strings.TrimSpace(code[len(code)-1]) == "" {
+				code = code[:len(code)-1]
+			}
+			blocks = append(blocks, markdownBlock{el: newMarkdownText(MarkdownCodeBlockTag, strings.Join(code, "\n"))})
+			continue
+		}
+
+		if m := mdFence.FindStringSubmatch(line); m != nil {
+			flush()
+			indent, fence := len(m[1]), m[2]
+			var code []string
+			i++
+			for i < len(lines) {
+				if closing := strings.TrimSpace(lines[i]); indentOf(lines[i]) < 4 &&
+					strings.HasPrefix(closing, fence) && strings.Trim(closing, fence[:1]) == "" {
+					i++
+					break
+				}
+				code = append(code, stripIndent(lines[i], indent))
+				i++
+			}
+			el := newMarkdownText(MarkdownCodeBlockTag, strings.Join(code, "\n"))
+			if info := strings.Fields(m[3]); len(info) > 0 {
+				el.Attributes = append(el.Attributes, xml.Attr{Name: xml.Name{Local: "language"}, Value: info[0]})
+			}
+			blocks = append(blocks, markdownBlock{el: el})
+			continue
+		}
+
+		if m := mdATXHeading.FindStringSubmatch(line); m != nil {
+			flush()
+			blocks = append(blocks, markdownBlock{el: newMarkdownText(MarkdownHeadingTag, strings.TrimSpace(m[2])), level: len(m[1])})
+			i++
+			continue
+		}
+
+		if mdThematicBreak.MatchString(line) {
+			flush()
+			i++
+			continue
+		}
+
+		if mdBlockQuote.MatchString(line) {
+			flush()
+			var quoted []string
+			for i < len(lines) && mdBlockQuote.MatchString(lines[i]) {
+				quoted = append(quoted, mdBlockQuote.ReplaceAllString(lines[i], ""))
+				i++
+			}
+			el := newMarkdownContainer(MarkdownBlockQuoteTag)
+			el.Children = elementsOf(parseMarkdownBlocks(quoted))
+			blocks = append(blocks, markdownBlock{el: el})
+			continue
+		}
+
+		if mdListItem.MatchString(line) {
+			flush()
+			var el *Element
+			el, i = parseMarkdownList(lines, i)
+			blocks = append(blocks, markdownBlock{el: el})
+			continue
+		}
+
+		if len(paragraph) == 0 && strings.Contains(line, "|") && i+1 < len(lines) && mdTableDelim.MatchString(lines[i+1]) {
+			var el *Element
+			el, i = parseMarkdownTable(lines, i)
+			blocks = append(blocks, markdownBlock{el: el})
+			continue
+		}
+
+		paragraph = append(paragraph, line)
+		i++
+	}
+	flush()
+
+	return blocks
+}
+
+// parseMarkdownList parses the list starting at lines[start] and returns it
+// with the index of the first line after it.
+func parseMarkdownList(lines []string, start int) (*Element, int) {
+	first := mdListItem.FindStringSubmatch(lines[start])
+	ordered := first[2] != "-" && first[2] != "+" && first[2] != "*"
+	delim := first[2][len(first[2])-1:]
+
+	list := newMarkdownContainer(MarkdownListTag)
+	if ordered {
+		list.Attributes = append(list.Attributes, xml.Attr{Name: xml.Name{Local: "type"}, Value: "ordered"})
+		if n, _ := strconv.Atoi(strings.TrimRight(first[2], ".)")); n != 1 {
+			list.Attributes = append(list.Attributes, xml.Attr{Name: xml.Name{Local: "start"}, Value: strconv.Itoa(n)})
+		}
+	} else {
+		list.Attributes = append(list.Attributes, xml.Attr{Name: xml.Name{Local: "type"}, Value: "bullet"})
+	}
+
+	i := start
+	for i < len(lines) {
+		m := mdListItem.FindStringSubmatch(lines[i])
+		if m == nil || m[2][len(m[2])-1:] != delim {
+			break
+		}
+
+		// Continuation lines are indented at least up to the item content.
+		contentIndent := len(m[1]) + len(m[2]) + len(m[3])
+		if m[4] == "" || len(m[3]) > 4 {
+			contentIndent = len(m[1]) + len(m[2]) + 1
+		}
+		item := []string{strings.Repeat(" ", contentIndent) + m[4]}
+		i++
+
+		for i < len(lines) {
+			line := lines[i]
+			if strings.TrimSpace(line) == "" {
+				// A blank line continues the item only if indented content follows.
+				j := i
+				for j < len(lines) && strings.TrimSpace(lines[j]) == "" {
+					j++
+				}
+				if j == len(lines) || indentOf(lines[j]) < contentIndent {
+					break
+				}
+				item = append(item, lines[i:j]...)
+				i = j
+				continue
+			}
+			if indentOf(line) >= contentIndent {
+				item = append(item, line)
+				i++
+				continue
+			}
+			// Lazy continuation of the item paragraph.
+			if startsMarkdownBlock(line) || strings.TrimSpace(item[len(item)-1]) == "" {
+				break
+			}
+			item = append(item, strings.Repeat(" ", contentIndent)+strings.TrimSpace(line))
+			i++
+		}
+
+		for k := range item {
+			item[k] = stripIndent(item[k], contentIndent)
+		}
+		list.Children = append(list.Children, markdownListItem(parseMarkdownBlocks(item)))
+
+		// Blank lines between items keep the list going.
+		j := i
+		for j < len(lines) && strings.TrimSpace(lines[j]) == "" {
+			j++
+		}
+		if j < len(lines) && j > i {
+			if m := mdListItem.FindStringSubmatch(lines[j]); m != nil && m[2][len(m[2])-1:] == delim {
+				i = j
+			}
+		}
+	}
+	return list, i
+}
+
+// markdownListItem builds an Item, inlining the text of an item holding a
+// single paragraph.
+func markdownListItem(blocks []markdownBlock) *Element {
+	item := newMarkdownContainer(MarkdownItemTag)
+	paragraphs := 0
+	for _, b := range blocks {
+		if b.el.Name == MarkdownParagraphTag {
+			paragraphs++
+		}
+	}
+	for i, b := range blocks {
+		if i == 0 && paragraphs == 1 && b.el.Name == MarkdownParagraphTag {
+			item.Children = append(item.Children, b.el.Children...)
+			continue
+		}
+		item.Children = append(item.Children, b.el)
+	}
+	return item
+}
+
+// parseMarkdownTable parses the GFM table starting at lines[start] and
+// returns it with the index of the first line after it.
+func parseMarkdownTable(lines []string, start int) (*Element, int) {
+	table := newMarkdownContainer(MarkdownTableTag)
+
+	header := newMarkdownContainer(MarkdownHeaderTag)
+	header.Children = markdownTableCells(lines[start])
+	table.Children = append(table.Children, header)
+
+	i := start + 2
+	for i < len(lines) && strings.TrimSpace(lines[i]) != "" && strings.Contains(lines[i], "|") && !startsMarkdownBlock(lines[i]) {
+		row := newMarkdownContainer(MarkdownRowTag)
+		row.Children = markdownTableCells(lines[i])
+		table.Children = append(table.Children, row)
+		i++
+	}
+	return table, i
+}
+
+func markdownTableCells(line string) []interface{} {
+	line = strings.TrimSpace(line)
+	line = strings.TrimPrefix(line, "|")
+	if strings.HasSuffix(line, "|") && !strings.HasSuffix(line, `\|`) {
+		line = line[:len(line)-1]
+	}
+
+	var cells []interface{}
+	var cell strings.Builder
+	for i := 0; i < len(line); i++ {
+		switch {
+		case line[i] == '\\' && i+1 < len(line) && line[i+1] == '|':
+			cell.WriteByte('|')
+			i++
+		case line[i] == '|':
+			cells = append(cells, newMarkdownText(MarkdownCellTag, strings.TrimSpace(cell.String())))
+			cell.Reset()
+		default:
+			cell.WriteByte(line[i])
+		}
+	}
+	return append(cells, newMarkdownText(MarkdownCellTag, strings.TrimSpace(cell.String())))
+}
+
+// startsMarkdownBlock reports whether line opens a block that interrupts a
+// paragraph.
+func startsMarkdownBlock(line string) bool {
+	return mdATXHeading.MatchString(line) || mdFence.MatchString(line) ||
+		mdThematicBreak.MatchString(line) || mdBlockQuote.MatchString(line) ||
+		mdListItem.MatchString(line)
+}
+
+// joinMarkdownLines joins the lines of a paragraph, turning soft line breaks
+// into spaces.
+func joinMarkdownLines(lines []string) string {
+	parts := make([]string, len(lines))
+	for i, line := range lines {
+		parts[i] = strings.TrimSpace(line)
+	}
+	return strings.Join(parts, " ")
+}
+
+func indentOf(line string) int {
+	return len(line) - len(strings.TrimLeft(line, " "))
+}
+
+func stripIndent(line string, n int) string {
+	if indent := indentOf(line); indent < n {
+		n = indent
+	}
+	return line[n:]
+}
+
+func expandLeadingTabs(line string) string {
+	var b strings.Builder
+	col := 0
+	for i := 0; i < len(line); i++ {
+		switch line[i] {
+		case ' ':
+			b.WriteByte(' ')
+			col++
+		case '\t':
+			n := 4 - col%4
+			b.WriteString(strings.Repeat(" ", n))
+			col += n
+		default:
+			b.WriteString(line[i:])
+			return b.String()
+		}
+	}
+	return b.String()
+}
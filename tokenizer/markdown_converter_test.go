@@ -0,0 +1,118 @@
+package tokenizer
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConvertMarkdownToXML_Golden(t *testing.T) {
+	files, err := filepath.Glob("testdata/*.md")
+	require.NoError(t, err)
+
+	for _, inputFile := range files {
+		t.Run(filepath.Base(inputFile), func(t *testing.T) {
+			goldenFile := strings.TrimSuffix(inputFile, ".md") + "_md.xml"
+
+			f, err := os.Open(inputFile)
+			require.NoError(t, err)
+			defer f.Close()
+
+			actual, err := ConvertMarkdownToXML(f)
+			require.NoError(t, err)
+
+			if *update {
+				require.NoError(t, os.WriteFile(goldenFile, []byte(actual+"\n"), 0644))
+			}
+
+			expected, err := os.ReadFile(goldenFile)
+			if os.IsNotExist(err) {
+				t.Fatalf("golden file %s missing, run with -update to generate", goldenFile)
+			}
+			require.NoError(t, err)
+			assert.Equal(t, string(expected), actual+"\n")
+		})
+	}
+}
+
+func TestConvertMarkdownToElement_RoundTrip(t *testing.T) {
+	f, err := os.Open("testdata/guide.md")
+	require.NoError(t, err)
+	defer f.Close()
+
+	root, err := ConvertMarkdownToElement(f)
+	require.NoError(t, err)
+
+	vocab := make(map[string]int)
+	id := Cl100kBaseMaxID + 1
+	for _, tok := range []string{TokenOrdered, TokenUnordered, "##level", "##type", "##language", "##start", TokenValueEnd} {
+		vocab[tok] = id
+		id++
+	}
+	for _, name := range []string{
+		MarkdownDocumentTag, MarkdownSectionTag, MarkdownHeadingTag, MarkdownParagraphTag,
+		MarkdownListTag, MarkdownItemTag, MarkdownCodeBlockTag, MarkdownBlockQuoteTag,
+		MarkdownTableTag, MarkdownHeaderTag, MarkdownRowTag, MarkdownCellTag,
+	} {
+		vocab["<"+name+">"] = id
+		vocab["</"+name+">"] = id + 1
+		id += 2
+	}
+	vocabPath := createTempVocab(t, vocab)
+	defer os.Remove(vocabPath)
+	tokenizer, err := NewTokenizer(vocabPath)
+	require.NoError(t, err)
+
+	res, err := tokenizer.TokenizeElement(root)
+	require.NoError(t, err)
+
+	decoded, err := tokenizer.DecodeXML(res.Tokens)
+	require.NoError(t, err)
+	assert.Equal(t, root.String(), decoded.String())
+}
+
+func TestConvertMarkdownToElement_Blocks(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "Empty",
+			input:    "",
+			expected: `<Document arbor-ordered="true"></Document>`,
+		},
+		{
+			name:     "OrderedListStart",
+			input:    "3) three\n4) four\n",
+			expected: `<Document arbor-ordered="true"><List arbor-ordered="true" type="ordered" start="3"><Item arbor-ordered="true">three</Item><Item arbor-ordered="true">four</Item></List></Document>`,
+		},
+		{
+			name:     "UnclosedFence",
+			input:    "~~~\ncode\n\n  more",
+			expected: `<Document arbor-ordered="true"><CodeBlock>code&#xA;&#xA;  more</CodeBlock></Document>`,
+		},
+		{
+			name:     "ClosingHashes",
+			input:    "## Title ##\n\tindented code",
+			expected: `<Document arbor-ordered="true"><Section arbor-ordered="true" level="2"><Heading>Title</Heading><CodeBlock>indented code</CodeBlock></Section></Document>`,
+		},
+		{
+			name:     "HeadingInQuote",
+			input:    "> # Quoted\n> text",
+			expected: `<Document arbor-ordered="true"><BlockQuote arbor-ordered="true"><Heading>Quoted</Heading><Paragraph>text</Paragraph></BlockQuote></Document>`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root, err := ConvertMarkdownToElement(strings.NewReader(tt.input))
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, root.String())
+		})
+	}
+}
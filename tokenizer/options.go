@@ -0,0 +1,155 @@
+package tokenizer
+
+// Options configures a Tokenizer and the Transformer it uses. The zero value
+// matches the historical behavior.
+type Options struct {
+	// TypedValues enables typed value encoding, see WithTypedValues.
+	TypedValues bool
+	// TypeHints overrides type detection, see WithTypedValues.
+	TypeHints map[string]ValueType
+	// SetAttributes lists the set-valued attributes, see WithSetAttributes.
+	SetAttributes []string
+	// AttributeOrder selects how attributes are ordered, see
+	// WithAttributeOrder.
+	AttributeOrder AttributeOrder
+	// OrderingRules sets the ordering of elements without arbor-ordered, see
+	// WithOrderingRules.
+	OrderingRules *OrderingRules
+	// OrderingRulesFile is loaded into OrderingRules by NewTokenizer, see
+	// WithOrderingRulesFile.
+	OrderingRulesFile string
+	// DefaultOrdering is the ordering of elements without arbor-ordered, see
+	// WithDefaultOrdering.
+	DefaultOrdering ChildOrder
+	// InheritOrdering makes elements without arbor-ordered take the ordering
+	// of their parent, see WithInheritedOrdering.
+	InheritOrdering bool
+}
+
+// AttributeOrder selects the order in which the attributes of an element are
+// emitted and the indices they receive.
+type AttributeOrder int
+
+const (
+	// AttributesSorted emits attributes sorted by name, all at index 0 of
+	// their element, so reordering attributes in the source changes nothing.
+	// This is the default.
+	AttributesSorted AttributeOrder = iota
+	// AttributesSourceOrder emits attributes in source order at indices 0, 1,
+	// 2... The children of the element then start after the last attribute.
+	AttributesSourceOrder
+	// AttributesUnordered emits attributes in source order, all at index 0.
+	AttributesUnordered
+)
+
+// ChildOrder is the ordering of the children of an element.
+type ChildOrder int
+
+const (
+	// ChildrenUnordered gives all the child elements the same index, so
+	// their order has no effect on the encoding. This is the default.
+	ChildrenUnordered ChildOrder = iota
+	// ChildrenOrdered gives the child elements increasing indices.
+	ChildrenOrdered
+)
+
+// Option sets a field of Options.
+type Option func(*Options)
+
+// WithTypedValues makes the Transformer emit numbers, booleans and ISO 8601
+// dates found in text and attribute values through dedicated tokens:
+// <__Number>digits</__Number>, <__Date>digits</__Date>, <__True/> and
+// <__False/>. Numbers and dates are tokenized one character at a time, so a
+// model always sees the same pieces for the same digits. A type is only used
+// when its tokens are in the vocab.
+//
+// hints may be nil. Its keys are a tag name for the text of that element,
+// "@attr" for an attribute on any element, or "Tag@attr" for an attribute of
+// a given element. A hint restricts detection to one type, and ValueString
+// disables it.
+func WithTypedValues(hints map[string]ValueType) Option {
+	return func(o *Options) {
+		o.TypedValues = true
+		o.TypeHints = hints
+	}
+}
+
+// WithSetAttributes makes the Transformer split the listed attributes on
+// whitespace and emit their members as unordered <__Item> children of the
+// attribute value. Members share one index, so class="btn active" and
+// class="active btn" give each member the same path. Duplicate members are
+// dropped and DecodeXML joins the members with single spaces.
+//
+// A name is either "attr" for an attribute on any element or "Tag@attr" for an
+// attribute of a given element. With no names, DefaultSetAttributes is used.
+// Splitting only happens when <__Item> and </__Item> are in the vocab.
+func WithSetAttributes(names ...string) Option {
+	return func(o *Options) {
+		if len(names) == 0 {
+			names = DefaultSetAttributes
+		}
+		o.SetAttributes = append(o.SetAttributes, names...)
+	}
+}
+
+// WithAttributeOrder sets how the Transformer orders attributes and how the
+// Encoder and PathTracker index them. DecodeXML restores attributes in the
+// order they were emitted.
+func WithAttributeOrder(order AttributeOrder) Option {
+	return func(o *Options) {
+		o.AttributeOrder = order
+	}
+}
+
+// WithOrderingRules makes the Transformer add arbor-ordered to the elements
+// matched by rules that do not carry it. The PathTracker applies the rules on
+// tags and paths; rules on attributes reach it only through the ordering
+// markers, when the vocab has them.
+func WithOrderingRules(rules *OrderingRules) Option {
+	return func(o *Options) {
+		o.OrderingRules = rules
+	}
+}
+
+// WithOrderingRulesFile is like WithOrderingRules with rules read by
+// NewTokenizer from a file, see LoadOrderingRules.
+func WithOrderingRulesFile(path string) Option {
+	return func(o *Options) {
+		o.OrderingRulesFile = path
+	}
+}
+
+// WithDefaultOrdering sets the ordering of elements without arbor-ordered
+// and without a matching ordering rule. arbor-ordered="true" and "false"
+// always win over the default. Content tokens are ordered whatever the
+// ordering of their element.
+func WithDefaultOrdering(order ChildOrder) Option {
+	return func(o *Options) {
+		o.DefaultOrdering = order
+	}
+}
+
+// WithInheritedOrdering makes elements without arbor-ordered and without a
+// matching ordering rule take the ordering of their parent, so one attribute
+// orders a whole subtree. The document element takes the default ordering.
+func WithInheritedOrdering() Option {
+	return func(o *Options) {
+		o.InheritOrdering = true
+	}
+}
+
+// withOptions replaces all options, it forwards a Tokenizer's options to the
+// components it creates.
+func withOptions(options Options) Option {
+	return func(o *Options) {
+		*o = options
+	}
+}
+
+func newOptions(opts []Option) Options {
+	var o Options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
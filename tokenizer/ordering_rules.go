@@ -0,0 +1,135 @@
+package tokenizer
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+)
+
+// OrderingRule declares whether the children of the matching elements are
+// ordered. All the selectors that are set must match.
+type OrderingRule struct {
+	// Tag matches the element name.
+	Tag string `json:"tag,omitempty"`
+	// Path matches the names of the element and its ancestors, separated by
+	// "/". A leading "/" anchors the path at the document element, otherwise
+	// it matches the last steps, e.g. "Items/Item". "*" matches any name.
+	Path string `json:"path,omitempty"`
+	// Attribute matches elements carrying this attribute. When Value is not
+	// empty, the attribute value, or one of its space-separated members,
+	// must equal Value.
+	Attribute string `json:"attribute,omitempty"`
+	Value     string `json:"value,omitempty"`
+	// Ordered is the ordering applied to the matching elements.
+	Ordered bool `json:"ordered"`
+}
+
+// OrderingRules declares element ordering outside the documents, so corpora
+// can be tokenized without adding arbor-ordered attributes. When several
+// rules match, the last one wins. An inline arbor-ordered attribute takes
+// precedence over the rules.
+type OrderingRules struct {
+	Rules []OrderingRule `json:"rules"`
+}
+
+// LoadOrderingRules reads ordering rules from a JSON file:
+//
+//	{"rules": [
+//	  {"tag": "Mayors", "ordered": true},
+//	  {"path": "/Catalog/Products", "ordered": false},
+//	  {"attribute": "class", "value": "steps", "ordered": true}
+//	]}
+func LoadOrderingRules(path string) (*OrderingRules, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open ordering rules file: %w", err)
+	}
+	defer f.Close()
+	return ParseOrderingRules(f)
+}
+
+// ParseOrderingRules reads ordering rules in the format of LoadOrderingRules.
+func ParseOrderingRules(r io.Reader) (*OrderingRules, error) {
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	var rules OrderingRules
+	if err := dec.Decode(&rules); err != nil {
+		return nil, fmt.Errorf("failed to decode ordering rules: %w", err)
+	}
+	for i, rule := range rules.Rules {
+		if rule.Tag == "" && rule.Path == "" && rule.Attribute == "" {
+			return nil, fmt.Errorf("ordering rule %d has no tag, path or attribute", i)
+		}
+		if rule.Value != "" && rule.Attribute == "" {
+			return nil, fmt.Errorf("ordering rule %d has a value but no attribute", i)
+		}
+		if rule.Path != "" && slices.Contains(strings.Split(strings.TrimPrefix(rule.Path, "/"), "/"), "") {
+			return nil, fmt.Errorf("ordering rule %d has an empty step in path %q", i, rule.Path)
+		}
+	}
+	return &rules, nil
+}
+
+// Match returns the ordering of an element, given the names from the
+// document element down to it and its attributes. ok is false when no rule
+// matches. attrs may be nil, then rules on attributes never match.
+func (r *OrderingRules) Match(path []string, attrs []xml.Attr) (ordered bool, ok bool) {
+	if r == nil || len(path) == 0 {
+		return false, false
+	}
+	for _, rule := range r.Rules {
+		if rule.matches(path, attrs) {
+			ordered, ok = rule.Ordered, true
+		}
+	}
+	return ordered, ok
+}
+
+func (rule OrderingRule) matches(path []string, attrs []xml.Attr) bool {
+	if rule.Tag != "" && rule.Tag != path[len(path)-1] {
+		return false
+	}
+	if rule.Path != "" && !matchPath(rule.Path, path) {
+		return false
+	}
+	if rule.Attribute != "" {
+		i := slices.IndexFunc(attrs, func(a xml.Attr) bool { return a.Name.Local == rule.Attribute })
+		if i < 0 {
+			return false
+		}
+		if rule.Value != "" && attrs[i].Value != rule.Value &&
+			!slices.Contains(strings.Fields(attrs[i].Value), rule.Value) {
+			return false
+		}
+	}
+	return true
+}
+
+// matchPath reports whether pattern matches the element path.
+func matchPath(pattern string, path []string) bool {
+	anchored := strings.HasPrefix(pattern, "/")
+	steps := strings.Split(strings.TrimPrefix(pattern, "/"), "/")
+	if len(steps) > len(path) || (anchored && len(steps) != len(path)) {
+		return false
+	}
+	tail := path[len(path)-len(steps):]
+	for i, step := range steps {
+		if step != "*" && step != tail[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// elementPath returns the names of the open elements followed by name.
+func elementPath(stack []*Element, name string) []string {
+	path := make([]string, 0, len(stack)+1)
+	for _, el := range stack {
+		path = append(path, el.Name)
+	}
+	return append(path, name)
+}
@@ -0,0 +1,84 @@
+package tokenizer
+
+import (
+	"encoding/xml"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOrderingRules_Match(t *testing.T) {
+	rules, err := LoadOrderingRules("testdata/ordering_rules.json")
+	require.NoError(t, err)
+
+	classAttr := []xml.Attr{{Name: xml.Name{Local: "class"}, Value: "big ordered"}}
+
+	tests := []struct {
+		name    string
+		path    []string
+		attrs   []xml.Attr
+		ordered bool
+		ok      bool
+	}{
+		{"Tag", []string{"Doc", "Section", "List"}, nil, true, true},
+		{"AnchoredPathWinsLater", []string{"Doc", "List"}, nil, false, true},
+		{"Wildcard", []string{"Doc", "Steps", "Step"}, nil, true, true},
+		{"AttributeMember", []string{"Doc", "div"}, classAttr, true, true},
+		{"NoMatch", []string{"Doc", "div"}, nil, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ordered, ok := rules.Match(tt.path, tt.attrs)
+			assert.Equal(t, tt.ok, ok)
+			assert.Equal(t, tt.ordered, ordered)
+		})
+	}
+
+	for _, bad := range []string{
+		`{"rules": [{"ordered": true}]}`,
+		`{"rules": [{"tag": "a", "value": "x"}]}`,
+		`{"rules": [{"path": "a//b"}]}`,
+		`{"rules": [{"tag": "a", "order": true}]}`,
+	} {
+		_, err := ParseOrderingRules(strings.NewReader(bad))
+		assert.Error(t, err, bad)
+	}
+}
+
+func TestOrderingRules_Tokenize(t *testing.T) {
+	vocab := map[string]int{
+		"<Doc>": 200001, "</Doc>": 200002,
+		"<List>": 200003, "</List>": 200004,
+		"<Item>": 200005, "</Item>": 200006,
+		"<Section>": 200007, "</Section>": 200008,
+	}
+	inline := newTypedTokenizer(t, vocab)
+	ruled := newTypedTokenizer(t, vocab, WithOrderingRulesFile("testdata/ordering_rules.json"))
+
+	// Rules give the same encoding as inline attributes, and an inline
+	// attribute wins over the rules.
+	items := `<Item>a</Item><Item>b</Item>`
+	expected, err := inline.Tokenize(strings.NewReader(`<Doc><List>` + items + `</List><Section><List arbor-ordered="true">` + items + `</List><List>` + items + `</List></Section></Doc>`))
+	require.NoError(t, err)
+	input := `<Doc><List>` + items + `</List><Section><List>` + items + `</List><List arbor-ordered="false">` + items + `</List></Section></Doc>`
+	res, err := ruled.Tokenize(strings.NewReader(input))
+	require.NoError(t, err)
+	assert.Equal(t, expected.Tokens, res.Tokens)
+	assert.Equal(t, expected.PaddedPaths, res.PaddedPaths)
+
+	// The tracker applies the tag and path rules, the inline attribute is
+	// not part of the stream.
+	res, err = ruled.Tokenize(strings.NewReader(`<Doc><List>` + items + `</List><Section><List>` + items + `</List></Section></Doc>`))
+	require.NoError(t, err)
+	tracker := ruled.NewPathTracker(nil)
+	for i, id := range res.Tokens {
+		path, err := tracker.Push(id)
+		require.NoError(t, err)
+		assert.Equal(t, trimPadding(res.PaddedPaths[i]), path, "token %d", i)
+	}
+
+	_, err = NewTokenizer(createTempVocab(t, vocab), WithOrderingRulesFile("testdata/missing.json"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,206 @@
+package tokenizer
+
+import "fmt"
+
+type trackerFrame struct {
+	name            string
+	childrenCounter int
+	attrCounter     int
+	ordered         bool
+	pathIndex       int
+}
+
+// PathTracker computes token paths incrementally during autoregressive
+// generation. Each token is assigned the path Encoder.Encode would give it in
+// the finished document, so inference loops can feed structural embeddings
+// step by step.
+type PathTracker struct {
+	vocabInv map[int]string
+	ordered  map[string]bool
+	options  Options
+	stack    []*trackerFrame
+}
+
+// NewPathTracker creates a PathTracker for the given vocab. Unless the vocab
+// has the <__Ordered/> and <__Unordered/> markers, the generated stream carries
+// no arbor-ordered attributes, so ordered maps tag names to the ordering of
+// their children; every other tag gets the ordering of WithDefaultOrdering and
+// WithInheritedOrdering, as in the Encoder. A marker in the stream overrides
+// the map. opts must match the options the Encoder used, such as
+// WithAttributeOrder.
+func NewPathTracker(vocab map[string]int, ordered map[string]bool, opts ...Option) *PathTracker {
+	vocabInv := make(map[int]string, len(vocab))
+	for k, v := range vocab {
+		vocabInv[v] = k
+	}
+	return &PathTracker{
+		vocabInv: vocabInv,
+		ordered:  ordered,
+		options:  newOptions(opts),
+	}
+}
+
+// NewPathTracker creates a PathTracker over the tokenizer vocab.
+func (t *Tokenizer) NewPathTracker(ordered map[string]bool) *PathTracker {
+	return NewPathTracker(t.vocab, ordered, withOptions(t.options))
+}
+
+// Next returns the path the given token would receive if it were emitted
+// next, without consuming it.
+func (p *PathTracker) Next(id int) ([]int, error) {
+	return p.step(id, false)
+}
+
+// Push consumes a token and returns its path.
+func (p *PathTracker) Push(id int) ([]int, error) {
+	return p.step(id, true)
+}
+
+func (p *PathTracker) step(id int, commit bool) ([]int, error) {
+	kind, name := classifyToken(p.vocabInv, id)
+
+	switch kind {
+	case KindStartTag, KindRegisteredAttr, KindUnregisteredAttr, KindKey, KindValue, KindEmpty:
+		var myIndex int
+		if len(p.stack) > 0 {
+			parent := p.stack[len(p.stack)-1]
+			switch kind {
+			case KindRegisteredAttr, KindUnregisteredAttr:
+				// Attributes live in the index 0 bucket of their element,
+				// unless each one has its own index.
+				myIndex = 0
+				if p.options.AttributeOrder == AttributesSourceOrder {
+					myIndex = parent.attrCounter
+					if commit {
+						parent.attrCounter++
+						parent.childrenCounter = max(parent.childrenCounter, parent.attrCounter)
+					}
+				}
+			default:
+				myIndex = parent.childrenCounter
+				if commit && parent.ordered {
+					parent.childrenCounter++
+				}
+			}
+		} else if kind != KindStartTag {
+			return nil, fmt.Errorf("token %d (%s) cannot appear outside the root element", id, kind)
+		}
+
+		path := p.childPath(myIndex)
+		if commit && kind != KindEmpty {
+			// <__Empty/> has no closing token, the Encoder pushes and pops it at once.
+			frame := &trackerFrame{pathIndex: myIndex}
+			switch kind {
+			case KindStartTag:
+				frame.name = name
+				frame.childrenCounter = 1
+				frame.ordered = p.options.DefaultOrdering == ChildrenOrdered
+				if p.options.InheritOrdering && len(p.stack) > 0 {
+					frame.ordered = p.stack[len(p.stack)-1].ordered
+				}
+				if ordered, ok := p.ordered[name]; ok {
+					frame.ordered = ordered
+				}
+				if ordered, ok := p.options.OrderingRules.Match(p.elementPath(name), nil); ok {
+					frame.ordered = ordered
+				}
+			case KindRegisteredAttr, KindUnregisteredAttr, KindValue:
+				frame.ordered = true
+			}
+			p.stack = append(p.stack, frame)
+		}
+		return path, nil
+
+	case KindItem:
+		if len(p.stack) == 0 {
+			return nil, fmt.Errorf("%s token %d cannot appear outside the root element", kind, id)
+		}
+		// Items are members of a set: they all share the first index of the
+		// attribute value.
+		parent := p.stack[len(p.stack)-1]
+		path := p.childPath(parent.childrenCounter)
+		if commit {
+			parent.ordered = false
+			p.stack = append(p.stack, &trackerFrame{pathIndex: parent.childrenCounter})
+		}
+		return path, nil
+
+	case KindEndTag, KindUnregisteredAttrEnd, KindKeyEnd, KindValueEnd, KindTypedValueEnd, KindItemEnd:
+		if len(p.stack) == 0 {
+			return nil, fmt.Errorf("unexpected %s token %d, no open element", kind, id)
+		}
+		popped := p.stack[len(p.stack)-1]
+		parentPath := p.currentPath()[:len(p.stack)-1]
+		path := append(parentPath, popped.pathIndex)
+		if commit {
+			p.stack = p.stack[:len(p.stack)-1]
+		}
+		return path, nil
+
+	case KindOrdered, KindUnordered:
+		if len(p.stack) == 0 {
+			return nil, fmt.Errorf("unexpected %s token %d, no open element", kind, id)
+		}
+		// The marker sits in the attribute bucket and sets the ordering of
+		// the element it follows.
+		path := p.childPath(0)
+		if commit {
+			p.stack[len(p.stack)-1].ordered = kind == KindOrdered
+		}
+		return path, nil
+
+	case KindTypedValue, KindBoolean:
+		if len(p.stack) == 0 {
+			return nil, fmt.Errorf("%s token %d cannot appear outside the root element", kind, id)
+		}
+		parent := p.stack[len(p.stack)-1]
+		index := parent.childrenCounter
+		path := p.childPath(index)
+		if commit {
+			// Typed values stand for content, which is always ordered.
+			parent.childrenCounter++
+			if kind == KindTypedValue {
+				p.stack = append(p.stack, &trackerFrame{pathIndex: index})
+			}
+		}
+		return path, nil
+
+	case KindContent:
+		if len(p.stack) == 0 {
+			return nil, fmt.Errorf("content token %d cannot appear outside the root element", id)
+		}
+		parent := p.stack[len(p.stack)-1]
+		path := p.childPath(parent.childrenCounter)
+		if commit {
+			// Content is always ordered.
+			parent.childrenCounter++
+		}
+		return path, nil
+	}
+
+	return nil, fmt.Errorf("token %d is never emitted by the encoder", id)
+}
+
+// elementPath returns the names of the open elements followed by name.
+func (p *PathTracker) elementPath(name string) []string {
+	var path []string
+	for _, frame := range p.stack {
+		if frame.name != "" {
+			path = append(path, frame.name)
+		}
+	}
+	return append(path, name)
+}
+
+// currentPath returns the path of the innermost open node.
+func (p *PathTracker) currentPath() []int {
+	path := make([]int, len(p.stack))
+	for i, item := range p.stack {
+		path[i] = item.pathIndex
+	}
+	return path
+}
+
+func (p *PathTracker) childPath(index int) []int {
+	return append(p.currentPath(), index)
+}
@@ -0,0 +1,173 @@
+package tokenizer
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// trimPadding strips the -1 padding added by getPaddedPaths.
+func trimPadding(p []int) []int {
+	n := len(p)
+	for n > 0 && p[n-1] == -1 {
+		n--
+	}
+	return p[:n]
+}
+
+func TestPathTracker_MatchesEncoder(t *testing.T) {
+	base := 200000
+	vocab := map[string]int{
+		"<Root>":                base + 1,
+		"</Root>":               base + 2,
+		"<List>":                base + 3,
+		"</List>":               base + 4,
+		"<Item>":                base + 5,
+		"</Item>":               base + 6,
+		"##id":                  base + 100,
+		"##type":                base + 101,
+		"<__UnregisteredAttr>":  base + 200,
+		"</__UnregisteredAttr>": base + 201,
+		"<__Key>":               base + 202,
+		"</__Key>":              base + 203,
+		"<__Value>":             base + 204,
+		"</__Value>":            base + 205,
+		"<__Empty/>":            base + 206,
+	}
+	vocabPath := createTempVocab(t, vocab)
+	defer os.Remove(vocabPath)
+	tokenizer, err := NewTokenizer(vocabPath)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name    string
+		input   string
+		ordered map[string]bool
+	}{
+		{
+			name:  "Unordered",
+			input: `<Root><List><Item>A</Item><Item>B</Item></List></Root>`,
+		},
+		{
+			name:    "Ordered",
+			input:   `<Root arbor-ordered="true"><List arbor-ordered="true"><Item>A</Item><Item>B</Item></List><Item>C</Item></Root>`,
+			ordered: map[string]bool{"Root": true, "List": true},
+		},
+		{
+			name:    "Mixed",
+			input:   `<Root arbor-ordered="true"><List><Item>A</Item><Item>B</Item></List><List><Item>C</Item></List></Root>`,
+			ordered: map[string]bool{"Root": true},
+		},
+		{
+			name:  "Attributes",
+			input: `<Root id="1" type=""><List unknown="val" id="x"><Item>A</Item></List></Root>`,
+		},
+		{
+			name:    "MixedContent",
+			input:   `<Root arbor-ordered="true">text before <Item id="7">A</Item> text after</Root>`,
+			ordered: map[string]bool{"Root": true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := tokenizer.Tokenize(strings.NewReader(tt.input))
+			require.NoError(t, err)
+
+			tracker := tokenizer.NewPathTracker(tt.ordered)
+			for i, id := range res.Tokens {
+				expected := trimPadding(res.PaddedPaths[i])
+
+				next, err := tracker.Next(id)
+				require.NoError(t, err)
+				assert.Equal(t, expected, next, "Next path of token %d", i)
+
+				pushed, err := tracker.Push(id)
+				require.NoError(t, err)
+				assert.Equal(t, expected, pushed, "Push path of token %d", i)
+			}
+		})
+	}
+}
+
+func TestPathTracker_NextDependsOnToken(t *testing.T) {
+	vocab := map[string]int{
+		"<root>":      100,
+		"</root>":     101,
+		"<child>":     102,
+		"</child>":    103,
+		"##attr":      110,
+		TokenValueEnd: 111,
+	}
+	tracker := NewPathTracker(vocab, map[string]bool{"root": true})
+
+	_, err := tracker.Push(100)
+	require.NoError(t, err)
+
+	// The same position yields different paths depending on what comes next.
+	attrPath, err := tracker.Next(110)
+	require.NoError(t, err)
+	assert.Equal(t, []int{0, 0}, attrPath)
+
+	childPath, err := tracker.Next(102)
+	require.NoError(t, err)
+	assert.Equal(t, []int{0, 1}, childPath)
+
+	endPath, err := tracker.Next(101)
+	require.NoError(t, err)
+	assert.Equal(t, []int{0}, endPath)
+
+	// Next does not advance the tracker.
+	childPath, err = tracker.Push(102)
+	require.NoError(t, err)
+	assert.Equal(t, []int{0, 1}, childPath)
+}
+
+func TestPathTracker_Errors(t *testing.T) {
+	vocab := map[string]int{
+		"<root>":            100,
+		"</root>":           101,
+		TokenRegisteredAttr: 102,
+	}
+	tracker := NewPathTracker(vocab, nil)
+
+	_, err := tracker.Push(42)
+	assert.Error(t, err, "content outside root")
+
+	_, err = tracker.Push(101)
+	assert.Error(t, err, "end tag with empty stack")
+
+	_, err = tracker.Push(102)
+	assert.Error(t, err, "token never emitted by the encoder")
+}
+
+func TestPathTracker_OrderingMarkers(t *testing.T) {
+	base := 200000
+	vocab := map[string]int{
+		"<Root>":       base + 1,
+		"</Root>":      base + 2,
+		"<Item>":       base + 5,
+		"</Item>":      base + 6,
+		TokenOrdered:   base + 201,
+		TokenUnordered: base + 202,
+	}
+	vocabPath := createTempVocab(t, vocab)
+	defer os.Remove(vocabPath)
+	tokenizer, err := NewTokenizer(vocabPath)
+	require.NoError(t, err)
+
+	input := `<Root arbor-ordered="true"><Item arbor-ordered="false"><Item>A</Item><Item>B</Item></Item><Item>C</Item></Root>`
+	res, err := tokenizer.Tokenize(strings.NewReader(input))
+	require.NoError(t, err)
+
+	// The map says Item is ordered, the markers in the stream say otherwise.
+	tracker := tokenizer.NewPathTracker(map[string]bool{"Item": true})
+	for i, id := range res.Tokens {
+		path, err := tracker.Push(id)
+		require.NoError(t, err)
+		assert.Equal(t, trimPadding(res.PaddedPaths[i]), path, "token %d", i)
+	}
+}
@@ -0,0 +1,206 @@
+package tokenizer
+
+import "fmt"
+
+// RepairActionKind tells whether a repair removed or added a token.
+type RepairActionKind int
+
+const (
+	// RepairDropped means an input token was removed.
+	RepairDropped RepairActionKind = iota
+	// RepairInserted means a token was added to the output.
+	RepairInserted
+)
+
+func (k RepairActionKind) String() string {
+	if k == RepairInserted {
+		return "inserted"
+	}
+	return "dropped"
+}
+
+// RepairAction describes a single fix applied by RepairTokens.
+type RepairAction struct {
+	Kind RepairActionKind
+	// Index is the position in the input sequence of the dropped token, or the
+	// position before which the token was inserted.
+	Index  int
+	Token  int
+	Reason string
+}
+
+func (a RepairAction) String() string {
+	return fmt.Sprintf("%s token %d at %d: %s", a.Kind, a.Token, a.Index, a.Reason)
+}
+
+// RepairReport lists the fixes applied by RepairTokens, in order.
+type RepairReport struct {
+	Actions []RepairAction
+}
+
+// Changed reports whether any fix was applied.
+func (r *RepairReport) Changed() bool {
+	return len(r.Actions) > 0
+}
+
+// RepairTokens turns a truncated or unbalanced token sequence into one that
+// DecodeXML reads as a well-formed Element. Open attribute wrappers and
+// elements are closed in the right order, unregistered attributes cut off
+// before their key was complete are dropped, and tokens that cannot appear
+// where they are found are removed.
+func (t *Tokenizer) RepairTokens(tokens []int) ([]int, *RepairReport) {
+	r := &repairer{
+		c:         NewConstraint(t.vocab, nil),
+		attrStart: -1,
+		report:    &RepairReport{},
+	}
+
+	for i, id := range tokens {
+		if r.c.Done() {
+			r.drop(i, id, "token after the end of the document")
+			continue
+		}
+		if r.c.Allows(id) {
+			r.consume(i, id)
+			continue
+		}
+
+		// The token may be fine once the pending attribute is closed, e.g. a
+		// child tag following a registered value that lacks </__Value>.
+		if (r.c.state != stateElement || r.c.typedEnd != "") && len(r.c.stack) > 0 {
+			trial := r.clone()
+			trial.closeAttribute(i)
+			if trial.c.Allows(id) {
+				*r = *trial
+				r.consume(i, id)
+				continue
+			}
+		}
+
+		// An end tag for an ancestor closes everything opened since.
+		if kind, name := classifyToken(r.c.vocabInv, id); kind == KindEndTag && r.hasOpen(name) {
+			r.closeAttribute(i)
+			for r.c.stack[len(r.c.stack)-1].name != name {
+				r.closeElement(i)
+			}
+			r.consume(i, id)
+			continue
+		}
+
+		r.drop(i, id, "token not allowed at this position")
+	}
+
+	if len(r.c.stack) > 0 {
+		r.closeAttribute(len(tokens))
+		for len(r.c.stack) > 0 {
+			r.closeElement(len(tokens))
+		}
+	}
+
+	return r.out, r.report
+}
+
+type repairer struct {
+	c      *Constraint
+	out    []int
+	outSrc []int // input index of each output token, -1 if inserted
+	// attrStart is the output index of the pending <__UnregisteredAttr>, or -1.
+	attrStart int
+	report    *RepairReport
+}
+
+func (r *repairer) clone() *repairer {
+	return &repairer{
+		c:         r.c.clone(),
+		out:       append([]int(nil), r.out...),
+		outSrc:    append([]int(nil), r.outSrc...),
+		attrStart: r.attrStart,
+		report:    &RepairReport{Actions: append([]RepairAction(nil), r.report.Actions...)},
+	}
+}
+
+func (r *repairer) consume(index, id int) {
+	if kind, _ := classifyToken(r.c.vocabInv, id); kind == KindUnregisteredAttr {
+		r.attrStart = len(r.out)
+	}
+	// Callers check Allows first, so Consume cannot fail here.
+	_ = r.c.Consume(id)
+	r.out = append(r.out, id)
+	r.outSrc = append(r.outSrc, index)
+	if r.c.state == stateElement {
+		r.attrStart = -1
+	}
+}
+
+func (r *repairer) insert(index int, tok string, reason string) {
+	id, ok := r.c.vocab[tok]
+	if !ok {
+		return
+	}
+	r.consume(-1, id)
+	r.report.Actions = append(r.report.Actions, RepairAction{Kind: RepairInserted, Index: index, Token: id, Reason: reason})
+}
+
+func (r *repairer) drop(index, id int, reason string) {
+	r.report.Actions = append(r.report.Actions, RepairAction{Kind: RepairDropped, Index: index, Token: id, Reason: reason})
+}
+
+// closeAttribute completes, or removes, the attribute being emitted so the
+// constraint is back directly inside the current element.
+func (r *repairer) closeAttribute(index int) {
+	switch r.c.typedEnd {
+	case "":
+	case TokenItemEnd:
+		r.insert(index, TokenItemEnd, "closed set item")
+	default:
+		r.insert(index, r.c.typedEnd, "closed typed value")
+	}
+	switch r.c.state {
+	case stateRegisteredValue, stateRegisteredEmpty:
+		if _, ok := r.c.vocab[TokenValueEnd]; ok {
+			r.insert(index, TokenValueEnd, "closed attribute value")
+		} else {
+			r.c.state = stateElement
+		}
+	case stateUnregisteredStart, stateKey, stateKeyContent:
+		for i := r.attrStart; i < len(r.out); i++ {
+			if r.outSrc[i] >= 0 {
+				r.drop(r.outSrc[i], r.out[i], "dangling attribute key")
+			}
+		}
+		r.out = r.out[:r.attrStart]
+		r.outSrc = r.outSrc[:r.attrStart]
+		r.attrStart = -1
+		r.c.state = stateElement
+	case stateAfterKey:
+		r.insert(index, TokenValue, "opened missing attribute value")
+		r.insert(index, TokenValueEnd, "closed attribute value")
+		r.insert(index, TokenUnregisteredAttrEnd, "closed attribute")
+	case stateUnregisteredValue:
+		r.insert(index, TokenValueEnd, "closed attribute value")
+		r.insert(index, TokenUnregisteredAttrEnd, "closed attribute")
+	case stateAfterValue:
+		r.insert(index, TokenUnregisteredAttrEnd, "closed attribute")
+	}
+}
+
+func (r *repairer) closeElement(index int) {
+	tok := "</" + r.c.stack[len(r.c.stack)-1].name + ">"
+	if _, ok := r.c.vocab[tok]; ok {
+		r.insert(index, tok, "closed open element")
+		return
+	}
+	// The Transformer rejects such tags, but a generated stream may still
+	// open one. It cannot be closed explicitly, so only the state is unwound.
+	r.c.stack = r.c.stack[:len(r.c.stack)-1]
+	r.c.done = len(r.c.stack) == 0
+}
+
+func (r *repairer) hasOpen(name string) bool {
+	for _, frame := range r.c.stack {
+		if frame.name == name {
+			return true
+		}
+	}
+	return false
+}
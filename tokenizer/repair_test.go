@@ -0,0 +1,154 @@
+package tokenizer
+
+import (
+	"testing"
+
+	"github.com/pkoukk/tiktoken-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRepairTokens(t *testing.T) {
+	tk, err := tiktoken.GetEncoding("cl100k_base")
+	require.NoError(t, err)
+
+	vocab := createConstraintVocab()
+	vocabInv := make(map[int]string)
+	for k, v := range vocab {
+		vocabInv[v] = k
+	}
+	tokenizer := &Tokenizer{
+		vocab:            vocab,
+		vocabInv:         vocabInv,
+		contentTokenizer: tk,
+	}
+
+	key := tk.Encode("key", nil, nil)[0]
+	val := tk.Encode("val", nil, nil)[0]
+
+	tests := []struct {
+		name     string
+		input    []int
+		expected []int
+		dropped  int
+		inserted int
+	}{
+		{
+			name:     "Valid_Unchanged",
+			input:    []int{100, 110, val, 125, 102, val, 103, 101},
+			expected: []int{100, 110, val, 125, 102, val, 103, 101},
+		},
+		{
+			name:     "Truncated_Element",
+			input:    []int{100, 102, val},
+			expected: []int{100, 102, val, 103, 101},
+			inserted: 2,
+		},
+		{
+			name:     "Truncated_RegisteredValue",
+			input:    []int{100, 110, val},
+			expected: []int{100, 110, val, 125, 101},
+			inserted: 2,
+		},
+		{
+			name:     "Dangling_Key",
+			input:    []int{100, 120, 122, key},
+			expected: []int{100, 101},
+			dropped:  3,
+			inserted: 1,
+		},
+		{
+			name:     "Truncated_After_Key",
+			input:    []int{100, 120, 122, key, 123},
+			expected: []int{100, 120, 122, key, 123, 124, 125, 121, 101},
+			inserted: 4,
+		},
+		{
+			name:     "Truncated_UnregisteredValue",
+			input:    []int{100, 120, 122, key, 123, 124, val},
+			expected: []int{100, 120, 122, key, 123, 124, val, 125, 121, 101},
+			inserted: 3,
+		},
+		{
+			name:     "Missing_ValueEnd_Before_Child",
+			input:    []int{100, 110, val, 102, 103, 101},
+			expected: []int{100, 110, val, 125, 102, 103, 101},
+			inserted: 1,
+		},
+		{
+			name:     "Ancestor_End_Closes_Children",
+			input:    []int{100, 102, 104, val, 101},
+			expected: []int{100, 102, 104, val, 105, 103, 101},
+			inserted: 2,
+		},
+		{
+			name:     "Unmatched_End_Dropped",
+			input:    []int{100, 103, val, 101},
+			expected: []int{100, val, 101},
+			dropped:  1,
+		},
+		{
+			name:     "Tokens_After_End_Dropped",
+			input:    []int{100, 101, 102, val},
+			expected: []int{100, 101},
+			dropped:  2,
+		},
+		{
+			name:     "Content_Before_Root_Dropped",
+			input:    []int{val, 100, 101},
+			expected: []int{100, 101},
+			dropped:  1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repaired, report := tokenizer.RepairTokens(tt.input)
+			assert.Equal(t, tt.expected, repaired)
+
+			var dropped, inserted int
+			for _, action := range report.Actions {
+				switch action.Kind {
+				case RepairDropped:
+					dropped++
+				case RepairInserted:
+					inserted++
+				}
+			}
+			assert.Equal(t, tt.dropped, dropped, "dropped: %v", report.Actions)
+			assert.Equal(t, tt.inserted, inserted, "inserted: %v", report.Actions)
+			assert.Equal(t, tt.dropped+tt.inserted > 0, report.Changed())
+
+			c := tokenizer.NewConstraint(nil)
+			for _, id := range repaired {
+				require.NoError(t, c.Consume(id))
+			}
+			assert.True(t, c.Done())
+
+			el, err := tokenizer.DecodeXML(repaired)
+			require.NoError(t, err)
+			require.NotNil(t, el)
+			assert.Equal(t, "root", el.Name)
+		})
+	}
+}
+
+func TestRepairTokens_DanglingKeyReport(t *testing.T) {
+	vocab := createConstraintVocab()
+	vocabInv := make(map[int]string)
+	for k, v := range vocab {
+		vocabInv[v] = k
+	}
+	tokenizer := &Tokenizer{vocab: vocab, vocabInv: vocabInv}
+
+	_, report := tokenizer.RepairTokens([]int{100, 102, 120, 122, 42})
+	require.Len(t, report.Actions, 5)
+
+	for i, index := range []int{2, 3, 4} {
+		assert.Equal(t, RepairDropped, report.Actions[i].Kind)
+		assert.Equal(t, index, report.Actions[i].Index)
+		assert.Equal(t, "dangling attribute key", report.Actions[i].Reason)
+	}
+	assert.Equal(t, RepairAction{Kind: RepairInserted, Index: 5, Token: 103, Reason: "closed open element"}, report.Actions[3])
+	assert.Equal(t, RepairAction{Kind: RepairInserted, Index: 5, Token: 101, Reason: "closed open element"}, report.Actions[4])
+}
@@ -0,0 +1,571 @@
+package tokenizer
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Schema is the content model of a document type, read from an XSD or a DTD
+// with LoadSchema. Names are local names: namespaces are ignored, as in the
+// Encoder.
+type Schema struct {
+	// Roots lists the elements allowed as the document element. It is empty
+	// for DTDs, which do not declare one.
+	Roots []string
+	// Elements maps element names to their declarations. When local
+	// declarations share a name, the first one wins.
+	Elements map[string]*ElementDecl
+}
+
+// ElementDecl declares the attributes and content of an element.
+type ElementDecl struct {
+	Name       string
+	Attributes []AttributeDecl
+	// AnyAttribute allows attributes that are not declared.
+	AnyAttribute bool
+	// Content is the model of the child elements, nil when the element has
+	// no child elements.
+	Content *Particle
+	// Text allows character data: simple content or mixed content.
+	Text bool
+	// Any allows any content, such as DTD ANY or xs:anyType.
+	Any bool
+
+	// nfa is the automaton of Content, built on first use and shared by the
+	// matchers of the element, so Content must not change after that.
+	nfaOnce sync.Once
+	nfa     *contentNFA
+}
+
+// AttributeDecl declares an attribute of an element.
+type AttributeDecl struct {
+	Name     string
+	Required bool
+}
+
+// ParticleKind is the kind of a content model particle.
+type ParticleKind int
+
+const (
+	// ParticleElement is a child element, named by Particle.Name.
+	ParticleElement ParticleKind = iota
+	// ParticleSequence is xs:sequence or a DTD "," group.
+	ParticleSequence
+	// ParticleChoice is xs:choice or a DTD "|" group.
+	ParticleChoice
+	// ParticleAll is xs:all: each child at most once, in any order.
+	ParticleAll
+	// ParticleAny is xs:any: any element.
+	ParticleAny
+)
+
+// Unbounded is the Particle.Max of a particle that may repeat without limit.
+const Unbounded = -1
+
+// maxExpandedOccurs bounds the repetitions expanded when matching content.
+// Larger maxOccurs values are treated as unbounded.
+const maxExpandedOccurs = 64
+
+// Particle is a node of a content model.
+type Particle struct {
+	Kind     ParticleKind
+	Name     string
+	Children []*Particle
+	// Min and Max are the occurrence bounds, Max is Unbounded or at least
+	// Min. A Max of 0, as XSD restrictions use to remove a particle, matches
+	// no occurrence.
+	Min int
+	Max int
+}
+
+// LoadSchema reads an XSD or a DTD, chosen by the file extension.
+func LoadSchema(path string) (*Schema, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open schema file: %w", err)
+	}
+	defer f.Close()
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".xsd":
+		return ParseXSD(f)
+	case ".dtd":
+		return ParseDTD(f)
+	}
+	return nil, fmt.Errorf("unknown schema format %q (expected .xsd or .dtd)", filepath.Ext(path))
+}
+
+// ElementNames returns the declared element names in ascending order.
+func (s *Schema) ElementNames() []string {
+	names := make([]string, 0, len(s.Elements))
+	for name := range s.Elements {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Vocab assigns IDs from startID to the tokens of every declared element and
+// attribute, plus </__Value> and <__Empty/> which registered attributes need.
+// Elements accepting undeclared attributes also bring the unregistered
+// attribute tokens. IDs follow the sorted names, so the same schema always
+// gives the same vocab.
+func (s *Schema) Vocab(startID int) map[string]int {
+	vocab := make(map[string]int)
+	id := startID
+	add := func(tok string) {
+		if _, ok := vocab[tok]; !ok {
+			vocab[tok] = id
+			id++
+		}
+	}
+
+	var attrs []string
+	anyAttribute := false
+	for _, name := range s.ElementNames() {
+		add("<" + name + ">")
+		add("</" + name + ">")
+		decl := s.Elements[name]
+		for _, a := range decl.Attributes {
+			attrs = append(attrs, a.Name)
+		}
+		anyAttribute = anyAttribute || decl.AnyAttribute || decl.Any
+	}
+	sort.Strings(attrs)
+	for _, a := range attrs {
+		add("##" + a)
+	}
+
+	add(TokenValueEnd)
+	add(TokenEmpty)
+	if anyAttribute {
+		for _, tok := range []string{TokenUnregisteredAttr, TokenUnregisteredAttrEnd, TokenKey, TokenKeyEnd, TokenValue} {
+			add(tok)
+		}
+	}
+	return vocab
+}
+
+// OrderingRules returns a rule per element whose content is an xs:all group
+// (unordered) or a sequence (ordered). Other elements keep the default.
+func (s *Schema) OrderingRules() *OrderingRules {
+	rules := &OrderingRules{}
+	for _, name := range s.ElementNames() {
+		content := s.Elements[name].Content
+		if content == nil {
+			continue
+		}
+		switch content.Kind {
+		case ParticleAll:
+			rules.Rules = append(rules.Rules, OrderingRule{Tag: name, Ordered: false})
+		case ParticleSequence:
+			rules.Rules = append(rules.Rules, OrderingRule{Tag: name, Ordered: true})
+		}
+	}
+	return rules
+}
+
+// ConstraintSchema returns a ConstraintSchema applying the content models
+// of the schema to constrained generation.
+func (s *Schema) ConstraintSchema() *ConstraintSchema {
+	return &ConstraintSchema{Roots: s.Roots, Model: s}
+}
+
+// ValidationError is a schema violation at a position of the document.
+type ValidationError struct {
+	Line    int
+	Column  int
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("line %d, column %d: %s", e.Line, e.Column, e.Message)
+}
+
+// ValidationErrors lists the violations found by Schema.Validate, in
+// document order.
+type ValidationErrors []*ValidationError
+
+func (e ValidationErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
+// Validate checks a document against the schema before tokenization. It
+// returns ValidationErrors listing every violation, or the XML syntax error
+// that stopped reading. Namespaced attributes, xmlns and arbor-* directives
+// are ignored.
+func (s *Schema) Validate(r io.Reader) error {
+	type frame struct {
+		decl    *ElementDecl // nil below undeclared elements and ANY content
+		matcher contentMatcher
+	}
+
+	dec := xml.NewDecoder(r)
+	var stack []*frame
+	var errs ValidationErrors
+	seenRoot := false
+
+	for {
+		line, col := dec.InputPos()
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		report := func(format string, args ...interface{}) {
+			errs = append(errs, &ValidationError{Line: line, Column: col, Message: fmt.Sprintf(format, args...)})
+		}
+
+		switch se := tok.(type) {
+		case xml.StartElement:
+			name := se.Name.Local
+			lax := false
+			if len(stack) == 0 {
+				if seenRoot {
+					report("unexpected second document element <%s>", name)
+				}
+				seenRoot = true
+				if len(s.Roots) > 0 && !slices.Contains(s.Roots, name) {
+					report("element <%s> is not allowed as document element, expected one of %s", name, strings.Join(s.Roots, ", "))
+				}
+			} else if parent := stack[len(stack)-1]; parent.decl == nil || parent.decl.Any {
+				lax = parent.decl == nil || s.Elements[name] == nil
+			} else if !parent.matcher.next(name) {
+				report("element <%s> is not allowed here in <%s>%s", name, parent.decl.Name, expectedSuffix(parent.matcher))
+			}
+
+			decl := s.Elements[name]
+			if decl == nil {
+				if !lax {
+					report("element <%s> is not declared", name)
+				}
+				stack = append(stack, &frame{})
+				continue
+			}
+			for _, msg := range decl.checkAttributes(se.Attr) {
+				report("%s", msg)
+			}
+			stack = append(stack, &frame{decl: decl, matcher: decl.newMatcher()})
+
+		case xml.EndElement:
+			top := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			if top.decl != nil && !top.matcher.canClose() {
+				report("element <%s> is incomplete%s", top.decl.Name, expectedSuffix(top.matcher))
+			}
+
+		case xml.CharData:
+			if len(stack) == 0 || strings.TrimSpace(string(se)) == "" {
+				continue
+			}
+			if top := stack[len(stack)-1]; top.decl != nil && !top.decl.Text && !top.decl.Any {
+				report("text is not allowed in <%s>", top.decl.Name)
+			}
+		}
+	}
+
+	if !seenRoot {
+		return ValidationErrors{{Line: 1, Column: 1, Message: "no document element"}}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// checkAttributes returns a message per undeclared or missing attribute.
+func (d *ElementDecl) checkAttributes(attrs []xml.Attr) []string {
+	var msgs []string
+	present := make(map[string]bool, len(attrs))
+	for _, a := range attrs {
+		if a.Name.Space != "" || a.Name.Local == "xmlns" || isDirective(a.Name.Local) {
+			continue
+		}
+		present[a.Name.Local] = true
+		if !d.AnyAttribute && !slices.ContainsFunc(d.Attributes, func(decl AttributeDecl) bool { return decl.Name == a.Name.Local }) {
+			msgs = append(msgs, fmt.Sprintf("attribute %q is not declared for <%s>", a.Name.Local, d.Name))
+		}
+	}
+	for _, a := range d.Attributes {
+		if a.Required && !present[a.Name] {
+			msgs = append(msgs, fmt.Sprintf("required attribute %q is missing on <%s>", a.Name, d.Name))
+		}
+	}
+	return msgs
+}
+
+func expectedSuffix(m contentMatcher) string {
+	allowed := m.allowed()
+	if len(allowed) == 0 {
+		return ", no child element expected"
+	}
+	return ", expected one of " + strings.Join(allowed, ", ")
+}
+
+// contentMatcher follows the children of an element through its content
+// model, one child name at a time.
+type contentMatcher interface {
+	// next consumes name and reports whether it was allowed. A rejected name
+	// leaves the matcher unchanged.
+	next(name string) bool
+	// allowed returns the names that may come next in ascending order, "*"
+	// standing for any element.
+	allowed() []string
+	// canClose reports whether the content is complete.
+	canClose() bool
+	// clone returns an independent copy of the matcher.
+	clone() contentMatcher
+}
+
+func (d *ElementDecl) newMatcher() contentMatcher {
+	switch {
+	case d.Any:
+		return anyMatcher{}
+	case d.Content == nil:
+		return emptyMatcher{}
+	case d.Content.Kind == ParticleAll:
+		return &allMatcher{group: d.Content, counts: make(map[string]int)}
+	}
+	d.nfaOnce.Do(func() { d.nfa = newContentNFA(d.Content) })
+	return &nfaMatcher{nfa: d.nfa, states: d.nfa.start}
+}
+
+// anyMatcher accepts any children.
+type anyMatcher struct{}
+
+func (anyMatcher) next(string) bool  { return true }
+func (anyMatcher) allowed() []string { return []string{"*"} }
+func (anyMatcher) canClose() bool    { return true }
+
+func (m anyMatcher) clone() contentMatcher { return m }
+
+// emptyMatcher accepts no children.
+type emptyMatcher struct{}
+
+func (emptyMatcher) next(string) bool  { return false }
+func (emptyMatcher) allowed() []string { return nil }
+func (emptyMatcher) canClose() bool    { return true }
+
+func (m emptyMatcher) clone() contentMatcher { return m }
+
+// allMatcher follows an xs:all group, whose children each appear at most
+// once in any order.
+type allMatcher struct {
+	group  *Particle
+	counts map[string]int
+	total  int
+}
+
+func (m *allMatcher) next(name string) bool {
+	for _, p := range m.group.Children {
+		if p.Name == name && m.counts[name] < allLimit(p) {
+			m.counts[name]++
+			m.total++
+			return true
+		}
+	}
+	return false
+}
+
+// allLimit is the number of times the child p of an xs:all group may appear:
+// at most once.
+func allLimit(p *Particle) int {
+	if p.Max == Unbounded {
+		return 1
+	}
+	return min(p.Max, 1)
+}
+
+func (m *allMatcher) allowed() []string {
+	var names []string
+	for _, p := range m.group.Children {
+		if m.counts[p.Name] < allLimit(p) {
+			names = append(names, p.Name)
+		}
+	}
+	sort.Strings(names)
+	return slices.Compact(names)
+}
+
+func (m *allMatcher) canClose() bool {
+	// An optional group may be left out entirely.
+	if m.total == 0 && m.group.Min == 0 {
+		return true
+	}
+	for _, p := range m.group.Children {
+		if m.counts[p.Name] < p.Min {
+			return false
+		}
+	}
+	return true
+}
+
+func (m *allMatcher) clone() contentMatcher {
+	counts := make(map[string]int, len(m.counts))
+	for k, v := range m.counts {
+		counts[k] = v
+	}
+	return &allMatcher{group: m.group, counts: counts, total: m.total}
+}
+
+type nfaEdge struct {
+	name string // "*" matches any element
+	to   int
+}
+
+// contentNFA is a nondeterministic automaton over child element names, built
+// from a content model by Thompson's construction.
+type contentNFA struct {
+	edges  [][]nfaEdge
+	eps    [][]int
+	accept int
+	// start is the epsilon closure of the start state.
+	start []int
+}
+
+func newContentNFA(p *Particle) *contentNFA {
+	n := &contentNFA{}
+	start := n.add()
+	n.accept = n.build(p, start)
+	n.start = n.closure([]int{start})
+	return n
+}
+
+func (n *contentNFA) add() int {
+	n.edges = append(n.edges, nil)
+	n.eps = append(n.eps, nil)
+	return len(n.edges) - 1
+}
+
+// build adds p after state from, with its occurrence bounds, and returns the
+// state reached after it.
+func (n *contentNFA) build(p *Particle, from int) int {
+	minOccurs, maxOccurs := min(p.Min, maxExpandedOccurs), p.Max
+	if maxOccurs > maxExpandedOccurs {
+		maxOccurs = Unbounded
+	}
+
+	cur := from
+	for i := 0; i < minOccurs; i++ {
+		cur = n.buildOnce(p, cur)
+	}
+	if maxOccurs == Unbounded {
+		loop := n.add()
+		n.eps[cur] = append(n.eps[cur], loop)
+		end := n.buildOnce(p, loop)
+		n.eps[end] = append(n.eps[end], loop)
+		return loop
+	}
+	for i := minOccurs; i < maxOccurs; i++ {
+		end := n.buildOnce(p, cur)
+		join := n.add()
+		n.eps[cur] = append(n.eps[cur], join)
+		n.eps[end] = append(n.eps[end], join)
+		cur = join
+	}
+	return cur
+}
+
+func (n *contentNFA) buildOnce(p *Particle, from int) int {
+	switch p.Kind {
+	case ParticleElement, ParticleAny:
+		name := p.Name
+		if p.Kind == ParticleAny {
+			name = "*"
+		}
+		to := n.add()
+		n.edges[from] = append(n.edges[from], nfaEdge{name: name, to: to})
+		return to
+	case ParticleChoice:
+		end := n.add()
+		for _, c := range p.Children {
+			e := n.build(c, from)
+			n.eps[e] = append(n.eps[e], end)
+		}
+		return end
+	}
+	// Sequences, and xs:all groups nested where XSD does not allow them.
+	cur := from
+	for _, c := range p.Children {
+		cur = n.build(c, cur)
+	}
+	return cur
+}
+
+// closure returns the states reachable from states through epsilon moves.
+func (n *contentNFA) closure(states []int) []int {
+	seen := make(map[int]bool, len(states))
+	var out []int
+	var visit func(s int)
+	visit = func(s int) {
+		if seen[s] {
+			return
+		}
+		seen[s] = true
+		out = append(out, s)
+		for _, t := range n.eps[s] {
+			visit(t)
+		}
+	}
+	for _, s := range states {
+		visit(s)
+	}
+	sort.Ints(out)
+	return out
+}
+
+// nfaMatcher follows a content model with a set of NFA states. The states
+// are replaced, never modified, as they start out shared with the NFA.
+type nfaMatcher struct {
+	nfa    *contentNFA
+	states []int
+}
+
+func (m *nfaMatcher) next(name string) bool {
+	var reached []int
+	for _, s := range m.states {
+		for _, e := range m.nfa.edges[s] {
+			if e.name == name || e.name == "*" {
+				reached = append(reached, e.to)
+			}
+		}
+	}
+	if len(reached) == 0 {
+		return false
+	}
+	m.states = m.nfa.closure(reached)
+	return true
+}
+
+func (m *nfaMatcher) allowed() []string {
+	var names []string
+	for _, s := range m.states {
+		for _, e := range m.nfa.edges[s] {
+			names = append(names, e.name)
+		}
+	}
+	sort.Strings(names)
+	return slices.Compact(names)
+}
+
+func (m *nfaMatcher) canClose() bool {
+	return slices.Contains(m.states, m.nfa.accept)
+}
+
+func (m *nfaMatcher) clone() contentMatcher {
+	// next replaces states instead of modifying it.
+	return &nfaMatcher{nfa: m.nfa, states: m.states}
+}
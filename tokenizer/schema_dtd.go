@@ -0,0 +1,301 @@
+package tokenizer
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+)
+
+// ParseDTD reads the <!ELEMENT> and <!ATTLIST> declarations of a DTD.
+// Comments, processing instructions, entity and notation declarations are
+// skipped; parameter entities are not supported. A DTD does not name the
+// document element, so Schema.Roots is empty.
+func ParseDTD(r io.Reader) (*Schema, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	src := string(data)
+	schema := &Schema{Elements: make(map[string]*ElementDecl)}
+	var attlists []string
+
+	for {
+		start := strings.Index(src, "<")
+		if start < 0 {
+			break
+		}
+		src = src[start:]
+		switch {
+		case strings.HasPrefix(src, "<!--"):
+			end := strings.Index(src, "-->")
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated comment in DTD")
+			}
+			src = src[end+3:]
+			continue
+		case strings.HasPrefix(src, "<?"):
+			end := strings.Index(src, "?>")
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated processing instruction in DTD")
+			}
+			src = src[end+2:]
+			continue
+		}
+
+		end := declarationEnd(src)
+		if end < 0 {
+			return nil, fmt.Errorf("unterminated declaration in DTD: %.40s", src)
+		}
+		decl := src[:end]
+		src = src[end+1:]
+		if hasParameterEntity(decl) {
+			return nil, fmt.Errorf("parameter entities are not supported: %.40s", decl)
+		}
+
+		switch {
+		case strings.HasPrefix(decl, "<!ELEMENT"):
+			el, err := parseDTDElement(strings.TrimSpace(decl[len("<!ELEMENT"):]))
+			if err != nil {
+				return nil, err
+			}
+			if _, ok := schema.Elements[el.Name]; ok {
+				return nil, fmt.Errorf("element %s is declared twice", el.Name)
+			}
+			schema.Elements[el.Name] = el
+		case strings.HasPrefix(decl, "<!ATTLIST"):
+			attlists = append(attlists, strings.TrimSpace(decl[len("<!ATTLIST"):]))
+		}
+	}
+
+	// Attribute lists may come before the element declaration.
+	for _, list := range attlists {
+		if err := parseDTDAttlist(schema, list); err != nil {
+			return nil, err
+		}
+	}
+	return schema, nil
+}
+
+// declarationEnd returns the index of the ">" closing the declaration at the
+// start of src, skipping quoted strings.
+func declarationEnd(src string) int {
+	var quote rune
+	for i, r := range src {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '\'':
+			quote = r
+		case r == '>':
+			return i
+		}
+	}
+	return -1
+}
+
+// hasParameterEntity reports whether decl uses or declares a parameter
+// entity, that is has a "%" outside quoted strings.
+func hasParameterEntity(decl string) bool {
+	var quote rune
+	for _, r := range decl {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '\'':
+			quote = r
+		case r == '%':
+			return true
+		}
+	}
+	return false
+}
+
+func parseDTDElement(s string) (*ElementDecl, error) {
+	i := strings.IndexFunc(s, unicode.IsSpace)
+	if i < 0 {
+		return nil, fmt.Errorf("element declaration %q has no content specification", s)
+	}
+	el := &ElementDecl{Name: s[:i]}
+	spec := strings.Join(strings.Fields(s[i:]), "")
+
+	switch {
+	case spec == "EMPTY":
+		return el, nil
+	case spec == "ANY":
+		el.Any = true
+		el.Text = true
+		return el, nil
+	case strings.HasPrefix(spec, "(#PCDATA"):
+		// Mixed content: (#PCDATA) or (#PCDATA|a|b)*
+		el.Text = true
+		inner := strings.TrimSuffix(strings.TrimSuffix(spec, "*"), ")")
+		names := strings.Split(strings.TrimPrefix(inner, "(#PCDATA"), "|")[1:]
+		if len(names) == 0 {
+			return el, nil
+		}
+		choice := &Particle{Kind: ParticleChoice, Min: 0, Max: Unbounded}
+		for _, name := range names {
+			choice.Children = append(choice.Children, &Particle{Kind: ParticleElement, Name: name, Min: 1, Max: 1})
+		}
+		el.Content = choice
+		return el, nil
+	}
+
+	p := &dtdContentParser{spec: spec}
+	content, err := p.group()
+	if err != nil {
+		return nil, fmt.Errorf("element %s: %w", el.Name, err)
+	}
+	if p.pos != len(p.spec) {
+		return nil, fmt.Errorf("element %s: unexpected %q in content model", el.Name, p.spec[p.pos:])
+	}
+	el.Content = content
+	return el, nil
+}
+
+// dtdContentParser reads a DTD content model with whitespace removed, such as
+// (head,(p|list)*,foot?).
+type dtdContentParser struct {
+	spec string
+	pos  int
+}
+
+func (p *dtdContentParser) group() (*Particle, error) {
+	if p.pos >= len(p.spec) || p.spec[p.pos] != '(' {
+		return nil, fmt.Errorf("expected ( in content model at %q", p.spec[p.pos:])
+	}
+	p.pos++
+
+	group := &Particle{Kind: ParticleSequence}
+	var sep byte
+	for {
+		child, err := p.particle()
+		if err != nil {
+			return nil, err
+		}
+		group.Children = append(group.Children, child)
+
+		if p.pos >= len(p.spec) {
+			return nil, fmt.Errorf("unterminated group in content model")
+		}
+		c := p.spec[p.pos]
+		p.pos++
+		if c == ')' {
+			break
+		}
+		if c != ',' && c != '|' {
+			return nil, fmt.Errorf("unexpected %q in content model", c)
+		}
+		if sep != 0 && c != sep {
+			return nil, fmt.Errorf("mixed , and | in one group of the content model")
+		}
+		sep = c
+	}
+	if sep == '|' {
+		group.Kind = ParticleChoice
+	}
+	group.Min, group.Max = p.occurrence()
+	return group, nil
+}
+
+func (p *dtdContentParser) particle() (*Particle, error) {
+	if p.pos < len(p.spec) && p.spec[p.pos] == '(' {
+		return p.group()
+	}
+	start := p.pos
+	for p.pos < len(p.spec) && !strings.ContainsRune("(),|?*+", rune(p.spec[p.pos])) {
+		p.pos++
+	}
+	if p.pos == start {
+		return nil, fmt.Errorf("expected a name in content model at %q", p.spec[start:])
+	}
+	particle := &Particle{Kind: ParticleElement, Name: p.spec[start:p.pos]}
+	particle.Min, particle.Max = p.occurrence()
+	return particle, nil
+}
+
+func (p *dtdContentParser) occurrence() (int, int) {
+	if p.pos < len(p.spec) {
+		switch p.spec[p.pos] {
+		case '?':
+			p.pos++
+			return 0, 1
+		case '*':
+			p.pos++
+			return 0, Unbounded
+		case '+':
+			p.pos++
+			return 1, Unbounded
+		}
+	}
+	return 1, 1
+}
+
+// parseDTDAttlist adds the attributes of an attribute list declaration to
+// their element. Lists for undeclared elements are ignored.
+func parseDTDAttlist(schema *Schema, s string) error {
+	fields := dtdFields(s)
+	if len(fields) == 0 {
+		return fmt.Errorf("empty attribute list declaration")
+	}
+	el, ok := schema.Elements[fields[0]]
+	fields = fields[1:]
+
+	for len(fields) > 0 {
+		if len(fields) < 3 {
+			return fmt.Errorf("incomplete attribute declaration %q", strings.Join(fields, " "))
+		}
+		name, typ, def := fields[0], fields[1], fields[2]
+		fields = fields[3:]
+		if typ == "NOTATION" {
+			// NOTATION (a|b) has its enumeration as a separate field.
+			if len(fields) == 0 {
+				return fmt.Errorf("incomplete attribute declaration %q", strings.Join([]string{name, typ, def}, " "))
+			}
+			def, fields = fields[0], fields[1:]
+		}
+		if def == "#FIXED" {
+			if len(fields) == 0 {
+				return fmt.Errorf("attribute %s has #FIXED without a value", name)
+			}
+			fields = fields[1:]
+		}
+		if ok {
+			el.Attributes = append(el.Attributes, AttributeDecl{Name: name, Required: def == "#REQUIRED"})
+		}
+	}
+	return nil
+}
+
+// dtdFields splits a declaration on whitespace, keeping quoted strings and
+// parenthesized enumerations whole.
+func dtdFields(s string) []string {
+	var fields []string
+	for {
+		s = strings.TrimLeftFunc(s, unicode.IsSpace)
+		if s == "" {
+			return fields
+		}
+		end := strings.IndexFunc(s, unicode.IsSpace)
+		switch s[0] {
+		case '"', '\'':
+			if i := strings.IndexByte(s[1:], s[0]); i >= 0 {
+				end = i + 2
+			}
+		case '(':
+			if i := strings.IndexByte(s, ')'); i >= 0 {
+				end = i + 1
+			}
+		}
+		if end < 0 {
+			end = len(s)
+		}
+		fields = append(fields, s[:end])
+		s = s[end:]
+	}
+}
@@ -0,0 +1,326 @@
+package tokenizer
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSchema_Load(t *testing.T) {
+	for _, path := range []string{"testdata/library.xsd", "testdata/library.dtd"} {
+		t.Run(path, func(t *testing.T) {
+			schema, err := LoadSchema(path)
+			require.NoError(t, err)
+
+			assert.Equal(t, []string{"Author", "Book", "Library", "Name", "Title", "Year"}, schema.ElementNames())
+			library := schema.Elements["Library"]
+			assert.Equal(t, []AttributeDecl{{Name: "id", Required: true}}, library.Attributes)
+			assert.Equal(t, ParticleSequence, library.Content.Kind)
+			assert.False(t, library.Text)
+			assert.True(t, schema.Elements["Title"].Text)
+			assert.Nil(t, schema.Elements["Title"].Content)
+
+			book := schema.Elements["Book"]
+			assert.Equal(t, []AttributeDecl{{Name: "isbn"}, {Name: "lang"}}, book.Attributes)
+			assert.Len(t, book.Content.Children, 3)
+		})
+	}
+
+	xsd, err := LoadSchema("testdata/library.xsd")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Library", "Book"}, xsd.Roots)
+	assert.Equal(t, ParticleAll, xsd.Elements["Book"].Content.Kind)
+
+	dtd, err := LoadSchema("testdata/library.dtd")
+	require.NoError(t, err)
+	assert.Empty(t, dtd.Roots)
+	assert.Equal(t, ParticleChoice, dtd.Elements["Book"].Content.Kind)
+	assert.Equal(t, Unbounded, dtd.Elements["Book"].Content.Max)
+
+	_, err = LoadSchema("testdata/ordering_rules.json")
+	assert.Error(t, err)
+}
+
+func TestSchema_ParseErrors(t *testing.T) {
+	for _, bad := range []string{
+		`<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema"><xs:element name="A"><xs:complexType><xs:sequence><xs:element ref="B"/></xs:sequence></xs:complexType></xs:element></xs:schema>`,
+		`<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema"><xs:element name="A"><xs:complexType><xs:group ref="G"/></xs:complexType></xs:element></xs:schema>`,
+		`<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema"><xs:element name="A"><xs:complexType><xs:sequence><xs:element name="B" maxOccurs="many"/></xs:sequence></xs:complexType></xs:element></xs:schema>`,
+		`<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema"><xs:element name="A"><xs:complexType><xs:sequence><xs:element name="B" minOccurs="2" maxOccurs="1"/></xs:sequence></xs:complexType></xs:element></xs:schema>`,
+		`<root/>`,
+	} {
+		_, err := ParseXSD(strings.NewReader(bad))
+		assert.Error(t, err, bad)
+	}
+
+	for _, bad := range []string{
+		`<!ELEMENT A (B,C|D)>`,
+		`<!ELEMENT A (B,C>`,
+		`<!ELEMENT A>`,
+		`<!ELEMENT A EMPTY><!ELEMENT A ANY>`,
+		`<!ENTITY % inline "B"><!ELEMENT A (%inline;)>`,
+		`<!ELEMENT A EMPTY><!ATTLIST A id CDATA>`,
+		`<!ELEMENT A EMPTY><!ATTLIST A n NOTATION (x|y)>`,
+		`<!ELEMENT A EMPTY><!ATTLIST A w CDATA %width;>`,
+	} {
+		_, err := ParseDTD(strings.NewReader(bad))
+		assert.Error(t, err, bad)
+	}
+}
+
+func TestSchema_RecursiveXSD(t *testing.T) {
+	schema, err := ParseXSD(strings.NewReader(`<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema">
+  <xs:element name="Tree" type="NodeType"/>
+  <xs:complexType name="NodeType">
+    <xs:sequence>
+      <xs:element name="Node" type="NodeType" minOccurs="0" maxOccurs="unbounded"/>
+    </xs:sequence>
+    <xs:attribute name="id"/>
+  </xs:complexType>
+  <xs:complexType name="LeafType">
+    <xs:complexContent>
+      <xs:extension base="NodeType">
+        <xs:sequence><xs:element name="Value" type="xs:int"/></xs:sequence>
+      </xs:extension>
+    </xs:complexContent>
+  </xs:complexType>
+</xs:schema>`))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Node", "Tree"}, schema.ElementNames())
+	assert.NoError(t, schema.Validate(strings.NewReader(`<Tree id="1"><Node><Node id="2"/></Node><Node/></Tree>`)))
+}
+
+func TestSchema_GroupRef(t *testing.T) {
+	schema, err := ParseXSD(strings.NewReader(`<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema">
+  <xs:element name="root">
+    <xs:complexType><xs:group ref="pair"/></xs:complexType>
+  </xs:element>
+  <xs:element name="list">
+    <xs:complexType>
+      <xs:sequence><xs:group ref="either" maxOccurs="2"/></xs:sequence>
+    </xs:complexType>
+  </xs:element>
+  <xs:group name="pair">
+    <xs:all><xs:element name="a"/><xs:element name="b"/></xs:all>
+  </xs:group>
+  <xs:group name="either">
+    <xs:choice><xs:element name="a"/><xs:element name="b"/></xs:choice>
+  </xs:group>
+</xs:schema>`))
+	require.NoError(t, err)
+
+	// The referenced model keeps its kind and takes the bounds of the
+	// reference.
+	assert.Equal(t, ParticleAll, schema.Elements["root"].Content.Kind)
+	either := schema.Elements["list"].Content.Children[0]
+	assert.Equal(t, ParticleChoice, either.Kind)
+	assert.Equal(t, [2]int{1, 2}, [2]int{either.Min, either.Max})
+
+	assert.Equal(t, []OrderingRule{{Tag: "list", Ordered: true}, {Tag: "root", Ordered: false}}, schema.OrderingRules().Rules)
+	assert.NoError(t, schema.Validate(strings.NewReader(`<root><b/><a/></root>`)))
+	assert.NoError(t, schema.Validate(strings.NewReader(`<list><b/><a/></list>`)))
+	assert.EqualError(t, schema.Validate(strings.NewReader(`<list><b/><a/><a/></list>`)),
+		`line 1, column 15: element <a> is not allowed here in <list>, no child element expected`)
+}
+
+func TestSchema_MaxOccursZero(t *testing.T) {
+	schema, err := ParseXSD(strings.NewReader(`<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema">
+  <xs:element name="seq">
+    <xs:complexType>
+      <xs:sequence><xs:element name="a"/><xs:element name="b" minOccurs="0" maxOccurs="0"/></xs:sequence>
+    </xs:complexType>
+  </xs:element>
+  <xs:element name="all">
+    <xs:complexType>
+      <xs:all><xs:element name="a"/><xs:element name="b" minOccurs="0" maxOccurs="0"/></xs:all>
+    </xs:complexType>
+  </xs:element>
+</xs:schema>`))
+	require.NoError(t, err)
+
+	// A particle with a maxOccurs of 0 never occurs.
+	for _, name := range []string{"seq", "all"} {
+		assert.NoError(t, schema.Validate(strings.NewReader("<"+name+"><a/></"+name+">")), name)
+		assert.Error(t, schema.Validate(strings.NewReader("<"+name+"><a/><b/></"+name+">")), name)
+	}
+}
+
+func TestSchema_MatcherReuse(t *testing.T) {
+	schema, err := LoadSchema("testdata/library.dtd")
+	require.NoError(t, err)
+	library := schema.Elements["Library"]
+
+	// The matchers of an element share its automaton but not their states.
+	first, second := library.newMatcher().(*nfaMatcher), library.newMatcher().(*nfaMatcher)
+	assert.Same(t, first.nfa, second.nfa)
+	require.True(t, first.next("Name"))
+	assert.False(t, second.canClose())
+	assert.Equal(t, []string{"Name"}, second.allowed())
+}
+
+func TestSchema_DTDQuotedPercent(t *testing.T) {
+	schema, err := ParseDTD(strings.NewReader(`<!ELEMENT a EMPTY><!ATTLIST a w CDATA "100%" s CDATA '%x;'>`))
+	require.NoError(t, err)
+	assert.Equal(t, []AttributeDecl{{Name: "w"}, {Name: "s"}}, schema.Elements["a"].Attributes)
+}
+
+func TestSchema_Vocab(t *testing.T) {
+	schema, err := LoadSchema("testdata/library.xsd")
+	require.NoError(t, err)
+
+	vocab := schema.Vocab(200000)
+	assert.Equal(t, map[string]int{
+		"<Author>": 200000, "</Author>": 200001,
+		"<Book>": 200002, "</Book>": 200003,
+		"<Library>": 200004, "</Library>": 200005,
+		"<Name>": 200006, "</Name>": 200007,
+		"<Title>": 200008, "</Title>": 200009,
+		"<Year>": 200010, "</Year>": 200011,
+		"##id": 200012, "##isbn": 200013, "##lang": 200014,
+		TokenValueEnd: 200015, TokenEmpty: 200016,
+	}, vocab)
+
+	// The DTD declares the same elements and attributes.
+	dtd, err := LoadSchema("testdata/library.dtd")
+	require.NoError(t, err)
+	assert.Equal(t, vocab, dtd.Vocab(200000))
+}
+
+func TestSchema_Tokenize(t *testing.T) {
+	schema, err := LoadSchema("testdata/library.xsd")
+	require.NoError(t, err)
+	assert.Equal(t, []OrderingRule{
+		{Tag: "Book", Ordered: false},
+		{Tag: "Library", Ordered: true},
+	}, schema.OrderingRules().Rules)
+
+	tokenizer := newTypedTokenizer(t, schema.Vocab(200000), WithOrderingRules(schema.OrderingRules()))
+	doc, err := os.ReadFile("testdata/library.xml")
+	require.NoError(t, err)
+	require.NoError(t, schema.Validate(strings.NewReader(string(doc))))
+
+	res, err := tokenizer.Tokenize(strings.NewReader(string(doc)))
+	require.NoError(t, err)
+
+	// The children of Book share their index, the children of Library do not.
+	vocab := tokenizer.vocab
+	pathOf := func(tok string, nth int) []int {
+		for i, id := range res.Tokens {
+			if id == vocab[tok] {
+				if nth == 0 {
+					return trimPadding(res.PaddedPaths[i])
+				}
+				nth--
+			}
+		}
+		t.Fatalf("token %s not found", tok)
+		return nil
+	}
+	assert.Equal(t, []int{0, 2}, pathOf("<Book>", 0))
+	assert.Equal(t, []int{0, 3}, pathOf("<Book>", 1))
+	title, author := pathOf("<Title>", 0), pathOf("<Author>", 0)
+	assert.Equal(t, title[:len(title)-1], author[:len(author)-1])
+	assert.Equal(t, title[len(title)-1], author[len(author)-1])
+
+	tracker := tokenizer.NewPathTracker(nil)
+	for i, id := range res.Tokens {
+		path, err := tracker.Push(id)
+		require.NoError(t, err)
+		assert.Equal(t, trimPadding(res.PaddedPaths[i]), path, "token %d", i)
+	}
+}
+
+func TestSchema_Validate(t *testing.T) {
+	doc, err := os.ReadFile("testdata/library_invalid.xml")
+	require.NoError(t, err)
+
+	tests := []struct {
+		schema   string
+		expected []string
+	}{
+		{
+			schema: "testdata/library.xsd",
+			expected: []string{
+				`line 1, column 1: required attribute "id" is missing on <Library>`,
+				`line 2, column 3: element <Book> is not allowed here in <Library>, expected one of Name`,
+				`line 2, column 3: attribute "shelf" is not declared for <Book>`,
+				`line 4, column 5: element <Title> is not allowed here in <Book>, expected one of Author, Year`,
+				`line 5, column 3: element <Book> is incomplete, expected one of Author, Year`,
+				`line 7, column 3: element <Magazine> is not allowed here in <Library>, expected one of Book`,
+				`line 7, column 3: element <Magazine> is not declared`,
+			},
+		},
+		{
+			schema: "testdata/library.dtd",
+			expected: []string{
+				`line 1, column 1: required attribute "id" is missing on <Library>`,
+				`line 2, column 3: element <Book> is not allowed here in <Library>, expected one of Name`,
+				`line 2, column 3: attribute "shelf" is not declared for <Book>`,
+				`line 7, column 3: element <Magazine> is not allowed here in <Library>, expected one of Book`,
+				`line 7, column 3: element <Magazine> is not declared`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.schema, func(t *testing.T) {
+			schema, err := LoadSchema(tt.schema)
+			require.NoError(t, err)
+
+			err = schema.Validate(strings.NewReader(string(doc)))
+			var errs ValidationErrors
+			require.True(t, errors.As(err, &errs), "got %v", err)
+			msgs := make([]string, len(errs))
+			for i, e := range errs {
+				msgs[i] = e.Error()
+			}
+			assert.Equal(t, tt.expected, msgs)
+		})
+	}
+
+	schema, err := LoadSchema("testdata/library.xsd")
+	require.NoError(t, err)
+	assert.Error(t, schema.Validate(strings.NewReader(`<Name>unclosed`)))
+
+	err = schema.Validate(strings.NewReader(`<Title>x</Title>`))
+	assert.EqualError(t, err, `line 1, column 1: element <Title> is not allowed as document element, expected one of Library, Book`)
+
+	err = schema.Validate(strings.NewReader("<Book>\n  <Title>x</Title>\n  <Author>y<Title/></Author>\n</Book>"))
+	assert.EqualError(t, err, `line 3, column 12: element <Title> is not allowed here in <Author>, no child element expected`)
+}
+
+func TestSchema_ValidateAnyType(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "any.xsd")
+	require.NoError(t, os.WriteFile(path, []byte(`<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema">
+  <xs:element name="Doc">
+    <xs:complexType>
+      <xs:sequence>
+        <xs:element name="Meta"/>
+        <xs:element name="Data" type="xs:anyType"/>
+        <xs:element name="Note" type="xs:string"/>
+      </xs:sequence>
+    </xs:complexType>
+  </xs:element>
+</xs:schema>`), 0644))
+	schema, err := LoadSchema(path)
+	require.NoError(t, err)
+
+	// An element without a type and one of type xs:anyType both accept any
+	// attributes, text and children.
+	for _, name := range []string{"Meta", "Data"} {
+		decl := schema.Elements[name]
+		assert.True(t, decl.Any, name)
+		assert.True(t, decl.AnyAttribute, name)
+		assert.True(t, decl.Text, name)
+	}
+	assert.NoError(t, schema.Validate(strings.NewReader(
+		`<Doc><Meta lang="en">x<b/></Meta><Data id="1">y<c k="v"/></Data><Note>z</Note></Doc>`)))
+
+	err = schema.Validate(strings.NewReader(`<Doc><Meta/><Data/><Note lang="en">z</Note></Doc>`))
+	assert.EqualError(t, err, `line 1, column 20: attribute "lang" is not declared for <Note>`)
+}
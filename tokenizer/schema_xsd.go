@@ -0,0 +1,335 @@
+package tokenizer
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// xsdNode is an element of an XSD document.
+type xsdNode struct {
+	XMLName  xml.Name
+	Attrs    []xml.Attr `xml:",any,attr"`
+	Children []*xsdNode `xml:",any"`
+}
+
+func (n *xsdNode) attr(name string) string {
+	for _, a := range n.Attrs {
+		if a.Name.Local == name {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+func (n *xsdNode) children(name string) []*xsdNode {
+	var out []*xsdNode
+	for _, c := range n.Children {
+		if c.XMLName.Local == name {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+// xsdParser resolves the global declarations of an XSD.
+type xsdParser struct {
+	schema          *Schema
+	elements        map[string]*xsdNode
+	types           map[string]*xsdNode
+	groups          map[string]*xsdNode
+	attributeGroups map[string]*xsdNode
+	expanding       map[*xsdNode]bool
+}
+
+// ParseXSD reads an XML Schema. Global elements become Schema.Roots. Named
+// and anonymous complex types, groups, attribute groups, complexContent
+// extensions and simpleContent are supported; imports, substitution groups
+// and identity constraints are not. Built-in and simple types are text.
+func ParseXSD(r io.Reader) (*Schema, error) {
+	var root xsdNode
+	if err := xml.NewDecoder(r).Decode(&root); err != nil {
+		return nil, fmt.Errorf("failed to parse XSD: %w", err)
+	}
+	if root.XMLName.Local != "schema" {
+		return nil, fmt.Errorf("expected xs:schema document element, got %s", root.XMLName.Local)
+	}
+
+	p := &xsdParser{
+		schema:          &Schema{Elements: make(map[string]*ElementDecl)},
+		elements:        make(map[string]*xsdNode),
+		types:           make(map[string]*xsdNode),
+		groups:          make(map[string]*xsdNode),
+		attributeGroups: make(map[string]*xsdNode),
+		expanding:       make(map[*xsdNode]bool),
+	}
+	for _, c := range root.Children {
+		name := c.attr("name")
+		switch c.XMLName.Local {
+		case "element":
+			p.elements[name] = c
+			p.schema.Roots = append(p.schema.Roots, name)
+		case "complexType":
+			p.types[name] = c
+		case "group":
+			p.groups[name] = c
+		case "attributeGroup":
+			p.attributeGroups[name] = c
+		}
+	}
+
+	// Global declarations win over local ones with the same name.
+	for _, name := range p.schema.Roots {
+		p.schema.Elements[name] = &ElementDecl{Name: name}
+	}
+	for _, name := range p.schema.Roots {
+		if err := p.fillElement(p.schema.Elements[name], p.elements[name]); err != nil {
+			return nil, err
+		}
+	}
+	return p.schema, nil
+}
+
+// localName strips the namespace prefix of a QName.
+func localName(qname string) string {
+	if i := strings.IndexByte(qname, ':'); i >= 0 {
+		return qname[i+1:]
+	}
+	return qname
+}
+
+// declareElement returns the declaration of an element node, creating it
+// for local elements seen for the first time.
+func (p *xsdParser) declareElement(n *xsdNode) (string, error) {
+	if ref := n.attr("ref"); ref != "" {
+		name := localName(ref)
+		if _, ok := p.elements[name]; !ok {
+			return "", fmt.Errorf("element ref %q is not a global element", ref)
+		}
+		return name, nil
+	}
+	name := n.attr("name")
+	if name == "" {
+		return "", fmt.Errorf("element without name or ref")
+	}
+	if _, ok := p.schema.Elements[name]; ok {
+		return name, nil
+	}
+	decl := &ElementDecl{Name: name}
+	p.schema.Elements[name] = decl
+	return name, p.fillElement(decl, n)
+}
+
+// fillElement reads the type of an element declaration.
+func (p *xsdParser) fillElement(decl *ElementDecl, n *xsdNode) error {
+	typ := localName(n.attr("type"))
+	if t, ok := p.types[typ]; ok {
+		return p.fillComplexType(decl, t)
+	}
+	if ct := n.children("complexType"); typ == "" && len(ct) > 0 {
+		return p.fillComplexType(decl, ct[0])
+	}
+	// Built-in and simple types.
+	decl.Text = true
+	if typ == "anyType" || (typ == "" && len(n.children("simpleType")) == 0) {
+		// No type is xs:anyType.
+		decl.Any = true
+		decl.AnyAttribute = true
+	}
+	return nil
+}
+
+func (p *xsdParser) fillComplexType(decl *ElementDecl, n *xsdNode) error {
+	if n.attr("mixed") == "true" {
+		decl.Text = true
+	}
+	for _, c := range n.Children {
+		switch c.XMLName.Local {
+		case "sequence", "choice", "all", "group":
+			particle, err := p.particle(c)
+			if err != nil {
+				return err
+			}
+			decl.Content = particle
+		case "attribute", "attributeGroup", "anyAttribute":
+			if err := p.addAttributes(decl, c); err != nil {
+				return err
+			}
+		case "simpleContent":
+			decl.Text = true
+			for _, d := range c.Children {
+				for _, a := range d.Children {
+					if err := p.addAttributes(decl, a); err != nil {
+						return err
+					}
+				}
+			}
+		case "complexContent":
+			if c.attr("mixed") == "true" {
+				decl.Text = true
+			}
+			if err := p.fillComplexContent(decl, c); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// fillComplexContent reads an extension or a restriction of a named type.
+// Extensions append their content to the content of the base type,
+// restrictions restate it. Attributes are inherited in both cases.
+func (p *xsdParser) fillComplexContent(decl *ElementDecl, n *xsdNode) error {
+	for _, d := range n.Children {
+		if d.XMLName.Local != "extension" && d.XMLName.Local != "restriction" {
+			continue
+		}
+		if base, ok := p.types[localName(d.attr("base"))]; ok {
+			if p.expanding[base] {
+				return fmt.Errorf("type %q derives from itself", d.attr("base"))
+			}
+			p.expanding[base] = true
+			err := p.fillComplexType(decl, base)
+			delete(p.expanding, base)
+			if err != nil {
+				return err
+			}
+		}
+		baseContent := decl.Content
+		decl.Content = nil
+		if err := p.fillComplexType(decl, d); err != nil {
+			return err
+		}
+		switch {
+		case d.XMLName.Local == "restriction":
+		case baseContent != nil && decl.Content != nil:
+			decl.Content = &Particle{Kind: ParticleSequence, Children: []*Particle{baseContent, decl.Content}, Min: 1, Max: 1}
+		case decl.Content == nil:
+			decl.Content = baseContent
+		}
+	}
+	return nil
+}
+
+func (p *xsdParser) addAttributes(decl *ElementDecl, n *xsdNode) error {
+	switch n.XMLName.Local {
+	case "anyAttribute":
+		decl.AnyAttribute = true
+	case "attribute":
+		name := n.attr("name")
+		if ref := n.attr("ref"); ref != "" {
+			name = localName(ref)
+		}
+		if name == "" || n.attr("use") == "prohibited" {
+			return nil
+		}
+		for i, a := range decl.Attributes {
+			if a.Name == name {
+				decl.Attributes[i].Required = n.attr("use") == "required"
+				return nil
+			}
+		}
+		decl.Attributes = append(decl.Attributes, AttributeDecl{Name: name, Required: n.attr("use") == "required"})
+	case "attributeGroup":
+		group, ok := p.attributeGroups[localName(n.attr("ref"))]
+		if !ok {
+			return fmt.Errorf("attribute group %q is not declared", n.attr("ref"))
+		}
+		if p.expanding[group] {
+			return fmt.Errorf("attribute group %q references itself", n.attr("ref"))
+		}
+		p.expanding[group] = true
+		defer delete(p.expanding, group)
+		for _, c := range group.Children {
+			if err := p.addAttributes(decl, c); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// particle reads a content model node with its occurrence bounds.
+func (p *xsdParser) particle(n *xsdNode) (*Particle, error) {
+	minOccurs, maxOccurs, err := occurs(n)
+	if err != nil {
+		return nil, err
+	}
+
+	switch n.XMLName.Local {
+	case "element":
+		name, err := p.declareElement(n)
+		if err != nil {
+			return nil, err
+		}
+		return &Particle{Kind: ParticleElement, Name: name, Min: minOccurs, Max: maxOccurs}, nil
+	case "any":
+		return &Particle{Kind: ParticleAny, Min: minOccurs, Max: maxOccurs}, nil
+	case "group":
+		group, ok := p.groups[localName(n.attr("ref"))]
+		if !ok {
+			return nil, fmt.Errorf("group %q is not declared", n.attr("ref"))
+		}
+		if p.expanding[group] {
+			return nil, fmt.Errorf("group %q references itself", n.attr("ref"))
+		}
+		p.expanding[group] = true
+		defer delete(p.expanding, group)
+		for _, c := range group.Children {
+			switch c.XMLName.Local {
+			case "sequence", "choice", "all":
+				// The model of a group has no bounds of its own, the
+				// reference gives them.
+				inner, err := p.particle(c)
+				if err != nil {
+					return nil, err
+				}
+				inner.Min, inner.Max = minOccurs, maxOccurs
+				return inner, nil
+			}
+		}
+		return nil, fmt.Errorf("group %q has no content model", n.attr("ref"))
+	}
+
+	kind := map[string]ParticleKind{"sequence": ParticleSequence, "choice": ParticleChoice, "all": ParticleAll}[n.XMLName.Local]
+	particle := &Particle{Kind: kind, Min: minOccurs, Max: maxOccurs}
+	for _, c := range n.Children {
+		switch c.XMLName.Local {
+		case "element", "any", "group", "sequence", "choice", "all":
+			child, err := p.particle(c)
+			if err != nil {
+				return nil, err
+			}
+			particle.Children = append(particle.Children, child)
+		}
+	}
+	return particle, nil
+}
+
+// occurs reads minOccurs and maxOccurs, which default to 1. maxOccurs may be
+// 0 to remove a particle.
+func occurs(n *xsdNode) (int, int, error) {
+	minOccurs, maxOccurs := 1, 1
+	if v := n.attr("minOccurs"); v != "" {
+		i, err := strconv.Atoi(v)
+		if err != nil || i < 0 {
+			return 0, 0, fmt.Errorf("invalid minOccurs %q", v)
+		}
+		minOccurs = i
+	}
+	if v := n.attr("maxOccurs"); v == "unbounded" {
+		maxOccurs = Unbounded
+	} else if v != "" {
+		i, err := strconv.Atoi(v)
+		if err != nil || i < 0 {
+			return 0, 0, fmt.Errorf("invalid maxOccurs %q", v)
+		}
+		maxOccurs = i
+	}
+	if maxOccurs != Unbounded && minOccurs > maxOccurs {
+		return 0, 0, fmt.Errorf("minOccurs %d is greater than maxOccurs %d", minOccurs, maxOccurs)
+	}
+	return minOccurs, maxOccurs, nil
+}
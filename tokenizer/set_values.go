@@ -0,0 +1,58 @@
+package tokenizer
+
+import (
+	"encoding/xml"
+	"slices"
+	"strings"
+)
+
+// DefaultSetAttributes lists the HTML attributes whose value is an unordered
+// set of space-separated tokens.
+var DefaultSetAttributes = []string{
+	"class",
+	"rel",
+	"rev",
+	"sandbox",
+	"headers",
+	"ping",
+	"itemprop",
+	"itemref",
+	"itemtype",
+	"aria-controls",
+	"aria-describedby",
+	"aria-labelledby",
+	"aria-owns",
+}
+
+// setValue returns the <__Value> element holding the members of a
+// set-valued attribute, or nil if the attribute is not set-valued, its value
+// is blank or the vocab lacks <__Item> and </__Item>.
+func (t *Transformer) setValue(tag, attr, value string) *Element {
+	if !t.isSetAttribute(tag, attr) || !t.hasTokens(TokenItem, TokenItemEnd) {
+		return nil
+	}
+	fields := strings.Fields(value)
+	if len(fields) == 0 {
+		return nil
+	}
+
+	valEl := &Element{
+		Name:       strings.Trim(TokenValue, "<>"),
+		Attributes: []xml.Attr{orderedAttr(false)},
+	}
+	itemName := strings.Trim(TokenItem, "<>")
+	seen := make(map[string]bool, len(fields))
+	for _, f := range fields {
+		if seen[f] {
+			continue
+		}
+		seen[f] = true
+		valEl.Children = append(valEl.Children, &Element{Name: itemName, Children: []interface{}{f}})
+	}
+	return valEl
+}
+
+func (t *Transformer) isSetAttribute(tag, attr string) bool {
+	return slices.Contains(t.options.SetAttributes, attr) ||
+		slices.Contains(t.options.SetAttributes, tag+"@"+attr)
+}
@@ -0,0 +1,162 @@
+package tokenizer
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func createSetVocab() map[string]int {
+	base := 200000
+	return map[string]int{
+		"<div>":                  base + 1,
+		"</div>":                 base + 2,
+		"<a>":                    base + 3,
+		"</a>":                   base + 4,
+		"##class":                base + 100,
+		"##id":                   base + 101,
+		TokenUnregisteredAttr:    base + 200,
+		TokenUnregisteredAttrEnd: base + 201,
+		TokenKey:                 base + 202,
+		TokenKeyEnd:              base + 203,
+		TokenValue:               base + 204,
+		TokenValueEnd:            base + 205,
+		TokenEmpty:               base + 206,
+		TokenItem:                base + 300,
+		TokenItemEnd:             base + 301,
+	}
+}
+
+func TestSetAttributes_Transform(t *testing.T) {
+	vocab := createSetVocab()
+
+	tests := []struct {
+		name     string
+		input    string
+		names    []string
+		expected string
+	}{
+		{
+			name:     "Registered",
+			input:    `<div class=" btn  btn-primary btn "></div>`,
+			expected: `<div><__RegisteredAttr><__Key>class</__Key><__Value arbor-ordered="false"><__Item>btn</__Item><__Item>btn-primary</__Item></__Value></__RegisteredAttr></div>`,
+		},
+		{
+			name:     "Unregistered",
+			input:    `<div><a rel="nofollow noopener">x</a></div>`,
+			expected: `<div><a><__UnregisteredAttr><__Key>rel</__Key><__Value arbor-ordered="false"><__Item>nofollow</__Item><__Item>noopener</__Item></__Value></__UnregisteredAttr>x</a></div>`,
+		},
+		{
+			name:     "Blank",
+			input:    `<div class=""></div>`,
+			expected: `<div><__RegisteredAttr><__Key>class</__Key><__Value><__Empty></__Empty></__Value></__RegisteredAttr></div>`,
+		},
+		{
+			name:     "NotSetValued",
+			input:    `<div id="a b"></div>`,
+			expected: `<div><__RegisteredAttr><__Key>id</__Key><__Value>a b</__Value></__RegisteredAttr></div>`,
+		},
+		{
+			name:     "ScopedName",
+			input:    `<div><a class="x y" data-tags="p q"></a></div>`,
+			names:    []string{"a@data-tags"},
+			expected: `<div><a><__RegisteredAttr><__Key>class</__Key><__Value>x y</__Value></__RegisteredAttr><__UnregisteredAttr><__Key>data-tags</__Key><__Value arbor-ordered="false"><__Item>p</__Item><__Item>q</__Item></__Value></__UnregisteredAttr></a></div>`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transformer := NewTransformer(vocab, WithSetAttributes(tt.names...))
+			el, err := transformer.Transform(strings.NewReader(tt.input))
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, el.String())
+		})
+	}
+}
+
+func TestSetAttributes_MissingTokens(t *testing.T) {
+	vocab := createSetVocab()
+	delete(vocab, TokenItemEnd)
+
+	transformer := NewTransformer(vocab, WithSetAttributes())
+	el, err := transformer.Transform(strings.NewReader(`<div class="a b"></div>`))
+	require.NoError(t, err)
+	assert.Equal(t, `<div><__RegisteredAttr><__Key>class</__Key><__Value>a b</__Value></__RegisteredAttr></div>`, el.String())
+}
+
+func TestSetAttributes_Tokenize(t *testing.T) {
+	tokenizer := newTypedTokenizer(t, createSetVocab(), WithSetAttributes())
+
+	encode := func(input string) []string {
+		res, err := tokenizer.Tokenize(strings.NewReader(input))
+		require.NoError(t, err)
+
+		// Paths are the ones the tracker derives from the stream alone.
+		tracker := tokenizer.NewPathTracker(nil)
+		c := tokenizer.NewConstraint(nil)
+		var pairs []string
+		for i, id := range res.Tokens {
+			path, err := tracker.Push(id)
+			require.NoError(t, err)
+			assert.Equal(t, trimPadding(res.PaddedPaths[i]), path, "token %d", i)
+			require.NoError(t, c.Consume(id), "token %d", i)
+			pairs = append(pairs, fmt.Sprint(id, path))
+		}
+		assert.True(t, c.Done())
+		sort.Strings(pairs)
+		return pairs
+	}
+
+	// Reordering the members only permutes the (token, path) pairs.
+	a := encode(`<div class="btn active" id="x"><a rel="nofollow noopener">go</a></div>`)
+	b := encode(`<div class="active btn" id="x"><a rel="noopener nofollow">go</a></div>`)
+	assert.Equal(t, a, b)
+
+	res, err := tokenizer.Tokenize(strings.NewReader(`<div class="btn  active"><a rel="nofollow noopener">go</a></div>`))
+	require.NoError(t, err)
+	decoded, err := tokenizer.DecodeXML(res.Tokens)
+	require.NoError(t, err)
+	assert.Equal(t, `<div class="btn active"><a rel="nofollow noopener">go</a></div>`, decoded.String())
+}
+
+func TestSetAttributes_Constraint(t *testing.T) {
+	vocab := createSetVocab()
+	c := NewConstraint(vocab, nil)
+	require.NoError(t, c.Consume(vocab["<div>"]))
+	require.NoError(t, c.Consume(vocab["##class"]))
+	assert.True(t, c.Allows(vocab[TokenItem]))
+
+	require.NoError(t, c.Consume(vocab[TokenItem]))
+	allowed := c.Allowed()
+	assert.True(t, allowed.Content)
+	assert.Equal(t, []int{vocab[TokenItemEnd]}, allowed.IDs)
+
+	require.NoError(t, c.Consume(16))
+	require.NoError(t, c.Consume(vocab[TokenItemEnd]))
+	// A set holds only items.
+	assert.False(t, c.Allows(16))
+	assert.False(t, c.Allows(vocab[TokenEmpty]))
+	require.NoError(t, c.Consume(vocab[TokenItem]))
+	require.NoError(t, c.Consume(17))
+	require.NoError(t, c.Consume(vocab[TokenItemEnd]))
+	require.NoError(t, c.Consume(vocab[TokenValueEnd]))
+
+	// A text value takes no items.
+	require.NoError(t, c.Consume(vocab["##id"]))
+	require.NoError(t, c.Consume(16))
+	assert.False(t, c.Allows(vocab[TokenItem]))
+}
+
+func TestSetAttributes_Repair(t *testing.T) {
+	tokenizer := newTypedTokenizer(t, createSetVocab(), WithSetAttributes())
+	vocab := tokenizer.vocab
+
+	repaired, report := tokenizer.RepairTokens([]int{vocab["<div>"], vocab["##class"], vocab[TokenItem], 17})
+	assert.Equal(t, []int{vocab["<div>"], vocab["##class"], vocab[TokenItem], 17, vocab[TokenItemEnd], vocab[TokenValueEnd], vocab["</div>"]}, repaired)
+	require.NotEmpty(t, report.Actions)
+	assert.Equal(t, "closed set item", report.Actions[0].Reason)
+}
@@ -0,0 +1,148 @@
+package tokenizer
+
+import "strings"
+
+// TokenKind describes the structural role a token ID plays in an encoded sequence.
+type TokenKind int
+
+const (
+	// KindContent is a text token produced by the content tokenizer.
+	KindContent TokenKind = iota
+	// KindStartTag opens an element, e.g. <City>.
+	KindStartTag
+	// KindEndTag closes an element, e.g. </City>.
+	KindEndTag
+	// KindRegisteredAttr starts a registered attribute, e.g. ##name.
+	KindRegisteredAttr
+	// KindUnregisteredAttr is <__UnregisteredAttr>.
+	KindUnregisteredAttr
+	// KindUnregisteredAttrEnd is </__UnregisteredAttr>.
+	KindUnregisteredAttrEnd
+	// KindKey is <__Key>.
+	KindKey
+	// KindKeyEnd is </__Key>.
+	KindKeyEnd
+	// KindValue is <__Value>.
+	KindValue
+	// KindValueEnd is </__Value>.
+	KindValueEnd
+	// KindEmpty is <__Empty/>.
+	KindEmpty
+	// KindOrdered is <__Ordered/>, restoring arbor-ordered="true".
+	KindOrdered
+	// KindUnordered is <__Unordered/>, restoring arbor-ordered="false".
+	KindUnordered
+	// KindTypedValue opens a typed value, <__Number>, <__Date> or <__RawText>.
+	KindTypedValue
+	// KindTypedValueEnd closes a typed value.
+	KindTypedValueEnd
+	// KindBoolean is <__True/> or <__False/>.
+	KindBoolean
+	// KindItem is <__Item>, a member of a set-valued attribute.
+	KindItem
+	// KindItemEnd is </__Item>.
+	KindItemEnd
+	// KindUnknown is an ID the Encoder never emits, such as <__RegisteredAttr>
+	// or an ID outside both the vocab and the content tokenizer range.
+	KindUnknown
+)
+
+var tokenKindNames = map[TokenKind]string{
+	KindContent:             "content",
+	KindStartTag:            "start",
+	KindEndTag:              "end",
+	KindRegisteredAttr:      "attr",
+	KindUnregisteredAttr:    "unregistered-attr",
+	KindUnregisteredAttrEnd: "unregistered-attr-end",
+	KindKey:                 "key",
+	KindKeyEnd:              "key-end",
+	KindValue:               "value",
+	KindValueEnd:            "value-end",
+	KindEmpty:               "empty",
+	KindOrdered:             "ordered",
+	KindUnordered:           "unordered",
+	KindTypedValue:          "typed",
+	KindTypedValueEnd:       "typed-end",
+	KindBoolean:             "boolean",
+	KindItem:                "item",
+	KindItemEnd:             "item-end",
+	KindUnknown:             "unknown",
+}
+
+func (k TokenKind) String() string {
+	if name, ok := tokenKindNames[k]; ok {
+		return name
+	}
+	return "unknown"
+}
+
+// TokenKind returns the structural role of the given token ID.
+func (t *Tokenizer) TokenKind(id int) TokenKind {
+	kind, _ := classifyToken(t.vocabInv, id)
+	return kind
+}
+
+// classifyToken returns the kind of a token ID together with the element or
+// attribute name it carries, if any.
+func classifyToken(vocabInv map[int]string, id int) (TokenKind, string) {
+	s, ok := vocabInv[id]
+	if !ok {
+		if id >= 0 && id < Cl100kBaseMaxID {
+			return KindContent, ""
+		}
+		return KindUnknown, ""
+	}
+
+	switch s {
+	case TokenUnregisteredAttr:
+		return KindUnregisteredAttr, ""
+	case TokenUnregisteredAttrEnd:
+		return KindUnregisteredAttrEnd, ""
+	case TokenKey:
+		return KindKey, ""
+	case TokenKeyEnd:
+		return KindKeyEnd, ""
+	case TokenValue:
+		return KindValue, ""
+	case TokenValueEnd:
+		return KindValueEnd, ""
+	case TokenEmpty:
+		return KindEmpty, ""
+	case TokenOrdered:
+		return KindOrdered, ""
+	case TokenUnordered:
+		return KindUnordered, ""
+	case TokenNumber, TokenDate, TokenRawText:
+		return KindTypedValue, s[3 : len(s)-1]
+	case TokenNumberEnd, TokenDateEnd, TokenRawTextEnd:
+		return KindTypedValueEnd, s[4 : len(s)-1]
+	case TokenTrue, TokenFalse:
+		return KindBoolean, s[3 : len(s)-2]
+	case TokenItem:
+		return KindItem, ""
+	case TokenItemEnd:
+		return KindItemEnd, ""
+	}
+
+	if strings.HasPrefix(s, "##") {
+		return KindRegisteredAttr, s[2:]
+	}
+	if !strings.HasSuffix(s, ">") || strings.HasSuffix(s, "/>") {
+		return KindUnknown, ""
+	}
+	if strings.HasPrefix(s, "</") {
+		name := s[2 : len(s)-1]
+		if strings.HasPrefix(name, "__") {
+			return KindUnknown, ""
+		}
+		return KindEndTag, name
+	}
+	if strings.HasPrefix(s, "<") {
+		name := s[1 : len(s)-1]
+		if strings.HasPrefix(name, "__") {
+			return KindUnknown, ""
+		}
+		return KindStartTag, name
+	}
+	return KindUnknown, ""
+}
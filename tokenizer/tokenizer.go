@@ -11,7 +11,19 @@ import (
 )
 
 const (
-	ArborOrderedAttribute    = "arbor-ordered"
+	ArborOrderedAttribute = "arbor-ordered"
+	// ArborSkipAttribute set to "true" drops the element and its subtree.
+	ArborSkipAttribute = "arbor-skip"
+	// ArborOpaqueAttribute set to "true" encodes the inner XML of the element
+	// as text instead of elements.
+	ArborOpaqueAttribute = "arbor-opaque"
+	// ArborMaxTokensAttribute caps the number of content tokens emitted for
+	// the text of the element and its descendants.
+	ArborMaxTokensAttribute = "arbor-max-tokens"
+	// ArborLabelAttribute attaches a label to the element, reported in
+	// TokenizationResult.Labels.
+	ArborLabelAttribute = "arbor-label"
+
 	TokenRegisteredAttr      = "<__RegisteredAttr>"
 	TokenUnregisteredAttr    = "<__UnregisteredAttr>"
 	TokenUnregisteredAttrEnd = "</__UnregisteredAttr>"
@@ -20,6 +32,26 @@ const (
 	TokenValue               = "<__Value>"
 	TokenValueEnd            = "</__Value>"
 	TokenEmpty               = "<__Empty/>"
+	// TokenOrdered and TokenUnordered carry an explicit arbor-ordered attribute
+	// through encoding so DecodeXML can restore it. Both are optional: they are
+	// emitted only when present in the vocab.
+	TokenOrdered   = "<__Ordered/>"
+	TokenUnordered = "<__Unordered/>"
+	// Typed value tokens, emitted only with WithTypedValues.
+	TokenNumber    = "<__Number>"
+	TokenNumberEnd = "</__Number>"
+	TokenDate      = "<__Date>"
+	TokenDateEnd   = "</__Date>"
+	TokenTrue      = "<__True/>"
+	TokenFalse     = "<__False/>"
+	// TokenRawText and TokenRawTextEnd wrap script and style bodies, see
+	// WithRawText.
+	TokenRawText    = "<__RawText>"
+	TokenRawTextEnd = "</__RawText>"
+	// TokenItem and TokenItemEnd wrap the members of a set-valued attribute,
+	// see WithSetAttributes.
+	TokenItem    = "<__Item>"
+	TokenItemEnd = "</__Item>"
 	// Cl100kBaseMaxID is the rough upper bound of cl100k_base vocab.
 	// The exact size is around 100277. We use 100500 to be safe.
 	Cl100kBaseMaxID = 100500
@@ -28,15 +60,31 @@ const (
 type TokenizationResult struct {
 	Tokens      []int
 	PaddedPaths [][]int
+	// Labels lists the elements carrying arbor-label, in document order.
+	Labels []NodeLabel
+}
+
+// NodeLabel is the label of an element set with arbor-label.
+type NodeLabel struct {
+	Label string
+	// Start and End are the indices in Tokens of the start and end tags.
+	Start int
+	End   int
+	// Path is the path of the start tag.
+	Path []int
 }
 
 type Tokenizer struct {
 	vocab            map[string]int
 	vocabInv         map[int]string
 	contentTokenizer *tiktoken.Tiktoken
+	// bpeRanks are the ranks given to NewTokenizerFromBytes, nil when
+	// tiktoken loaded them.
+	bpeRanks map[string]int
+	options  Options
 }
 
-func NewTokenizer(vocabPath string) (*Tokenizer, error) {
+func NewTokenizer(vocabPath string, opts ...Option) (*Tokenizer, error) {
 	f, err := os.Open(vocabPath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open vocab file: %w", err)
@@ -48,6 +96,39 @@ func NewTokenizer(vocabPath string) (*Tokenizer, error) {
 		return nil, fmt.Errorf("failed to decode vocab file: %w", err)
 	}
 
+	tke, err := tiktoken.GetEncoding("cl100k_base")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tiktoken encoding: %w", err)
+	}
+	return newTokenizer(vocab, tke, opts)
+}
+
+// NewTokenizerFromBytes creates a Tokenizer from the contents of a vocab file
+// and of the cl100k_base BPE ranks file (cl100k_base.tiktoken), for
+// environments that cannot read them from disk or download them, such as
+// WebAssembly.
+func NewTokenizerFromBytes(vocabData, bpeRanks []byte, opts ...Option) (*Tokenizer, error) {
+	var vocab map[string]int
+	if err := json.Unmarshal(vocabData, &vocab); err != nil {
+		return nil, fmt.Errorf("failed to decode vocab: %w", err)
+	}
+	ranks, err := parseBPERanks(bpeRanks)
+	if err != nil {
+		return nil, err
+	}
+	tke, err := newCl100kBase(ranks)
+	if err != nil {
+		return nil, err
+	}
+	tok, err := newTokenizer(vocab, tke, opts)
+	if err != nil {
+		return nil, err
+	}
+	tok.bpeRanks = ranks
+	return tok, nil
+}
+
+func newTokenizer(vocab map[string]int, tke *tiktoken.Tiktoken, opts []Option) (*Tokenizer, error) {
 	vocabInv := make(map[int]string)
 	for k, v := range vocab {
 		if v < Cl100kBaseMaxID {
@@ -56,32 +137,50 @@ func NewTokenizer(vocabPath string) (*Tokenizer, error) {
 		vocabInv[v] = k
 	}
 
-	tke, err := tiktoken.GetEncoding("cl100k_base")
-	if err != nil {
-		return nil, fmt.Errorf("failed to get tiktoken encoding: %w", err)
+	options := newOptions(opts)
+	if options.OrderingRulesFile != "" {
+		var err error
+		options.OrderingRules, err = LoadOrderingRules(options.OrderingRulesFile)
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	return &Tokenizer{
 		vocab:            vocab,
 		vocabInv:         vocabInv,
 		contentTokenizer: tke,
+		options:          options,
 	}, nil
 }
 
 func (t *Tokenizer) Tokenize(r io.Reader) (*TokenizationResult, error) {
-	transformer := NewTransformer(t.vocab)
+	transformer := NewTransformer(t.vocab, withOptions(t.options))
 	rootElement, err := transformer.Transform(r)
 	if err != nil {
 		return nil, err
 	}
 
-	encoder := NewEncoder(t.vocab, t.contentTokenizer)
+	encoder := NewEncoder(t.vocab, t.contentTokenizer, withOptions(t.options))
 	// Serialize Element to string and pass to Encoder.
 	// Ideally Encoder could traverse Element directly, but sticking to XML stream interface for now.
 	// Element.String() produces valid XML.
 	return encoder.Encode(strings.NewReader(rootElement.String()))
 }
 
+// TokenizeElement tokenizes an Element tree built in memory, such as the
+// output of ConvertJSONToElement or ConvertHTMLToElement.
+func (t *Tokenizer) TokenizeElement(el *Element) (*TokenizationResult, error) {
+	transformer := NewTransformer(t.vocab, withOptions(t.options))
+	rootElement, err := transformer.TransformElement(el)
+	if err != nil {
+		return nil, err
+	}
+
+	encoder := NewEncoder(t.vocab, t.contentTokenizer, withOptions(t.options))
+	return encoder.Encode(strings.NewReader(rootElement.String()))
+}
+
 // getPaddedPaths returns the paths as a 2D matrix.
 // It pads shorter paths with padValue (usually -1).
 func getPaddedPaths(paths [][]int, maxDepth int, padValue int) [][]int {
@@ -111,6 +210,16 @@ func getPaddedPaths(paths [][]int, maxDepth int, padValue int) [][]int {
 	return tensor
 }
 
+// Vocab returns a copy of the vocab, mapping tags and special tokens to
+// their IDs.
+func (t *Tokenizer) Vocab() map[string]int {
+	vocab := make(map[string]int, len(t.vocab))
+	for k, v := range t.vocab {
+		vocab[k] = v
+	}
+	return vocab
+}
+
 func (t *Tokenizer) Decode(tokens []int) string {
 	var parts []string
 	for _, token := range tokens {
@@ -16,16 +16,31 @@ const (
 )
 
 type Transformer struct {
-	vocab map[string]int
+	vocab   map[string]int
+	options Options
 }
 
-func NewTransformer(vocab map[string]int) *Transformer {
-	return &Transformer{vocab: vocab}
+func NewTransformer(vocab map[string]int, opts ...Option) *Transformer {
+	return &Transformer{vocab: vocab, options: newOptions(opts)}
+}
+
+// tokenSource yields XML tokens, like xml.Decoder.
+type tokenSource interface {
+	Token() (xml.Token, error)
 }
 
 // Transform converts standard XML into a valid XML object where attributes are converted to child elements.
 func (t *Transformer) Transform(r io.Reader) (*Element, error) {
-	decoder := xml.NewDecoder(r)
+	return t.transform(xml.NewDecoder(r))
+}
+
+// TransformElement is like Transform for an Element tree built in memory,
+// without serializing it to XML first.
+func (t *Transformer) TransformElement(el *Element) (*Element, error) {
+	return t.transform(newElementTokenReader(el))
+}
+
+func (t *Transformer) transform(decoder tokenSource) (*Element, error) {
 	var stack []*Element
 	var root *Element
 
@@ -40,6 +55,13 @@ func (t *Transformer) Transform(r io.Reader) (*Element, error) {
 
 		switch se := token.(type) {
 		case xml.StartElement:
+			if hasDirective(se.Attr, ArborSkipAttribute) {
+				if err := skipElement(decoder); err != nil {
+					return nil, err
+				}
+				continue
+			}
+
 			tagName := "<" + se.Name.Local + ">"
 			if _, ok := t.vocab[tagName]; !ok {
 				return nil, fmt.Errorf("tag %s not found in vocab", tagName)
@@ -47,15 +69,24 @@ func (t *Transformer) Transform(r io.Reader) (*Element, error) {
 
 			el := &Element{Name: se.Name.Local}
 
-			sort.Slice(se.Attr, func(i, j int) bool {
-				return se.Attr[i].Name.Local < se.Attr[j].Name.Local
-			})
+			if t.options.AttributeOrder == AttributesSorted {
+				sort.Slice(se.Attr, func(i, j int) bool {
+					return se.Attr[i].Name.Local < se.Attr[j].Name.Local
+				})
+			}
 
-			// Check for arbor-ordered attribute
+			// Ordering rules stand in for a missing arbor-ordered
+			if _, ok := directiveValue(se.Attr, ArborOrderedAttribute); !ok {
+				if ordered, ok := t.options.OrderingRules.Match(elementPath(stack, se.Name.Local), se.Attr); ok {
+					el.Attributes = append(el.Attributes, orderedAttr(ordered))
+				}
+			}
+
+			// Keep the directives the Encoder handles
 			for _, attr := range se.Attr {
-				if attr.Name.Local == ArborOrderedAttribute {
+				switch attr.Name.Local {
+				case ArborOrderedAttribute, ArborMaxTokensAttribute, ArborLabelAttribute:
 					el.Attributes = append(el.Attributes, attr)
-					break
 				}
 			}
 
@@ -69,7 +100,7 @@ func (t *Transformer) Transform(r io.Reader) (*Element, error) {
 
 			// Process Attributes
 			for _, attr := range se.Attr {
-				if attr.Name.Local == ArborOrderedAttribute {
+				if isDirective(attr.Name.Local) {
 					continue
 				}
 				if err := t.processAttributeToElement(el, attr); err != nil {
@@ -77,6 +108,18 @@ func (t *Transformer) Transform(r io.Reader) (*Element, error) {
 				}
 			}
 
+			// Opaque content is read here, up to and including the end tag.
+			if hasDirective(se.Attr, ArborOpaqueAttribute) {
+				inner, err := innerXML(decoder)
+				if err != nil {
+					return nil, err
+				}
+				if inner != "" {
+					el.Children = append(el.Children, t.rawText(inner))
+				}
+				stack = stack[:len(stack)-1]
+			}
+
 		case xml.EndElement:
 			tagName := "</" + se.Name.Local + ">"
 			if _, ok := t.vocab[tagName]; !ok {
@@ -93,12 +136,15 @@ func (t *Transformer) Transform(r io.Reader) (*Element, error) {
 			if trimmed != "" {
 				if len(stack) > 0 {
 					current := stack[len(stack)-1]
-					current.Children = append(current.Children, trimmed)
+					current.Children = append(current.Children, t.typedValue(current.Name, trimmed))
 				}
 			}
 		}
 	}
 
+	if root == nil {
+		return nil, fmt.Errorf("no root element")
+	}
 	return root, nil
 }
 
@@ -124,13 +170,15 @@ func (t *Transformer) processAttributeToElement(parent *Element, attr xml.Attr)
 		valName := strings.Trim(TokenValue, "<>")
 		valEl := &Element{Name: valName}
 
-		if attr.Value == "" && hasEmpty {
+		if setEl := t.setValue(parent.Name, attr.Name.Local, attr.Value); setEl != nil {
+			valEl = setEl
+		} else if attr.Value == "" && hasEmpty {
 			// <__Empty/>
 			emptyName := strings.Trim(TokenEmpty, "<> /") // Strip < > /
 			valEl.Children = append(valEl.Children, &Element{Name: emptyName})
 		} else {
 			if attr.Value != "" {
-				valEl.Children = append(valEl.Children, attr.Value)
+				valEl.Children = append(valEl.Children, t.typedValue(parent.Name+"@"+attr.Name.Local, attr.Value))
 			}
 		}
 
@@ -161,11 +209,14 @@ func (t *Transformer) processAttributeToElement(parent *Element, attr xml.Attr)
 		})
 
 		// <__Value>val</__Value>
-		valName := strings.Trim(TokenValue, "<>")
-		pair.Children = append(pair.Children, &Element{
-			Name:     valName,
-			Children: []interface{}{attr.Value},
-		})
+		valEl := t.setValue(parent.Name, attr.Name.Local, attr.Value)
+		if valEl == nil {
+			valEl = &Element{
+				Name:     strings.Trim(TokenValue, "<>"),
+				Children: []interface{}{t.typedValue(parent.Name+"@"+attr.Name.Local, attr.Value)},
+			}
+		}
+		pair.Children = append(pair.Children, valEl)
 
 		parent.Children = append(parent.Children, pair)
 	}
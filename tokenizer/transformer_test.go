@@ -10,6 +10,9 @@ import (
 	"path/filepath"
 	"strings"
 	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 var update = flag.Bool("update", false, "update golden files")
@@ -244,3 +247,23 @@ func TestTransformer_Golden(t *testing.T) {
 func StringsDiff(a, b string) bool {
 	return a != b
 }
+
+func TestTransformer_TransformElement(t *testing.T) {
+	vocab := map[string]int{"<Root>": 1, "</Root>": 2, "<Item>": 3, "</Item>": 4, "##id": 5}
+	input := `<Root arbor-ordered="true"><Item id="1">A &amp; B</Item>text</Root>`
+
+	fromXML, err := NewTransformer(vocab).Transform(strings.NewReader(input))
+	require.NoError(t, err)
+
+	el := &Element{
+		Name:       "Root",
+		Attributes: []xml.Attr{{Name: xml.Name{Local: ArborOrderedAttribute}, Value: "true"}},
+		Children: []interface{}{
+			&Element{Name: "Item", Attributes: []xml.Attr{{Name: xml.Name{Local: "id"}, Value: "1"}}, Children: []interface{}{"A & B"}},
+			"text",
+		},
+	}
+	fromElement, err := NewTransformer(vocab).TransformElement(el)
+	require.NoError(t, err)
+	assert.Equal(t, fromXML.String(), fromElement.String())
+}
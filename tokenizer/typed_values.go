@@ -0,0 +1,148 @@
+package tokenizer
+
+import (
+	"regexp"
+	"strings"
+	"time"
+)
+
+// ValueType is the type of a scalar value in typed value mode.
+type ValueType string
+
+const (
+	// ValueString keeps the value as ordinary text.
+	ValueString ValueType = "string"
+	// ValueNumber is an integer or decimal number, emitted as <__Number>.
+	ValueNumber ValueType = "number"
+	// ValueBoolean is true or false, emitted as <__True/> or <__False/>.
+	ValueBoolean ValueType = "boolean"
+	// ValueDate is an ISO 8601 date or date-time, emitted as <__Date>.
+	ValueDate ValueType = "date"
+)
+
+var (
+	// numberPattern follows the JSON number grammar, so identifiers with
+	// leading zeros such as zip codes stay text.
+	numberPattern = regexp.MustCompile(`^-?(0|[1-9][0-9]*)(\.[0-9]+)?([eE][+-]?[0-9]+)?$`)
+	// hintedNumberPattern is used when a hint says the value is a number.
+	hintedNumberPattern = regexp.MustCompile(`^[+-]?[0-9]+(\.[0-9]+)?([eE][+-]?[0-9]+)?$`)
+
+	dateLayouts = []string{
+		"2006-01-02",
+		"2006-01-02T15:04",
+		"2006-01-02T15:04:05",
+		"2006-01-02T15:04:05.999999999",
+		time.RFC3339Nano,
+	}
+)
+
+// typedValue returns the child to store for the value s: s itself, or a
+// typed element wrapping it. key is the hint key for s.
+func (t *Transformer) typedValue(key string, s string) interface{} {
+	if !t.options.TypedValues {
+		return s
+	}
+
+	types := []ValueType{ValueBoolean, ValueNumber, ValueDate}
+	hint, hinted := t.typeHint(key)
+	if hinted {
+		types = []ValueType{hint}
+	}
+	for _, typ := range types {
+		if el := t.typedElement(typ, s, hinted); el != nil {
+			return el
+		}
+	}
+	return s
+}
+
+// typeHint looks up key in the type hints, falling back from "Tag@attr" to
+// "@attr".
+func (t *Transformer) typeHint(key string) (ValueType, bool) {
+	if hint, ok := t.options.TypeHints[key]; ok {
+		return hint, true
+	}
+	if i := strings.IndexByte(key, '@'); i > 0 {
+		hint, ok := t.options.TypeHints[key[i:]]
+		return hint, ok
+	}
+	return "", false
+}
+
+// typedElement returns the element encoding s as typ, or nil if s is not a
+// valid typ or the vocab lacks its tokens.
+func (t *Transformer) typedElement(typ ValueType, s string, hinted bool) *Element {
+	switch typ {
+	case ValueBoolean:
+		tok := TokenFalse
+		switch s {
+		case "true":
+			tok = TokenTrue
+		case "false":
+		default:
+			return nil
+		}
+		if _, ok := t.vocab[tok]; !ok {
+			return nil
+		}
+		return &Element{Name: strings.Trim(tok, "</>")}
+
+	case ValueNumber:
+		pattern := numberPattern
+		if hinted {
+			pattern = hintedNumberPattern
+		}
+		if !pattern.MatchString(s) || !t.hasTokens(TokenNumber, TokenNumberEnd) {
+			return nil
+		}
+		return &Element{Name: strings.Trim(TokenNumber, "<>"), Children: []interface{}{s}}
+
+	case ValueDate:
+		if !isISODate(s) || !t.hasTokens(TokenDate, TokenDateEnd) {
+			return nil
+		}
+		return &Element{Name: strings.Trim(TokenDate, "<>"), Children: []interface{}{s}}
+	}
+	return nil
+}
+
+func (t *Transformer) hasTokens(toks ...string) bool {
+	for _, tok := range toks {
+		if _, ok := t.vocab[tok]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func isISODate(s string) bool {
+	for _, layout := range dateLayouts {
+		if _, err := time.Parse(layout, s); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+func isTypedValueToken(s string) bool {
+	switch s {
+	case TokenNumber, TokenDate, TokenTrue, TokenFalse, TokenRawText:
+		return true
+	}
+	return false
+}
+
+// typedLiteral returns the text a typed value token stands for in decoded
+// output, and whether s is such a token. The <__Number>, <__Date> and
+// <__RawText> wrappers stand for nothing, their body is ordinary content.
+func typedLiteral(s string) (string, bool) {
+	switch s {
+	case TokenNumber, TokenNumberEnd, TokenDate, TokenDateEnd, TokenRawText, TokenRawTextEnd:
+		return "", true
+	case TokenTrue:
+		return "true", true
+	case TokenFalse:
+		return "false", true
+	}
+	return "", false
+}
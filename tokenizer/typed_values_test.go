@@ -0,0 +1,179 @@
+package tokenizer
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func createTypedVocab() map[string]int {
+	base := 200000
+	return map[string]int{
+		"<Root>":                 base + 1,
+		"</Root>":                base + 2,
+		"<Item>":                 base + 3,
+		"</Item>":                base + 4,
+		"##count":                base + 100,
+		"##label":                base + 101,
+		TokenUnregisteredAttr:    base + 200,
+		TokenUnregisteredAttrEnd: base + 201,
+		TokenKey:                 base + 202,
+		TokenKeyEnd:              base + 203,
+		TokenValue:               base + 204,
+		TokenValueEnd:            base + 205,
+		TokenEmpty:               base + 206,
+		TokenNumber:              base + 300,
+		TokenNumberEnd:           base + 301,
+		TokenDate:                base + 302,
+		TokenDateEnd:             base + 303,
+		TokenTrue:                base + 304,
+		TokenFalse:               base + 305,
+	}
+}
+
+func newTypedTokenizer(t *testing.T, vocab map[string]int, opts ...Option) *Tokenizer {
+	vocabPath := createTempVocab(t, vocab)
+	t.Cleanup(func() { os.Remove(vocabPath) })
+	tokenizer, err := NewTokenizer(vocabPath, opts...)
+	require.NoError(t, err)
+	return tokenizer
+}
+
+func TestTypedValues_Transform(t *testing.T) {
+	vocab := createTypedVocab()
+
+	tests := []struct {
+		name     string
+		input    string
+		hints    map[string]ValueType
+		expected string
+	}{
+		{
+			name:     "Text",
+			input:    `<Root><Item>12345</Item><Item>-1.5e3</Item><Item>true</Item><Item>false</Item><Item>2024-01-01</Item><Item>2024-01-01T10:30:00Z</Item></Root>`,
+			expected: `<Root><Item><__Number>12345</__Number></Item><Item><__Number>-1.5e3</__Number></Item><Item><__True></__True></Item><Item><__False></__False></Item><Item><__Date>2024-01-01</__Date></Item><Item><__Date>2024-01-01T10:30:00Z</__Date></Item></Root>`,
+		},
+		{
+			name:     "NotTyped",
+			input:    `<Root><Item>007</Item><Item>True</Item><Item>2024-13-01</Item><Item>12 apples</Item></Root>`,
+			expected: `<Root><Item>007</Item><Item>True</Item><Item>2024-13-01</Item><Item>12 apples</Item></Root>`,
+		},
+		{
+			name:     "Attributes",
+			input:    `<Root count="42" other="true"></Root>`,
+			expected: `<Root><__RegisteredAttr><__Key>count</__Key><__Value><__Number>42</__Number></__Value></__RegisteredAttr><__UnregisteredAttr><__Key>other</__Key><__Value><__True></__True></__Value></__UnregisteredAttr></Root>`,
+		},
+		{
+			name:     "Hints",
+			input:    `<Root label="12" count="007"><Item>2024-01-01</Item></Root>`,
+			hints:    map[string]ValueType{"@label": ValueString, "Root@count": ValueNumber, "Item": ValueNumber},
+			expected: `<Root><__RegisteredAttr><__Key>count</__Key><__Value><__Number>007</__Number></__Value></__RegisteredAttr><__RegisteredAttr><__Key>label</__Key><__Value>12</__Value></__RegisteredAttr><Item>2024-01-01</Item></Root>`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transformer := NewTransformer(vocab, WithTypedValues(tt.hints))
+			el, err := transformer.Transform(strings.NewReader(tt.input))
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, el.String())
+		})
+	}
+}
+
+func TestTypedValues_MissingTokens(t *testing.T) {
+	vocab := map[string]int{"<Root>": 1, "</Root>": 2, TokenTrue: 3}
+
+	transformer := NewTransformer(vocab, WithTypedValues(nil))
+	el, err := transformer.Transform(strings.NewReader(`<Root>42</Root>`))
+	require.NoError(t, err)
+	assert.Equal(t, `<Root>42</Root>`, el.String(), "numbers need <__Number> and </__Number>")
+
+	el, err = transformer.Transform(strings.NewReader(`<Root>true</Root>`))
+	require.NoError(t, err)
+	assert.Equal(t, `<Root><__True></__True></Root>`, el.String())
+}
+
+func TestTypedValues_Tokenize(t *testing.T) {
+	vocab := createTypedVocab()
+	plain := newTypedTokenizer(t, vocab)
+	typed := newTypedTokenizer(t, vocab, WithTypedValues(nil))
+
+	input := `<Root count="2024" extra="2024-01-01"><Item>12345</Item><Item>true</Item><Item>Route 66</Item></Root>`
+
+	res, err := typed.Tokenize(strings.NewReader(input))
+	require.NoError(t, err)
+
+	// Every digit is its own token.
+	var digits []int
+	for i, id := range res.Tokens {
+		if id == vocab[TokenNumber] {
+			for _, d := range res.Tokens[i+1:] {
+				if d == vocab[TokenNumberEnd] {
+					break
+				}
+				digits = append(digits, d)
+			}
+		}
+	}
+	var expected []int
+	for _, r := range "2024" + "12345" {
+		expected = append(expected, typed.contentTokenizer.Encode(string(r), nil, nil)...)
+	}
+	assert.Equal(t, expected, digits)
+
+	// Typed values take the place of content, so paths follow them.
+	tracker := typed.NewPathTracker(nil)
+	for i, id := range res.Tokens {
+		path, err := tracker.Push(id)
+		require.NoError(t, err)
+		assert.Equal(t, trimPadding(res.PaddedPaths[i]), path, "token %d", i)
+	}
+
+	c := typed.NewConstraint(nil)
+	for i, id := range res.Tokens {
+		require.NoError(t, c.Consume(id), "token %d", i)
+	}
+	assert.True(t, c.Done())
+
+	// Decoding gives back the same tree as without typed values.
+	decoded, err := typed.DecodeXML(res.Tokens)
+	require.NoError(t, err)
+	plainRes, err := plain.Tokenize(strings.NewReader(input))
+	require.NoError(t, err)
+	plainDecoded, err := plain.DecodeXML(plainRes.Tokens)
+	require.NoError(t, err)
+	assert.Equal(t, plainDecoded.String(), decoded.String())
+}
+
+func TestTypedValues_Constraint(t *testing.T) {
+	vocab := createTypedVocab()
+	c := NewConstraint(vocab, nil)
+	require.NoError(t, c.Consume(vocab["<Root>"]))
+	assert.True(t, c.Allows(vocab[TokenNumber]))
+	assert.True(t, c.Allows(vocab[TokenTrue]))
+
+	require.NoError(t, c.Consume(vocab[TokenNumber]))
+	allowed := c.Allowed()
+	assert.True(t, allowed.Content)
+	assert.Equal(t, []int{vocab[TokenNumberEnd]}, allowed.IDs)
+	assert.Error(t, c.Consume(vocab["</Root>"]))
+
+	require.NoError(t, c.Consume(16))
+	require.NoError(t, c.Consume(vocab[TokenNumberEnd]))
+	require.NoError(t, c.Consume(vocab["</Root>"]))
+	assert.True(t, c.Done())
+}
+
+func TestTypedValues_Repair(t *testing.T) {
+	tokenizer := newTypedTokenizer(t, createTypedVocab(), WithTypedValues(nil))
+	vocab := tokenizer.vocab
+
+	repaired, report := tokenizer.RepairTokens([]int{vocab["<Root>"], vocab[TokenDate], 17})
+	assert.Equal(t, []int{vocab["<Root>"], vocab[TokenDate], 17, vocab[TokenDateEnd], vocab["</Root>"]}, repaired)
+	require.Len(t, report.Actions, 2)
+	assert.Equal(t, "closed typed value", report.Actions[0].Reason)
+}
@@ -0,0 +1,189 @@
+package tokenizer
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// YAMLRootTag wraps the content of a YAML stream.
+	YAMLRootTag = "YAML"
+	// YAMLDocumentTag wraps each document of a multi-document stream.
+	YAMLDocumentTag = "Document"
+)
+
+// ConvertYAMLToElement converts a YAML stream into an Element tree, using the
+// same layout as ConvertJSONToElement: mappings become arbor-ordered="false"
+// containers, sequences become arbor-ordered="true" containers of DataItemTag
+// elements and scalars become text. Scalar types other than strings are kept
+// in a DataTypeAttribute ("number", "boolean", "null", "timestamp", or the
+// tag itself for custom tags such as !Ref). Aliases and merge keys are
+// resolved. A stream with several documents, such as a set of Kubernetes
+// manifests, yields one ordered YAMLDocumentTag child per document.
+func ConvertYAMLToElement(r io.Reader) (*Element, error) {
+	dec := yaml.NewDecoder(r)
+
+	var docs []*yaml.Node
+	for {
+		var doc yaml.Node
+		if err := dec.Decode(&doc); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, err
+		}
+		docs = append(docs, &doc)
+	}
+
+	root := &Element{Name: YAMLRootTag}
+	c := &yamlConverter{expanding: make(map[*yaml.Node]bool)}
+	switch len(docs) {
+	case 0:
+	case 1:
+		if err := c.convert(docs[0], root); err != nil {
+			return nil, err
+		}
+	default:
+		root.Attributes = append(root.Attributes, orderedAttr(true))
+		for _, doc := range docs {
+			child := &Element{Name: YAMLDocumentTag}
+			if err := c.convert(doc, child); err != nil {
+				return nil, err
+			}
+			root.Children = append(root.Children, child)
+		}
+	}
+	return root, nil
+}
+
+type yamlConverter struct {
+	// expanding holds the anchors being expanded, to reject recursive aliases.
+	expanding map[*yaml.Node]bool
+}
+
+// convert writes the YAML node n into el.
+func (c *yamlConverter) convert(n *yaml.Node, el *Element) error {
+	switch n.Kind {
+	case yaml.DocumentNode:
+		if len(n.Content) == 0 {
+			return nil
+		}
+		return c.convert(n.Content[0], el)
+
+	case yaml.AliasNode:
+		if c.expanding[n.Alias] {
+			return fmt.Errorf("line %d: recursive alias *%s", n.Line, n.Value)
+		}
+		c.expanding[n.Alias] = true
+		defer delete(c.expanding, n.Alias)
+		return c.convert(n.Alias, el)
+
+	case yaml.MappingNode:
+		el.Attributes = append(el.Attributes, orderedAttr(false))
+		members, err := c.mappingMembers(n)
+		if err != nil {
+			return err
+		}
+		for _, m := range members {
+			child := memberElement(m.key)
+			if err := c.convert(m.value, child); err != nil {
+				return err
+			}
+			el.Children = append(el.Children, child)
+		}
+		return nil
+
+	case yaml.SequenceNode:
+		el.Attributes = append(el.Attributes, orderedAttr(true))
+		for _, item := range n.Content {
+			child := &Element{Name: DataItemTag}
+			if err := c.convert(item, child); err != nil {
+				return err
+			}
+			el.Children = append(el.Children, child)
+		}
+		return nil
+
+	case yaml.ScalarNode:
+		switch n.ShortTag() {
+		case "!!str", "!!binary":
+		case "!!int", "!!float":
+			el.Attributes = append(el.Attributes, typeAttr("number"))
+		case "!!bool":
+			el.Attributes = append(el.Attributes, typeAttr("boolean"))
+		case "!!null":
+			el.Attributes = append(el.Attributes, typeAttr("null"))
+			return nil
+		case "!!timestamp":
+			el.Attributes = append(el.Attributes, typeAttr("timestamp"))
+		default:
+			el.Attributes = append(el.Attributes, typeAttr(n.Tag))
+		}
+		if n.Value != "" {
+			el.Children = append(el.Children, n.Value)
+		}
+		return nil
+	}
+
+	return fmt.Errorf("line %d: unsupported YAML node kind %d", n.Line, n.Kind)
+}
+
+type yamlMember struct {
+	key   string
+	value *yaml.Node
+}
+
+// mappingMembers returns the key/value pairs of a mapping, with merge keys
+// (<<) expanded. Explicit keys take precedence over merged ones.
+func (c *yamlConverter) mappingMembers(n *yaml.Node) ([]yamlMember, error) {
+	var members, merged []yamlMember
+	seen := make(map[string]bool)
+
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		key, value := n.Content[i], n.Content[i+1]
+		if key.Kind == yaml.AliasNode {
+			key = key.Alias
+		}
+		if key.Kind != yaml.ScalarNode {
+			return nil, fmt.Errorf("line %d: unsupported non-scalar mapping key", key.Line)
+		}
+
+		if key.ShortTag() == "!!merge" {
+			sources := []*yaml.Node{value}
+			if value.Kind == yaml.SequenceNode {
+				sources = value.Content
+			}
+			for _, src := range sources {
+				if src.Kind == yaml.AliasNode {
+					src = src.Alias
+				}
+				if src.Kind != yaml.MappingNode {
+					return nil, fmt.Errorf("line %d: merge key value must be a mapping", value.Line)
+				}
+				sub, err := c.mappingMembers(src)
+				if err != nil {
+					return nil, err
+				}
+				merged = append(merged, sub...)
+			}
+			continue
+		}
+
+		if seen[key.Value] {
+			return nil, fmt.Errorf("line %d: duplicate mapping key %q", key.Line, key.Value)
+		}
+		seen[key.Value] = true
+		members = append(members, yamlMember{key: key.Value, value: value})
+	}
+
+	for _, m := range merged {
+		if !seen[m.key] {
+			seen[m.key] = true
+			members = append(members, m)
+		}
+	}
+	return members, nil
+}
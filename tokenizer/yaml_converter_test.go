@@ -0,0 +1,122 @@
+package tokenizer
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConvertYAMLToElement(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name: "Mapping",
+			input: `name: web
+replicas: 3
+ratio: 0.5
+enabled: yes
+debug: false
+owner: ~
+created: 2024-01-02
+`,
+			expected: `<YAML arbor-ordered="false"><name>web</name><replicas type="number">3</replicas><ratio type="number">0.5</ratio><enabled>yes</enabled><debug type="boolean">false</debug><owner type="null"></owner><created type="timestamp">2024-01-02</created></YAML>`,
+		},
+		{
+			name: "Sequence",
+			input: `- a
+- [1, 2]
+- {k: v}
+`,
+			expected: `<YAML arbor-ordered="true"><Item>a</Item><Item arbor-ordered="true"><Item type="number">1</Item><Item type="number">2</Item></Item><Item arbor-ordered="false"><k>v</k></Item></YAML>`,
+		},
+		{
+			name: "AliasesAndMerge",
+			input: `base: &base
+  image: nginx
+  port: 80
+svc:
+  <<: *base
+  port: 8080
+copy: *base
+`,
+			expected: `<YAML arbor-ordered="false"><base arbor-ordered="false"><image>nginx</image><port type="number">80</port></base><svc arbor-ordered="false"><port type="number">8080</port><image>nginx</image></svc><copy arbor-ordered="false"><image>nginx</image><port type="number">80</port></copy></YAML>`,
+		},
+		{
+			name: "TagsAndKeys",
+			input: `"app.kubernetes.io/name": web
+id: !Ref Instance
+`,
+			expected: `<YAML arbor-ordered="false"><Entry key="app.kubernetes.io/name">web</Entry><id type="!Ref">Instance</id></YAML>`,
+		},
+		{
+			name: "MultipleDocuments",
+			input: `kind: Service
+---
+kind: Deployment
+`,
+			expected: `<YAML arbor-ordered="true"><Document arbor-ordered="false"><kind>Service</kind></Document><Document arbor-ordered="false"><kind>Deployment</kind></Document></YAML>`,
+		},
+		{
+			name:     "Empty",
+			input:    ``,
+			expected: `<YAML></YAML>`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			el, err := ConvertYAMLToElement(strings.NewReader(tt.input))
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, el.String())
+		})
+	}
+}
+
+func TestConvertYAMLToElement_Errors(t *testing.T) {
+	for _, input := range []string{
+		"a: [1, 2",
+		"a: 1\na: 2\n",
+		"? [a, b]\n: c\n",
+		"a: &x\n  b: *x\n",
+	} {
+		_, err := ConvertYAMLToElement(strings.NewReader(input))
+		assert.Error(t, err, "input %q", input)
+	}
+}
+
+func TestConvertYAMLToElement_Tokenize(t *testing.T) {
+	base := 200000
+	vocab := map[string]int{
+		"<YAML>":      base + 1,
+		"</YAML>":     base + 2,
+		"<Item>":      base + 3,
+		"</Item>":     base + 4,
+		"<ports>":     base + 5,
+		"</ports>":    base + 6,
+		"<name>":      base + 7,
+		"</name>":     base + 8,
+		"##type":      base + 100,
+		TokenValueEnd: base + 101,
+	}
+	vocabPath := createTempVocab(t, vocab)
+	defer os.Remove(vocabPath)
+	tokenizer, err := NewTokenizer(vocabPath)
+	require.NoError(t, err)
+
+	el, err := ConvertYAMLToElement(strings.NewReader("name: web\nports: [80, 443]\n"))
+	require.NoError(t, err)
+	res, err := tokenizer.TokenizeElement(el)
+	require.NoError(t, err)
+
+	decoded, err := tokenizer.DecodeXML(res.Tokens)
+	require.NoError(t, err)
+	out, err := ConvertElementToJSON(decoded)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"name": "web", "ports": [80, 443]}`, string(out))
+}
@@ -0,0 +1,215 @@
+//go:build js && wasm
+
+// Command wasm exposes the tokenizer to JavaScript, for tokenizing in the
+// browser:
+//
+//	GOOS=js GOARCH=wasm go build -o build/arbor.wasm ./wasm
+//
+// Once started with wasm_exec.js, it sets globalThis.arbor.newTokenizer(vocab,
+// bpeRanks, options). vocab is the contents of a vocab file and bpeRanks of
+// cl100k_base.tiktoken, each as a Uint8Array, ArrayBuffer or string, as the
+// module reads neither from disk nor from the network. options may set
+// typedValues, setAttributes, attributeOrder, defaultOrdering and
+// inheritOrdering like the tokenize flags. It returns an object with:
+//
+//	tokenize(xml)              {tokens, paddedPaths, depth, labels}
+//	decode(tokens)             the space-joined tokens
+//	decodeXML(tokens, repair)  the XML document
+//
+// tokens and paddedPaths are Int32Arrays, paddedPaths flattened row by row
+// with depth columns. Functions throw an Error when they fail.
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"syscall/js"
+
+	"github.com/clems4ever/arbor-encoder/tokenizer"
+)
+
+func main() {
+	arbor := js.Global().Get("Object").New()
+	arbor.Set("newTokenizer", jsFunc(newTokenizer))
+	js.Global().Set("arbor", arbor)
+	select {}
+}
+
+// throwing wraps a JavaScript function returning [error, value] into one
+// returning value or throwing error, as Go cannot throw across syscall/js.
+var throwing = js.Global().Get("Function").New("f", `return function(...args) {
+	const [err, value] = f(...args);
+	if (err !== null) throw new Error(err);
+	return value;
+}`)
+
+// jsFunc wraps f as a JavaScript function that throws the errors of f.
+func jsFunc(f func(args []js.Value) (interface{}, error)) js.Value {
+	return throwing.Invoke(js.FuncOf(func(this js.Value, args []js.Value) (result interface{}) {
+		defer func() {
+			if r := recover(); r != nil {
+				result = []interface{}{fmt.Sprint(r), nil}
+			}
+		}()
+		v, err := f(args)
+		if err != nil {
+			return []interface{}{err.Error(), nil}
+		}
+		return []interface{}{nil, v}
+	}))
+}
+
+func newTokenizer(args []js.Value) (interface{}, error) {
+	if len(args) < 2 {
+		return nil, fmt.Errorf("newTokenizer(vocab, bpeRanks, options) expects at least 2 arguments")
+	}
+	var opts []tokenizer.Option
+	if len(args) > 2 && args[2].Type() == js.TypeObject {
+		var err error
+		opts, err = tokenizerOptions(args[2])
+		if err != nil {
+			return nil, err
+		}
+	}
+	tok, err := tokenizer.NewTokenizerFromBytes(jsBytes(args[0]), jsBytes(args[1]), opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	obj := js.Global().Get("Object").New()
+	obj.Set("tokenize", jsFunc(func(args []js.Value) (interface{}, error) {
+		if len(args) < 1 {
+			return nil, fmt.Errorf("tokenize(xml) expects 1 argument")
+		}
+		res, err := tok.Tokenize(bytes.NewReader(jsBytes(args[0])))
+		if err != nil {
+			return nil, err
+		}
+		return tokenizationResult(res), nil
+	}))
+	obj.Set("decode", jsFunc(func(args []js.Value) (interface{}, error) {
+		if len(args) < 1 {
+			return nil, fmt.Errorf("decode(tokens) expects 1 argument")
+		}
+		return tok.Decode(jsInts(args[0])), nil
+	}))
+	obj.Set("decodeXML", jsFunc(func(args []js.Value) (interface{}, error) {
+		if len(args) < 1 {
+			return nil, fmt.Errorf("decodeXML(tokens, repair) expects at least 1 argument")
+		}
+		tokens := jsInts(args[0])
+		if len(args) > 1 && args[1].Truthy() {
+			tokens, _ = tok.RepairTokens(tokens)
+		}
+		root, err := tok.DecodeXML(tokens)
+		if err != nil {
+			return nil, err
+		}
+		if root == nil {
+			return nil, errors.New("no element to decode")
+		}
+		return root.String(), nil
+	}))
+	return obj, nil
+}
+
+// tokenizerOptions reads the tokenizer options from a JavaScript object.
+func tokenizerOptions(v js.Value) ([]tokenizer.Option, error) {
+	var opts []tokenizer.Option
+	if v.Get("typedValues").Truthy() {
+		opts = append(opts, tokenizer.WithTypedValues(nil))
+	}
+	if v.Get("setAttributes").Truthy() {
+		opts = append(opts, tokenizer.WithSetAttributes())
+	}
+	if v.Get("inheritOrdering").Truthy() {
+		opts = append(opts, tokenizer.WithInheritedOrdering())
+	}
+	switch order := stringOption(v, "defaultOrdering"); order {
+	case "", "unordered":
+	case "ordered":
+		opts = append(opts, tokenizer.WithDefaultOrdering(tokenizer.ChildrenOrdered))
+	default:
+		return nil, fmt.Errorf("unknown defaultOrdering %q (expected ordered or unordered)", order)
+	}
+	switch order := stringOption(v, "attributeOrder"); order {
+	case "", "sorted":
+	case "source":
+		opts = append(opts, tokenizer.WithAttributeOrder(tokenizer.AttributesSourceOrder))
+	case "unordered":
+		opts = append(opts, tokenizer.WithAttributeOrder(tokenizer.AttributesUnordered))
+	default:
+		return nil, fmt.Errorf("unknown attributeOrder %q (expected sorted, source or unordered)", order)
+	}
+	return opts, nil
+}
+
+func stringOption(v js.Value, name string) string {
+	if o := v.Get(name); o.Type() == js.TypeString {
+		return o.String()
+	}
+	return ""
+}
+
+func tokenizationResult(res *tokenizer.TokenizationResult) js.Value {
+	depth := 0
+	if len(res.PaddedPaths) > 0 {
+		depth = len(res.PaddedPaths[0])
+	}
+	paths := make([]int, 0, len(res.PaddedPaths)*depth)
+	for _, row := range res.PaddedPaths {
+		paths = append(paths, row...)
+	}
+	labels := js.Global().Get("Array").New()
+	for _, l := range res.Labels {
+		label := js.Global().Get("Object").New()
+		label.Set("label", l.Label)
+		label.Set("start", l.Start)
+		label.Set("end", l.End)
+		label.Set("path", int32Array(l.Path))
+		labels.Call("push", label)
+	}
+
+	out := js.Global().Get("Object").New()
+	out.Set("tokens", int32Array(res.Tokens))
+	out.Set("paddedPaths", int32Array(paths))
+	out.Set("depth", depth)
+	out.Set("labels", labels)
+	return out
+}
+
+// int32Array copies values to a new Int32Array in one transfer.
+func int32Array(values []int) js.Value {
+	buf := make([]byte, 4*len(values))
+	for i, v := range values {
+		binary.LittleEndian.PutUint32(buf[4*i:], uint32(int32(v)))
+	}
+	u8 := js.Global().Get("Uint8Array").New(len(buf))
+	js.CopyBytesToJS(u8, buf)
+	return js.Global().Get("Int32Array").New(u8.Get("buffer"))
+}
+
+// jsBytes returns the contents of a Uint8Array or ArrayBuffer, or the UTF-8
+// bytes of a string.
+func jsBytes(v js.Value) []byte {
+	if v.Type() == js.TypeString {
+		return []byte(v.String())
+	}
+	if v.InstanceOf(js.Global().Get("ArrayBuffer")) {
+		v = js.Global().Get("Uint8Array").New(v)
+	}
+	buf := make([]byte, v.Get("length").Int())
+	js.CopyBytesToGo(buf, v)
+	return buf
+}
+
+// jsInts reads an array or typed array of token IDs.
+func jsInts(v js.Value) []int {
+	out := make([]int, v.Get("length").Int())
+	for i := range out {
+		out[i] = v.Index(i).Int()
+	}
+	return out
+}
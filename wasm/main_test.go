@@ -0,0 +1,87 @@
+//go:build js && wasm
+
+package main
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"syscall/js"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const vocab = `{"<Doc>": 200001, "</Doc>": 200002, "<Item>": 200003, "</Item>": 200004}`
+
+// byteRanks returns a ranks file mapping every byte to its value, so content
+// tokens are the bytes of the text.
+func byteRanks() string {
+	var b strings.Builder
+	for i := 0; i < 256; i++ {
+		fmt.Fprintf(&b, "%s %d\n", base64.StdEncoding.EncodeToString([]byte{byte(i)}), i)
+	}
+	return b.String()
+}
+
+// call invokes a JavaScript function and returns the error it throws.
+func call(f js.Value, args ...interface{}) (v js.Value, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			jsErr, ok := r.(js.Error)
+			if !ok {
+				panic(r)
+			}
+			err = jsErr
+		}
+	}()
+	return f.Invoke(args...), nil
+}
+
+func ints(v js.Value) []int {
+	out := make([]int, v.Get("length").Int())
+	for i := range out {
+		out[i] = v.Index(i).Int()
+	}
+	return out
+}
+
+func TestNewTokenizer(t *testing.T) {
+	newTok := jsFunc(newTokenizer)
+	ranks := js.Global().Get("Uint8Array").New(len(byteRanks()))
+	js.CopyBytesToJS(ranks, []byte(byteRanks()))
+
+	tok, err := call(newTok, vocab, ranks, map[string]interface{}{"defaultOrdering": "ordered"})
+	require.NoError(t, err)
+
+	res, err := call(tok.Get("tokenize"), `<Doc arbor-label="d"><Item>a</Item><Item>b</Item></Doc>`)
+	require.NoError(t, err)
+	assert.True(t, res.Get("tokens").InstanceOf(js.Global().Get("Int32Array")))
+	assert.Equal(t, []int{200001, 200003, 'a', 200004, 200003, 'b', 200004, 200002}, ints(res.Get("tokens")))
+	require.Equal(t, 3, res.Get("depth").Int())
+	// The second Item of the ordered Doc is at [0, 2].
+	assert.Equal(t, []int{0, 2, -1}, ints(res.Get("paddedPaths"))[4*3:5*3])
+	labels := res.Get("labels")
+	require.Equal(t, 1, labels.Length())
+	assert.Equal(t, "d", labels.Index(0).Get("label").String())
+
+	text, err := call(tok.Get("decode"), res.Get("tokens"))
+	require.NoError(t, err)
+	assert.Equal(t, "<Doc> <Item> a </Item> <Item> b </Item> </Doc>", text.String())
+
+	xml, err := call(tok.Get("decodeXML"), []interface{}{200001, 200003, 'a'}, true)
+	require.NoError(t, err)
+	assert.Equal(t, "<Doc><Item>a</Item></Doc>", xml.String())
+
+	_, err = call(tok.Get("decodeXML"), []interface{}{200002})
+	assert.Error(t, err)
+	_, err = call(tok.Get("decodeXML"), []interface{}{})
+	assert.ErrorContains(t, err, "no element to decode")
+	_, err = call(tok.Get("tokenize"), `<Doc><Item>`)
+	assert.Error(t, err)
+	_, err = call(newTok, vocab, ranks, map[string]interface{}{"attributeOrder": "random"})
+	assert.ErrorContains(t, err, "unknown attributeOrder")
+	_, err = call(newTok, vocab)
+	assert.ErrorContains(t, err, "expects at least 2 arguments")
+}